@@ -2,32 +2,63 @@ package arch
 
 import (
 	"os"
+	"runtime"
 	"strings"
 )
 
-func Validate(str string) string {
-	if str == "" {
-		str = strings.ToLower(os.Getenv("PROCESSOR_ARCHITECTURE"))
-	}
-	if strings.Contains(str, "arm64") {
-		return "arm64"
+// Normalized architecture names, matching the names Adoptium uses for its builds
+const (
+	X86     = "x86"
+	X64     = "x64"
+	AArch64 = "aarch64"
+	ARM     = "arm"
+	PPC64LE = "ppc64le"
+	S390X   = "s390x"
+	RISCV64 = "riscv64"
+	Unknown = "?"
+)
+
+// Names returns the normalized architecture names
+func Names() []string {
+	return []string{X86, X64, AArch64, ARM, PPC64LE, S390X, RISCV64}
+}
+
+// Host returns the architecture of the machine jdkvm is running on
+func Host() string {
+	// A 32-bit jdkvm running under WOW64 still reports the real processor here
+	if wow := os.Getenv("PROCESSOR_ARCHITEW6432"); wow != "" {
+		return Normalize(wow)
 	}
-	if strings.Contains(str, "64") {
-		return "64"
+	return Normalize(runtime.GOARCH)
+}
+
+// Normalize maps the many spellings of an architecture (GOARCH, os.arch,
+// PROCESSOR_ARCHITECTURE, the legacy "32"/"64"/"arm64" arguments) to one name
+func Normalize(str string) string {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "x86", "386", "i386", "i686", "x86-32", "x86_32", "32":
+		return X86
+	case "x64", "amd64", "x86_64", "x86-64", "64":
+		return X64
+	case "aarch64", "arm64":
+		return AArch64
+	case "arm", "arm32", "armv7", "armv7l", "armhf", "aarch32":
+		return ARM
+	case "ppc64le":
+		return PPC64LE
+	case "s390x":
+		return S390X
+	case "riscv64":
+		return RISCV64
 	}
-	return "32"
+	return Unknown
 }
 
-// Simplified architecture detection - in a real implementation this would be more robust
-func Bit(path string) string {
-	// For Java, we can check the file extension or use more complex detection
-	// This is a simplified version
-	if strings.Contains(strings.ToLower(path), "arm64") {
-		return "arm64"
-	} else if strings.Contains(strings.ToLower(path), "64") {
-		return "64"
-	} else if strings.Contains(strings.ToLower(path), "32") {
-		return "32"
+// Validate returns the normalized architecture for str, falling back to the host
+// architecture when str is empty or not recognized
+func Validate(str string) string {
+	if a := Normalize(str); a != Unknown {
+		return a
 	}
-	return "?"
+	return Host()
 }
@@ -0,0 +1,145 @@
+package arch
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"os"
+	"path/filepath"
+)
+
+// Detect reports the architecture of a Java installation by reading the
+// executable header of its launcher. path may be the install directory or
+// the launcher itself.
+func Detect(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Unknown
+	}
+	if info.IsDir() {
+		path = launcher(path)
+		if path == "" {
+			return Unknown
+		}
+	}
+
+	if a, ok := fromELF(path); ok {
+		return a
+	}
+	if a, ok := fromPE(path); ok {
+		return a
+	}
+	if a, ok := fromMachO(path); ok {
+		return a
+	}
+	return Unknown
+}
+
+// launcher finds bin/java (or bin/java.exe) inside an install directory
+func launcher(dir string) string {
+	for _, name := range []string{"java.exe", "java"} {
+		p := filepath.Join(dir, "bin", name)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	// macOS archives keep the JDK under Contents/Home
+	p := filepath.Join(dir, "Contents", "Home", "bin", "java")
+	if _, err := os.Stat(p); err == nil {
+		return p
+	}
+	return ""
+}
+
+func fromELF(path string) (string, bool) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	switch f.Machine {
+	case elf.EM_386:
+		return X86, true
+	case elf.EM_X86_64:
+		return X64, true
+	case elf.EM_AARCH64:
+		return AArch64, true
+	case elf.EM_ARM:
+		return ARM, true
+	case elf.EM_PPC64:
+		if f.Data == elf.ELFDATA2LSB {
+			return PPC64LE, true
+		}
+	case elf.EM_S390:
+		if f.Class == elf.ELFCLASS64 {
+			return S390X, true
+		}
+	case elf.EM_RISCV:
+		if f.Class == elf.ELFCLASS64 {
+			return RISCV64, true
+		}
+	}
+	return Unknown, true
+}
+
+func fromPE(path string) (string, bool) {
+	f, err := pe.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	switch f.Machine {
+	case pe.IMAGE_FILE_MACHINE_I386:
+		return X86, true
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		return X64, true
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		return AArch64, true
+	case pe.IMAGE_FILE_MACHINE_ARMNT:
+		return ARM, true
+	case pe.IMAGE_FILE_MACHINE_RISCV64:
+		return RISCV64, true
+	}
+	return Unknown, true
+}
+
+func fromMachO(path string) (string, bool) {
+	if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		return machoCpu(f.Cpu), true
+	}
+
+	// Universal binaries carry several architectures; prefer the host's slice
+	fat, err := macho.OpenFat(path)
+	if err != nil {
+		return "", false
+	}
+	defer fat.Close()
+
+	host := Host()
+	found := Unknown
+	for _, a := range fat.Arches {
+		name := machoCpu(a.Cpu)
+		if name == host {
+			return name, true
+		}
+		if found == Unknown {
+			found = name
+		}
+	}
+	return found, true
+}
+
+func machoCpu(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.Cpu386:
+		return X86
+	case macho.CpuAmd64:
+		return X64
+	case macho.CpuArm64:
+		return AArch64
+	}
+	return Unknown
+}
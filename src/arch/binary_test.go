@@ -0,0 +1,184 @@
+package arch
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// elfHeader returns an ELF header with no sections. interp, when set,
+// is added as the program interpreter.
+func elfHeader(class elf.Class, data elf.Data, machine elf.Machine, interp string) []byte {
+	var order binary.ByteOrder = binary.LittleEndian
+	if data == elf.ELFDATA2MSB {
+		order = binary.BigEndian
+	}
+	var ident [elf.EI_NIDENT]byte
+	copy(ident[:], elf.ELFMAG)
+	ident[elf.EI_CLASS], ident[elf.EI_DATA], ident[elf.EI_VERSION] = byte(class), byte(data), byte(elf.EV_CURRENT)
+
+	buf := &bytes.Buffer{}
+	if class == elf.ELFCLASS32 {
+		binary.Write(buf, order, elf.Header32{
+			Ident: ident, Type: uint16(elf.ET_EXEC), Machine: uint16(machine), Version: uint32(elf.EV_CURRENT),
+			Ehsize: 52, Phentsize: 32, Shentsize: 40,
+		})
+		return buf.Bytes()
+	}
+
+	header := elf.Header64{
+		Ident: ident, Type: uint16(elf.ET_EXEC), Machine: uint16(machine), Version: uint32(elf.EV_CURRENT),
+		Ehsize: 64, Phentsize: 56, Shentsize: 64,
+	}
+	if interp != "" {
+		header.Phoff, header.Phnum = 64, 1
+	}
+	binary.Write(buf, order, header)
+	if interp != "" {
+		binary.Write(buf, order, elf.Prog64{
+			Type: uint32(elf.PT_INTERP), Off: 64 + 56, Filesz: uint64(len(interp) + 1), Memsz: uint64(len(interp) + 1),
+		})
+		buf.WriteString(interp + "\x00")
+	}
+	return buf.Bytes()
+}
+
+// peHeader returns a PE image with a file header and no sections
+func peHeader(machine uint16) []byte {
+	buf := &bytes.Buffer{}
+	dos := make([]byte, 0x40)
+	copy(dos, "MZ")
+	binary.LittleEndian.PutUint32(dos[0x3c:], 0x40)
+	buf.Write(dos)
+	buf.WriteString("PE\x00\x00")
+	binary.Write(buf, binary.LittleEndian, pe.FileHeader{Machine: machine})
+	buf.Write(make([]byte, 0x100)) // debug/pe reads past the header
+	return buf.Bytes()
+}
+
+// machoHeader returns a 64-bit Mach-O header with no load commands
+func machoHeader(cpu macho.Cpu) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, macho.FileHeader{Magic: macho.Magic64, Cpu: cpu, Type: macho.TypeExec})
+	buf.Write(make([]byte, 4)) // reserved
+	return buf.Bytes()
+}
+
+// machoFat returns a universal binary holding one slice per CPU
+func machoFat(cpus ...macho.Cpu) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.BigEndian, []uint32{macho.MagicFat, uint32(len(cpus))})
+	offset := uint32(0x1000)
+	slices := make([][]byte, len(cpus))
+	for i, cpu := range cpus {
+		slices[i] = machoHeader(cpu)
+		binary.Write(buf, binary.BigEndian, macho.FatArchHeader{Cpu: cpu, Offset: offset, Size: uint32(len(slices[i])), Align: 12})
+		offset += 0x1000
+	}
+	for i, s := range slices {
+		buf.Write(make([]byte, 0x1000*(i+1)-buf.Len()))
+		buf.Write(s)
+	}
+	return buf.Bytes()
+}
+
+func writeLauncher(t *testing.T, content []byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bin", "java"), content, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"elf x64", elfHeader(elf.ELFCLASS64, elf.ELFDATA2LSB, elf.EM_X86_64, ""), X64},
+		{"elf aarch64", elfHeader(elf.ELFCLASS64, elf.ELFDATA2LSB, elf.EM_AARCH64, ""), AArch64},
+		{"elf ppc64le", elfHeader(elf.ELFCLASS64, elf.ELFDATA2LSB, elf.EM_PPC64, ""), PPC64LE},
+		{"elf ppc64 big endian", elfHeader(elf.ELFCLASS64, elf.ELFDATA2MSB, elf.EM_PPC64, ""), Unknown},
+		{"elf s390x", elfHeader(elf.ELFCLASS64, elf.ELFDATA2MSB, elf.EM_S390, ""), S390X},
+		{"elf riscv64", elfHeader(elf.ELFCLASS64, elf.ELFDATA2LSB, elf.EM_RISCV, ""), RISCV64},
+		{"elf x86", elfHeader(elf.ELFCLASS32, elf.ELFDATA2LSB, elf.EM_386, ""), X86},
+		{"elf arm", elfHeader(elf.ELFCLASS32, elf.ELFDATA2LSB, elf.EM_ARM, ""), ARM},
+		{"elf mips", elfHeader(elf.ELFCLASS32, elf.ELFDATA2LSB, elf.EM_MIPS, ""), Unknown},
+		{"pe x64", peHeader(pe.IMAGE_FILE_MACHINE_AMD64), X64},
+		{"pe x86", peHeader(pe.IMAGE_FILE_MACHINE_I386), X86},
+		{"pe arm64", peHeader(pe.IMAGE_FILE_MACHINE_ARM64), AArch64},
+		{"macho x64", machoHeader(macho.CpuAmd64), X64},
+		{"macho arm64", machoHeader(macho.CpuArm64), AArch64},
+		{"macho universal", machoFat(macho.CpuPpc, macho.CpuArm64), AArch64},
+		{"not an executable", []byte("#!/bin/sh\nexec java \"$@\"\n"), Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeLauncher(t, tt.content)
+			if got := Detect(dir); got != tt.want {
+				t.Errorf("Detect(dir) = %q, want %q", got, tt.want)
+			}
+			if got := Detect(filepath.Join(dir, "bin", "java")); got != tt.want {
+				t.Errorf("Detect(launcher) = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectUniversalPrefersHost(t *testing.T) {
+	dir := writeLauncher(t, machoFat(macho.CpuAmd64, macho.CpuArm64))
+	want := X64
+	if Host() == AArch64 {
+		want = AArch64
+	}
+	if got := Detect(dir); got != want {
+		t.Errorf("Detect = %q, want %q", got, want)
+	}
+}
+
+func TestDetectMissing(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{filepath.Join(dir, "nope"), dir} {
+		if got := Detect(path); got != Unknown {
+			t.Errorf("Detect(%s) = %q, want %q", path, got, Unknown)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"amd64", X64},
+		{"x86_64", X64},
+		{"64", X64},
+		{"386", X86},
+		{"i686", X86},
+		{"32", X86},
+		{"arm64", AArch64},
+		{" AArch64 ", AArch64},
+		{"arm", ARM},
+		{"armv7l", ARM},
+		{"ppc64le", PPC64LE},
+		{"s390x", S390X},
+		{"riscv64", RISCV64},
+		{"mips", Unknown},
+		{"", Unknown},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package arch
+
+import (
+	"debug/elf"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// C libraries a Linux build of Java can be linked against
+const (
+	Glibc = "glibc"
+	Musl  = "musl"
+)
+
+// HostLibc reports the C library of the host. It is empty outside Linux.
+func HostLibc() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+
+	// The dynamic loader of the system shell is the most reliable signal
+	for _, p := range []string{"/bin/sh", "/usr/bin/env"} {
+		if l := Libc(p); l != "" {
+			return l
+		}
+	}
+
+	// musl's ldd prints its banner to stderr and exits non-zero, so ignore the error
+	out, _ := exec.Command("ldd", "--version").CombinedOutput()
+	if strings.Contains(strings.ToLower(string(out)), "musl") {
+		return Musl
+	}
+	if matches, _ := filepath.Glob("/lib/ld-musl-*.so.1"); len(matches) > 0 {
+		if _, err := os.Stat("/lib/libc.so.6"); err != nil {
+			return Musl
+		}
+	}
+	return Glibc
+}
+
+// Libc reports which C library a dynamically linked ELF executable expects,
+// based on its program interpreter. path may be an install directory. It is
+// empty for static, non-ELF or unreadable files.
+func Libc(path string) string {
+	interp := Interpreter(path)
+	switch {
+	case interp == "":
+		return ""
+	case strings.Contains(interp, "ld-musl"):
+		return Musl
+	default:
+		return Glibc
+	}
+}
+
+// Interpreter returns the ELF program interpreter (dynamic loader) of an executable
+func Interpreter(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = launcher(path)
+	}
+	if path == "" {
+		return ""
+	}
+
+	f, err := elf.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+		data, err := io.ReadAll(prog.Open())
+		if err != nil {
+			return ""
+		}
+		return strings.TrimRight(string(data), "\x00")
+	}
+	return ""
+}
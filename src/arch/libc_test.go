@@ -0,0 +1,37 @@
+package arch
+
+import (
+	"debug/elf"
+	"testing"
+)
+
+func TestLibc(t *testing.T) {
+	tests := []struct {
+		name   string
+		interp string
+		want   string
+	}{
+		{"glibc", "/lib64/ld-linux-x86-64.so.2", Glibc},
+		{"glibc aarch64", "/lib/ld-linux-aarch64.so.1", Glibc},
+		{"musl", "/lib/ld-musl-x86_64.so.1", Musl},
+		{"static", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeLauncher(t, elfHeader(elf.ELFCLASS64, elf.ELFDATA2LSB, elf.EM_X86_64, tt.interp))
+			if got := Interpreter(dir); got != tt.interp {
+				t.Errorf("Interpreter = %q, want %q", got, tt.interp)
+			}
+			if got := Libc(dir); got != tt.want {
+				t.Errorf("Libc = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLibcNotELF(t *testing.T) {
+	dir := writeLauncher(t, peHeader(0x8664))
+	if got := Libc(dir); got != "" {
+		t.Errorf("Libc = %q, want empty", got)
+	}
+}
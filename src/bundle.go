@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"jdkvm/arch"
+	"jdkvm/cli"
+	"jdkvm/java"
+	"jdkvm/web"
+)
+
+// bundle packages Java archives for air-gapped machines and installs them:
+//
+//	jdkvm bundle create 17 21 [--os linux] [--arch x64] [--image jre] -o jdks.tar
+//	jdkvm bundle install jdks.tar [17 ...] [--arch x64] [--trust-bundle]
+//	jdkvm bundle list jdks.tar
+func bundle(args []string, cpuarch string, osName string, image string, target string, trust bool) error {
+	action := ""
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	switch action {
+	case "create":
+		versions := args
+		if target == "" || len(versions) == 0 {
+			return cli.Usage("bundle create needs at least one version and -o <file>")
+		}
+		if cpuarch == "" {
+			cpuarch = arch.Validate(env.arch)
+		}
+		if image == "" {
+			image = java.ImageJDK
+		}
+		if err := web.CreateBundle(target, versions, osName, cpuarch, image); err != nil {
+			os.Remove(target)
+			return fmt.Errorf("failed to create bundle: %w", err)
+		}
+		fmt.Printf("Bundle written to %s\n", target)
+	case "install":
+		if len(args) == 0 {
+			return cli.Usage("bundle install needs a bundle file")
+		}
+		if manifest, err := web.ReadBundleManifest(args[0]); err == nil {
+			for version, info := range manifest.Catalog {
+				if len(args) == 1 || slices.Contains(args[1:], version) {
+					if err := enforcePolicy(info.VendorName(), info.Latest); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if err := web.InstallBundle(env.root, args[0], args[1:], cpuarch, trust); err != nil {
+			return fmt.Errorf("failed to install from bundle: %w", err)
+		}
+	case "list":
+		if len(args) == 0 {
+			return cli.Usage("bundle list needs a bundle file")
+		}
+		manifest, err := web.ReadBundleManifest(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		fmt.Printf("\nBundle created %s:\n", manifest.Created)
+		for _, artifact := range manifest.Artifacts {
+			fmt.Printf("  %-20s %-14s %-8s %s\n", manifest.Catalog[artifact.Version].Latest, artifact.OS, artifact.Arch, artifact.Image)
+		}
+	default:
+		return unknownAction(action)
+	}
+	return nil
+}
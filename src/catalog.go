@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jdkvm/cli"
+	"jdkvm/web"
+)
+
+// catalog inspects the catalog of Java releases:
+//
+//	jdkvm catalog where
+//	jdkvm catalog validate <file>
+//	jdkvm catalog upgrade <file> [output]
+//	jdkvm catalog update [url]
+//	jdkvm catalog keygen <private-key-file>
+//	jdkvm catalog sign <file> <private-key-file>
+func catalog(args []string) error {
+	action := ""
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "where":
+		for _, c := range web.CatalogReport() {
+			marker := " "
+			if c.Used {
+				marker = "*"
+			}
+			location := c.Location
+			if location == "" {
+				location = "-"
+			}
+			fmt.Printf("%s %-9s %s\n    %s\n", marker, c.Source, location, c.Status)
+		}
+		fmt.Println("\nSources are tried from the top; * marks the catalog in use.")
+	case "validate":
+		if len(args) < 2 {
+			return cli.Usage("catalog validate needs a file")
+		}
+		return validateCatalog(args[1])
+	case "upgrade":
+		if len(args) < 2 {
+			return cli.Usage("catalog upgrade needs a file")
+		}
+		output := args[1]
+		if len(args) > 2 {
+			output = args[2]
+		}
+		return upgradeCatalog(args[1], output)
+	case "update":
+		catalogURL := env.config.Get("catalog_update_url")
+		if len(args) > 1 {
+			catalogURL = args[1]
+		}
+		return updateCatalog(catalogURL)
+	case "keygen":
+		if len(args) < 2 {
+			return cli.Usage("catalog keygen needs a private key file")
+		}
+		return generateCatalogKey(args[1])
+	case "sign":
+		if len(args) < 3 {
+			return cli.Usage("catalog sign needs a file and a private key file")
+		}
+		return signCatalog(args[1], args[2])
+	default:
+		return unknownAction(action)
+	}
+	return nil
+}
+
+// signedCatalogFile is where catalog update stores the signed catalog. It is
+// kept with the installs rather than in the cache, so clearing the cache
+// cannot undo the rollback protection.
+func signedCatalogFile() string {
+	return filepath.Join(env.root, "signed-catalog.json")
+}
+
+// updateCatalog fetches and stores the signed catalog
+func updateCatalog(catalogURL string) error {
+	if catalogURL == "" {
+		return errors.New("no catalog to update from. Set one with: jdkvm config set catalog_update_url <url>")
+	}
+	publicKey := env.config.Get("catalog_public_key")
+	if publicKey == "" {
+		return errors.New("signed catalogs need a pinned key. Set it with: jdkvm config set catalog_public_key <base64 key>")
+	}
+
+	update, err := web.UpdateCatalog(catalogURL, publicKey, signedCatalogFile())
+	if err != nil {
+		return fmt.Errorf("catalog update failed: %w", err)
+	}
+	if !update.Updated {
+		fmt.Printf("The catalog is up to date (published %s)\n", update.Published)
+		return nil
+	}
+	if update.Previous != "" {
+		fmt.Printf("Updated the catalog to the one published %s (was %s)\n", update.Published, update.Previous)
+	} else {
+		fmt.Printf("Stored the catalog published %s\n", update.Published)
+	}
+	return nil
+}
+
+// generateCatalogKey writes a new private key for signing catalogs and
+// prints the public key machines should pin
+func generateCatalogKey(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	public, private, err := web.GenerateCatalogKey()
+	if err == nil {
+		err = os.WriteFile(path, []byte(private+"\n"), 0600)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote the private key to %s; keep it secret.\n", path)
+	fmt.Printf("Public key: %s\n", public)
+	fmt.Printf("Pin it on every machine with: jdkvm config set --system catalog_public_key %s\n", public)
+	return nil
+}
+
+// signCatalog writes <file>.sig next to a catalog
+func signCatalog(path string, keyFile string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return err
+	}
+	signature, err := web.SignCatalog(content, strings.TrimSpace(string(key)))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := os.WriteFile(path+web.SignatureSuffix, []byte(signature+"\n"), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s%s\n", path, web.SignatureSuffix)
+	return nil
+}
+
+// validateCatalog prints every schema problem of a catalog file and fails
+// with the verification status when there is any
+func validateCatalog(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	c, version, err := web.DecodeCatalog(content)
+	if err != nil {
+		return cli.Exit(cli.ExitVerification, "%s: %v", path, err)
+	}
+	if version < web.CatalogSchemaVersion {
+		fmt.Printf("%s uses schema %d; it is upgraded when loaded (jdkvm catalog upgrade %s rewrites it)\n", path, version, path)
+	}
+
+	errs := web.ValidateCatalog(c)
+	for _, e := range errs {
+		fmt.Printf("%s: %v\n", path, e)
+	}
+	if len(errs) > 0 {
+		return cli.Exit(cli.ExitVerification, "%d problems found", len(errs))
+	}
+
+	releases, artifacts := 0, 0
+	for _, fr := range c.Versions {
+		releases += len(fr.Releases)
+		for _, r := range fr.Releases {
+			artifacts += len(r.Artifacts)
+		}
+	}
+	fmt.Printf("%s is valid: %d feature releases, %d releases, %d artifacts\n", path, len(c.Versions), releases, artifacts)
+	return nil
+}
+
+// upgradeCatalog rewrites a catalog in the current schema
+func upgradeCatalog(path string, output string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	c, version, err := web.ParseCatalog(content)
+	if err != nil {
+		return cli.Exit(cli.ExitVerification, "%s: %v", path, err)
+	}
+	if version == web.CatalogSchemaVersion && output == path {
+		fmt.Printf("%s already uses schema %d\n", path, version)
+		return nil
+	}
+
+	upgraded, _ := json.MarshalIndent(c, "", "  ")
+	if err := os.WriteFile(output, append(upgraded, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s in schema %d (was %d)\n", output, web.CatalogSchemaVersion, version)
+	return nil
+}
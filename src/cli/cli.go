@@ -0,0 +1,458 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Exit statuses. They are part of the command line interface: scripts and CI
+// rely on them, so existing values never change meaning.
+const (
+	ExitOK = 0
+	// ExitFailure is any failure without a more specific status
+	ExitFailure = 1
+	// ExitUsage means the command line was wrong: unknown command or flag, missing argument
+	ExitUsage = 2
+	// ExitNotInstalled means the requested version is not installed, or not in the catalog
+	ExitNotInstalled = 3
+	// ExitNetwork means a download or request failed
+	ExitNetwork = 4
+	// ExitVerification means a checksum, signature or architecture check failed
+	ExitVerification = 5
+)
+
+// Error is a failure with the exit status it should end the program with
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Exit returns an error that ends the program with code
+func Exit(code int, format string, args ...interface{}) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Usage returns a usage error; the program prints the command's usage line after it
+func Usage(format string, args ...interface{}) error {
+	return Exit(ExitUsage, format, args...)
+}
+
+// reportedError is an error the command has already shown to the user
+type reportedError struct {
+	err error
+}
+
+func (e *reportedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *reportedError) Unwrap() error {
+	return e.err
+}
+
+// Reported marks an error the command has already printed, e.g. as a JSON
+// document; it still sets the exit status but is not printed again
+func Reported(err error) error {
+	return &reportedError{err: err}
+}
+
+// Flag is an option. Flags without a Value placeholder are booleans.
+type Flag struct {
+	Name  string
+	Short string
+	// Value names the flag's argument in help, e.g. "<arch>"
+	Value string
+	Usage string
+	// Complete returns the flag's values for shell completion
+	Complete func() []string
+}
+
+func (f Flag) isBool() bool {
+	return f.Value == ""
+}
+
+func (f Flag) label() string {
+	label := "--" + f.Name
+	if f.Short != "" {
+		label = "-" + f.Short + ", " + label
+	}
+	if !f.isBool() {
+		label += " " + f.Value
+	}
+	return label
+}
+
+// Command is a jdkvm command
+type Command struct {
+	Name    string
+	Aliases []string
+	// Args describes the positional arguments in help, e.g. "<version> [arch]"
+	Args    string
+	Summary string
+	// Help is printed by help <command> after the usage line
+	Help  string
+	Flags []Flag
+	// Passthrough is the number of positional arguments after which the
+	// rest of the command line belongs to another program, flags included
+	Passthrough int
+	// Progress marks commands whose normal output --quiet suppresses
+	Progress bool
+	Hidden   bool
+	// Complete returns the candidates for the next positional argument,
+	// given the ones before it, for shell completion
+	Complete func(args []string) []string
+	Run      func(c *Context) error
+}
+
+// Names returns the command's name followed by its aliases
+func (cmd *Command) Names() []string {
+	return append([]string{cmd.Name}, cmd.Aliases...)
+}
+
+// App is the command tree with the flags every command accepts
+type App struct {
+	Name     string
+	Usage    string
+	Flags    []Flag
+	Commands []*Command
+	Examples []string
+	// Footer is printed at the end of the main help
+	Footer string
+	// Before runs after parsing, before the command
+	Before func(c *Context) error
+	// ExitCode maps errors that are not *Error to an exit status
+	ExitCode func(err error) int
+	// Stderr receives errors and usage
+	Stderr io.Writer
+}
+
+// Context is a parsed command line
+type Context struct {
+	App     *App
+	Command *Command
+	// Args are the positional arguments
+	Args   []string
+	values map[string][]string
+}
+
+// Arg returns the i-th positional argument, or "" when there is none
+func (c *Context) Arg(i int) string {
+	if i < len(c.Args) {
+		return c.Args[i]
+	}
+	return ""
+}
+
+// String returns the last value given for a flag
+func (c *Context) String(name string) string {
+	values := c.values[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// Strings returns every value given for a repeatable flag
+func (c *Context) Strings(name string) []string {
+	return c.values[name]
+}
+
+// Bool reports whether a boolean flag was given
+func (c *Context) Bool(name string) bool {
+	return c.String(name) == "true"
+}
+
+// Find returns the command with a name or alias
+func (a *App) Find(name string) *Command {
+	for _, cmd := range a.Commands {
+		for _, n := range cmd.Names() {
+			if n == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// Run parses args (without the program name), runs the command and returns
+// the exit status
+func (a *App) Run(args []string) int {
+	if a.Stderr == nil {
+		a.Stderr = os.Stderr
+	}
+	c, err := a.parse(args)
+	if err == nil && c.Command == nil {
+		a.PrintHelp(os.Stdout)
+		return ExitOK
+	}
+	if err == nil && c.Bool("help") {
+		a.PrintCommandHelp(os.Stdout, c.Command)
+		return ExitOK
+	}
+	if err == nil && a.Before != nil {
+		err = a.Before(c)
+	}
+	if err == nil {
+		err = c.Command.Run(c)
+	}
+	if err == nil {
+		return ExitOK
+	}
+
+	code := ExitFailure
+	var exitErr *Error
+	if errors.As(err, &exitErr) {
+		code = exitErr.Code
+	} else if a.ExitCode != nil {
+		code = a.ExitCode(err)
+	}
+	var reported *reportedError
+	if !errors.As(err, &reported) {
+		fmt.Fprintln(a.Stderr, err)
+	}
+	if code == ExitUsage && c != nil && c.Command != nil {
+		fmt.Fprintf(a.Stderr, "Usage: %s\nRun '%s help %s' for details.\n", a.usageLine(c.Command), a.Name, c.Command.Name)
+	}
+	return code
+}
+
+// parse splits args into the command, its flags and positional arguments.
+// Flags before the command must be global; flags after it may be global or
+// the command's own.
+func (a *App) parse(args []string) (*Context, error) {
+	c := &Context{App: a, values: map[string][]string{}}
+
+	i := 0
+	for ; i < len(args); i++ {
+		if !isFlag(args[i]) {
+			break
+		}
+		next, err := a.parseFlag(c, a.Flags, args, i)
+		if err != nil {
+			return c, err
+		}
+		i = next
+	}
+	if i == len(args) {
+		return c, nil
+	}
+
+	name := args[i]
+	c.Command = a.Find(name)
+	if c.Command == nil {
+		if suggestion := a.suggestCommand(name); suggestion != "" {
+			return c, Usage("%q is not a %s command. Did you mean %q?", name, a.Name, suggestion)
+		}
+		return c, Usage("%q is not a %s command. Run '%s help' for the list of commands.", name, a.Name, a.Name)
+	}
+
+	flags := append(append([]Flag{}, a.Flags...), c.Command.Flags...)
+	for i++; i < len(args); i++ {
+		arg := args[i]
+		passthrough := c.Command.Passthrough > 0 && len(c.Args) >= c.Command.Passthrough
+		switch {
+		case arg == "--":
+			c.Args = append(c.Args, args[i+1:]...)
+			return c, nil
+		case passthrough && !isFlag(arg):
+			c.Args = append(c.Args, args[i:]...)
+			return c, nil
+		case isFlag(arg):
+			next, err := a.parseFlag(c, flags, args, i)
+			if err != nil {
+				return c, err
+			}
+			i = next
+		default:
+			c.Args = append(c.Args, arg)
+		}
+	}
+	return c, nil
+}
+
+// parseFlag records the flag at args[i] and returns the index of the last
+// argument it used
+func (a *App) parseFlag(c *Context, flags []Flag, args []string, i int) (int, error) {
+	arg := args[i]
+	name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+
+	var flag *Flag
+	for j := range flags {
+		f := &flags[j]
+		if (strings.HasPrefix(arg, "--") && f.Name == name) || (!strings.HasPrefix(arg, "--") && f.Short != "" && f.Short == name) {
+			flag = f
+			break
+		}
+	}
+	if flag == nil {
+		if suggestion := suggest(name, flagNames(flags)); suggestion != "" {
+			return i, Usage("unknown flag %s. Did you mean --%s?", arg, suggestion)
+		}
+		return i, Usage("unknown flag %s", arg)
+	}
+
+	switch {
+	case flag.isBool() && hasValue:
+		if value != "true" && value != "false" {
+			return i, Usage("--%s takes no value", flag.Name)
+		}
+	case flag.isBool():
+		value = "true"
+	case !hasValue:
+		if i+1 >= len(args) {
+			return i, Usage("--%s needs a value %s", flag.Name, flag.Value)
+		}
+		i++
+		value = args[i]
+	}
+	c.values[flag.Name] = append(c.values[flag.Name], value)
+	return i, nil
+}
+
+func isFlag(arg string) bool {
+	return len(arg) > 1 && arg[0] == '-' && arg != "--"
+}
+
+func flagNames(flags []Flag) []string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = f.Name
+	}
+	return names
+}
+
+func (a *App) suggestCommand(name string) string {
+	var names []string
+	for _, cmd := range a.Commands {
+		if !cmd.Hidden {
+			names = append(names, cmd.Names()...)
+		}
+	}
+	return suggest(name, names)
+}
+
+// suggest returns the candidate closest to a mistyped name, or "" when none
+// is close enough to be a likely typo
+func suggest(name string, candidates []string) string {
+	best, bestDistance := "", 3
+	if len(name) <= 4 {
+		bestDistance = 2
+	}
+	for _, candidate := range candidates {
+		d := distance(strings.ToLower(name), candidate)
+		if d < bestDistance || (strings.HasPrefix(candidate, name) && best == "") {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance between two strings
+func distance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// Help runs "help [command]": the list of commands, or the help of one
+func (a *App) Help(c *Context) error {
+	name := c.Arg(0)
+	if name == "" {
+		a.PrintHelp(os.Stdout)
+		return nil
+	}
+	cmd := a.Find(name)
+	if cmd == nil {
+		if suggestion := a.suggestCommand(name); suggestion != "" {
+			return Usage("%q is not a %s command. Did you mean %q?", name, a.Name, suggestion)
+		}
+		return Usage("%q is not a %s command", name, a.Name)
+	}
+	a.PrintCommandHelp(os.Stdout, cmd)
+	return nil
+}
+
+// PrintHelp prints the list of commands
+func (a *App) PrintHelp(w io.Writer) {
+	fmt.Fprintf(w, "\nUsage: %s\n\nCommands:\n", a.Usage)
+	for _, cmd := range a.Commands {
+		if cmd.Hidden {
+			continue
+		}
+		fmt.Fprintf(w, "  %-14s%s\n", strings.Join(cmd.Names(), ", "), cmd.Summary)
+	}
+	fmt.Fprintln(w, "\nGlobal options:")
+	printFlags(w, a.Flags)
+	if len(a.Examples) > 0 {
+		fmt.Fprintln(w, "\nExamples:")
+		for _, e := range a.Examples {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+	if a.Footer != "" {
+		fmt.Fprintf(w, "\n%s\n", a.Footer)
+	}
+}
+
+// PrintCommandHelp prints the usage, description and flags of a command
+func (a *App) PrintCommandHelp(w io.Writer, cmd *Command) {
+	fmt.Fprintf(w, "Usage: %s\n", a.usageLine(cmd))
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(w, "Aliases: %s\n", strings.Join(cmd.Aliases, ", "))
+	}
+	fmt.Fprintf(w, "\n%s\n", cmd.Summary)
+	if cmd.Help != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimRight(cmd.Help, "\n"))
+	}
+	if len(cmd.Flags) > 0 {
+		fmt.Fprintln(w, "\nOptions:")
+		printFlags(w, cmd.Flags)
+	}
+	fmt.Fprintln(w, "\nGlobal options:")
+	printFlags(w, a.Flags)
+}
+
+func (a *App) usageLine(cmd *Command) string {
+	line := a.Name + " " + cmd.Name
+	if cmd.Args != "" {
+		line += " " + cmd.Args
+	}
+	if len(cmd.Flags) > 0 {
+		line += " [options]"
+	}
+	return line
+}
+
+func printFlags(w io.Writer, flags []Flag) {
+	sorted := append([]Flag{}, flags...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	for _, f := range sorted {
+		fmt.Fprintf(w, "  %-24s%s\n", f.label(), f.Usage)
+	}
+}
@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testApp is a small command tree shaped like jdkvm's
+func testApp() *App {
+	versions := func(args []string) []string {
+		if len(args) == 0 {
+			return []string{"11", "17", "17.0.11+9", "21"}
+		}
+		return nil
+	}
+	return &App{
+		Name: "jdkvm",
+		Flags: []Flag{
+			{Name: "quiet", Short: "q", Usage: "Print less"},
+			{Name: "output", Short: "o", Value: "<format>", Usage: "Output format", Complete: func() []string { return []string{"text", "json"} }},
+		},
+		Commands: []*Command{
+			{
+				Name: "install", Aliases: []string{"i"}, Args: "<version>",
+				Flags: []Flag{
+					{Name: "arch", Value: "<arch>", Complete: func() []string { return []string{"x64", "aarch64"} }},
+					{Name: "yes", Short: "y"},
+					{Name: "vendor", Value: "<vendor>"},
+				},
+				Complete: versions,
+				Run: func(c *Context) error {
+					if c.Arg(0) == "" {
+						return Usage("install needs a version")
+					}
+					return nil
+				},
+			},
+			{Name: "exec", Args: "<version> -- <command>", Passthrough: 1, Complete: versions, Run: func(c *Context) error { return nil }},
+			{Name: "list", Aliases: []string{"ls"}, Run: func(c *Context) error { return nil }},
+			{Name: "__internal", Hidden: true, Run: func(c *Context) error { return nil }},
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		command string
+		posArgs []string
+		values  map[string][]string
+		wantErr string
+	}{
+		{"command", []string{"install", "17"}, "install", []string{"17"}, map[string][]string{}, ""},
+		{"alias", []string{"i", "17"}, "install", []string{"17"}, map[string][]string{}, ""},
+		{"global flag first", []string{"-q", "list"}, "list", nil, map[string][]string{"quiet": {"true"}}, ""},
+		{"flags anywhere after the command", []string{"install", "--arch", "x64", "17", "-y"}, "install", []string{"17"},
+			map[string][]string{"arch": {"x64"}, "yes": {"true"}}, ""},
+		{"flag with =", []string{"install", "--arch=aarch64", "17"}, "install", []string{"17"}, map[string][]string{"arch": {"aarch64"}}, ""},
+		{"short flag with value", []string{"-o", "json", "list"}, "list", nil, map[string][]string{"output": {"json"}}, ""},
+		{"bool flag =false", []string{"install", "--yes=false", "17"}, "install", []string{"17"}, map[string][]string{"yes": {"false"}}, ""},
+		{"repeated flag", []string{"install", "--vendor", "a", "--vendor", "b"}, "install", nil, map[string][]string{"vendor": {"a", "b"}}, ""},
+		{"double dash", []string{"install", "--", "-17"}, "install", []string{"-17"}, map[string][]string{}, ""},
+		{"passthrough", []string{"exec", "17", "java", "-version", "--quiet"}, "exec", []string{"17", "java", "-version", "--quiet"}, map[string][]string{}, ""},
+		{"flag before passthrough", []string{"exec", "-q", "17", "mvn", "-q"}, "exec", []string{"17", "mvn", "-q"}, map[string][]string{"quiet": {"true"}}, ""},
+		{"no command", []string{"-q"}, "", nil, map[string][]string{"quiet": {"true"}}, ""},
+		{"unknown command", []string{"instal", "17"}, "", nil, nil, `Did you mean "install"?`},
+		{"unrelated command", []string{"frobnicate"}, "", nil, nil, "is not a jdkvm command"},
+		{"command flag before the command", []string{"--arch", "x64", "install"}, "", nil, nil, "unknown flag --arch"},
+		{"misspelt flag", []string{"install", "--vendr", "x"}, "", nil, nil, "Did you mean --vendor?"},
+		{"missing value", []string{"install", "17", "--arch"}, "", nil, nil, "--arch needs a value <arch>"},
+		{"bool flag with value", []string{"install", "--yes=maybe"}, "", nil, nil, "--yes takes no value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := testApp().parse(tt.args)
+			if tt.wantErr != "" {
+				var exitErr *Error
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !errors.As(err, &exitErr) || exitErr.Code != ExitUsage {
+					t.Fatalf("parse error = %v, want a usage error mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			command := ""
+			if c.Command != nil {
+				command = c.Command.Name
+			}
+			if command != tt.command || !reflect.DeepEqual(c.Args, tt.posArgs) || !reflect.DeepEqual(c.values, tt.values) {
+				t.Errorf("parse = %s %q %v, want %s %q %v", command, c.Args, c.values, tt.command, tt.posArgs, tt.values)
+			}
+		})
+	}
+}
+
+func TestRunExitCodes(t *testing.T) {
+	notInstalled := errors.New("Java 8 is not installed")
+	tests := []struct {
+		name      string
+		args      []string
+		run       func(c *Context) error
+		wantCode  int
+		wantUsage bool
+		wantOut   string
+	}{
+		{"ok", []string{"install", "17"}, nil, ExitOK, false, ""},
+		{"usage error from the command", []string{"install"}, nil, ExitUsage, true, "install needs a version"},
+		{"usage error from parsing", []string{"install", "--nope"}, nil, ExitUsage, true, "unknown flag --nope"},
+		{"unknown command", []string{"nope"}, nil, ExitUsage, false, "is not a jdkvm command"},
+		{"exit code", []string{"list"}, func(c *Context) error { return Exit(ExitNetwork, "offline") }, ExitNetwork, false, "offline"},
+		{"wrapped exit code", []string{"list"}, func(c *Context) error {
+			return errors.Join(errors.New("context"), Exit(ExitVerification, "bad checksum"))
+		}, ExitVerification, false, "bad checksum"},
+		{"mapped by ExitCode", []string{"list"}, func(c *Context) error { return notInstalled }, ExitNotInstalled, false, "not installed"},
+		{"plain error", []string{"list"}, func(c *Context) error { return errors.New("boom") }, ExitFailure, false, "boom"},
+		{"reported", []string{"list"}, func(c *Context) error { return Reported(Exit(7, "already shown")) }, 7, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := testApp()
+			stderr := &bytes.Buffer{}
+			app.Stderr = stderr
+			app.ExitCode = func(err error) int {
+				if errors.Is(err, notInstalled) {
+					return ExitNotInstalled
+				}
+				return ExitFailure
+			}
+			if tt.run != nil {
+				app.Find("list").Run = tt.run
+			}
+
+			if code := app.Run(tt.args); code != tt.wantCode {
+				t.Errorf("Run = %d, want %d", code, tt.wantCode)
+			}
+			out := stderr.String()
+			if tt.wantOut == "" && out != "" {
+				t.Errorf("stderr = %q, want nothing", out)
+			}
+			if !strings.Contains(out, tt.wantOut) {
+				t.Errorf("stderr = %q, want it to mention %q", out, tt.wantOut)
+			}
+			if got := strings.Contains(out, "Usage: jdkvm"); got != tt.wantUsage {
+				t.Errorf("usage line printed = %v, want %v", got, tt.wantUsage)
+			}
+		})
+	}
+}
+
+func TestBeforeRunsFirst(t *testing.T) {
+	app := testApp()
+	app.Stderr = &bytes.Buffer{}
+	app.Before = func(c *Context) error { return Exit(ExitNetwork, "no catalog") }
+	app.Find("list").Run = func(c *Context) error {
+		t.Error("the command ran after Before failed")
+		return nil
+	}
+	if code := app.Run([]string{"list"}); code != ExitNetwork {
+		t.Errorf("Run = %d, want %d", code, ExitNetwork)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"install", "uninstall", "list", "use", "upgrade", "outdated"}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"instal", "install"},
+		{"INSTALL", "install"},
+		{"lst", "list"},
+		{"upgarde", "upgrade"},
+		{"out", "outdated"},
+		{"xyz", ""},
+		{"frobnicate", ""},
+	}
+	for _, tt := range tests {
+		if got := suggest(tt.name, candidates); got != tt.want {
+			t.Errorf("suggest(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
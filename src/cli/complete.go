@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompleteCommand is the hidden command the completion scripts call. It
+// prints the candidates for the word being typed, one per line.
+const CompleteCommand = "__complete"
+
+// Shells lists the shells completion scripts are generated for
+var Shells = []string{"bash", "zsh", "fish", "powershell"}
+
+// CompletionCommands returns the completion command, which prints a shell's
+// completion script, and the hidden command the scripts call
+func (a *App) CompletionCommands() []*Command {
+	return []*Command{
+		{
+			Name: "completion", Args: "<shell>",
+			Summary: "Print a shell completion script (" + strings.Join(Shells, ", ") + ")",
+			Help: fmt.Sprintf(`bash:       source <(%[1]s completion bash)
+zsh:        source <(%[1]s completion zsh)
+fish:       %[1]s completion fish | source
+PowerShell: %[1]s completion powershell | Out-String | Invoke-Expression
+
+Add the line to the shell's startup file to keep completion in new sessions.`, a.Name),
+			Complete: func(args []string) []string {
+				if len(args) == 0 {
+					return Shells
+				}
+				return nil
+			},
+			Run: func(c *Context) error {
+				script, err := a.CompletionScript(c.Arg(0))
+				if err != nil {
+					return err
+				}
+				fmt.Print(script)
+				return nil
+			},
+		},
+		{
+			Name:   CompleteCommand,
+			Hidden: true,
+			Flags:  []Flag{{Name: "current", Value: "<word>", Usage: "The word being completed"}},
+			Run: func(c *Context) error {
+				for _, candidate := range a.Complete(c.Args, c.String("current")) {
+					fmt.Println(candidate)
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// Complete returns the candidates for current, the word being typed, given
+// the words before it (without the program name)
+func (a *App) Complete(words []string, current string) []string {
+	var cmd *Command
+	var args []string
+	var pending *Flag
+	flags := a.Flags
+	flagsDone := false
+
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		switch {
+		case cmd != nil && cmd.Passthrough > 0 && len(args) > cmd.Passthrough:
+			// The rest belongs to the program being run
+			return nil
+		case w == "--" && !flagsDone:
+			flagsDone = true
+		case isFlag(w) && !flagsDone:
+			f := findFlag(flags, w)
+			if f != nil && !f.isBool() && !strings.Contains(w, "=") {
+				if i+1 == len(words) {
+					pending = f
+				}
+				i++
+			}
+		case cmd == nil:
+			if cmd = a.Find(w); cmd == nil {
+				return nil
+			}
+			flags = append(append([]Flag{}, a.Flags...), cmd.Flags...)
+		default:
+			args = append(args, w)
+		}
+	}
+
+	if cmd != nil && cmd.Passthrough > 0 && len(args) > cmd.Passthrough {
+		return nil
+	}
+	if pending != nil {
+		return filter(flagValues(pending), current)
+	}
+	if !flagsDone && strings.HasPrefix(current, "--") && strings.Contains(current, "=") {
+		name, _, _ := strings.Cut(current, "=")
+		f := findFlag(flags, name)
+		if f == nil || f.isBool() {
+			return nil
+		}
+		var candidates []string
+		for _, v := range flagValues(f) {
+			candidates = append(candidates, name+"="+v)
+		}
+		return filter(candidates, current)
+	}
+	if !flagsDone && strings.HasPrefix(current, "-") {
+		var candidates []string
+		for _, f := range flags {
+			candidates = append(candidates, "--"+f.Name)
+		}
+		return filter(candidates, current)
+	}
+	if cmd == nil {
+		var candidates []string
+		for _, c := range a.Commands {
+			if !c.Hidden {
+				candidates = append(candidates, c.Names()...)
+			}
+		}
+		return filter(candidates, current)
+	}
+	if (cmd.Passthrough > 0 && len(args) >= cmd.Passthrough) || cmd.Complete == nil {
+		return nil
+	}
+	return filter(cmd.Complete(args), current)
+}
+
+// findFlag returns the flag an argument such as --arch, --arch=x64 or -y names
+func findFlag(flags []Flag, arg string) *Flag {
+	name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	long := strings.HasPrefix(arg, "--")
+	for i := range flags {
+		if (long && flags[i].Name == name) || (!long && flags[i].Short != "" && flags[i].Short == name) {
+			return &flags[i]
+		}
+	}
+	return nil
+}
+
+func flagValues(f *Flag) []string {
+	if f.Complete == nil {
+		return nil
+	}
+	return f.Complete()
+}
+
+// filter returns the candidates starting with prefix, without duplicates
+func filter(candidates []string, prefix string) []string {
+	seen := map[string]bool{}
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) && !seen[c] {
+			seen[c] = true
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// CompletionScript returns the completion script for a shell. The scripts
+// ask the program for candidates, so they never go stale; when there are
+// none they fall back to file names.
+func (a *App) CompletionScript(shell string) (string, error) {
+	var script string
+	switch strings.ToLower(shell) {
+	case "bash":
+		script = bashScript
+	case "zsh":
+		script = zshScript
+	case "fish":
+		script = fishScript
+	case "powershell", "pwsh":
+		script = powershellScript
+	case "":
+		return "", Usage("completion needs a shell: %s", strings.Join(Shells, ", "))
+	default:
+		return "", Usage("unsupported shell %q; use one of: %s", shell, strings.Join(Shells, ", "))
+	}
+	r := strings.NewReplacer("{{name}}", a.Name, "{{func}}", strings.ReplaceAll(a.Name, "-", "_"), "{{complete}}", CompleteCommand)
+	return r.Replace(script), nil
+}
+
+const bashScript = `# bash completion for {{name}}
+_{{func}}() {
+    local IFS=$'\n'
+    COMPREPLY=($({{name}} {{complete}} --current="${COMP_WORDS[COMP_CWORD]}" -- "${COMP_WORDS[@]:1:COMP_CWORD-1}" 2>/dev/null))
+}
+complete -o default -F _{{func}} {{name}}
+`
+
+const zshScript = `#compdef {{name}}
+# zsh completion for {{name}}
+_{{func}}() {
+    local -a candidates
+    candidates=("${(@f)$({{name}} {{complete}} --current="${words[CURRENT]}" -- "${(@)words[2,CURRENT-1]}" 2>/dev/null)}")
+    candidates=(${candidates:#})
+    if (( ${#candidates} )); then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+if [ "$funcstack[1]" = "_{{func}}" ]; then
+    _{{func}} "$@"
+else
+    compdef _{{func}} {{name}}
+fi
+`
+
+const fishScript = `# fish completion for {{name}}
+function __{{func}}_complete
+    set -l words (commandline -opc)
+    set -e words[1]
+    set -l candidates ({{name}} {{complete}} --current=(commandline -ct) -- $words 2>/dev/null)
+    if test (count $candidates) -gt 0
+        printf '%s\n' $candidates
+    else
+        __fish_complete_path (commandline -ct)
+    end
+end
+complete -c {{name}} -f -a '(__{{func}}_complete)'
+`
+
+const powershellScript = `# PowerShell completion for {{name}}
+Register-ArgumentCompleter -Native -CommandName '{{name}}', '{{name}}.exe' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 |
+        Where-Object { $_.Extent.EndOffset -lt $cursorPosition } |
+        ForEach-Object { $_.ToString() })
+    & '{{name}}' {{complete}} "--current=$wordToComplete" -- @words 2>$null | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`
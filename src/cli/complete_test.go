@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		name    string
+		words   []string
+		current string
+		want    []string
+	}{
+		{"commands", nil, "", []string{"install", "i", "exec", "list", "ls"}},
+		{"command prefix", nil, "l", []string{"list", "ls"}},
+		{"hidden commands are left out", nil, "__", nil},
+		{"global flags", nil, "--", []string{"--quiet", "--output"}},
+		{"command flags", []string{"install"}, "--", []string{"--quiet", "--output", "--arch", "--yes", "--vendor"}},
+		{"flag prefix", []string{"install"}, "--a", []string{"--arch"}},
+		{"flag value", []string{"install", "--arch"}, "", []string{"x64", "aarch64"}},
+		{"flag value prefix", []string{"install", "--arch"}, "a", []string{"aarch64"}},
+		{"short flag value", []string{"-o"}, "j", []string{"json"}},
+		{"flag value with =", []string{"install"}, "--arch=x", []string{"--arch=x64"}},
+		{"bool flag with =", []string{"install"}, "--yes=", nil},
+		{"flag without completions", []string{"install", "--vendor"}, "", nil},
+		{"first argument", []string{"install"}, "17", []string{"17", "17.0.11+9"}},
+		{"after a flag value", []string{"install", "--arch", "x64"}, "2", []string{"21"}},
+		{"after a flag=value", []string{"install", "--arch=x64"}, "1", []string{"11", "17", "17.0.11+9"}},
+		{"after a bool flag", []string{"-q", "install", "-y"}, "2", []string{"21"}},
+		{"alias", []string{"i"}, "2", []string{"21"}},
+		{"no more arguments", []string{"install", "17"}, "", nil},
+		{"after --", []string{"install", "--"}, "--", nil},
+		{"passthrough version", []string{"exec"}, "1", []string{"11", "17", "17.0.11+9"}},
+		{"passthrough command", []string{"exec", "17"}, "", nil},
+		{"passthrough flags", []string{"exec", "17", "java"}, "--", nil},
+		{"unknown command", []string{"frobnicate"}, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testApp().Complete(tt.words, tt.current)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Complete(%q, %q) = %q, want %q", tt.words, tt.current, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteCommandLine(t *testing.T) {
+	app := testApp()
+	app.Commands = append(app.Commands, app.CompletionCommands()...)
+
+	// The scripts pass the words typed so far after --, so they are never
+	// taken for flags of the hidden command
+	c, err := app.parse([]string{CompleteCommand, "--current=--a", "--", "install", "--yes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Command.Name != CompleteCommand || c.String("current") != "--a" || !reflect.DeepEqual(c.Args, []string{"install", "--yes"}) {
+		t.Errorf("parse = %s %q %q", c.Command.Name, c.String("current"), c.Args)
+	}
+	if got := app.Complete(c.Args, c.String("current")); !reflect.DeepEqual(got, []string{"--arch"}) {
+		t.Errorf("Complete = %q", got)
+	}
+}
+
+func TestCompletionScript(t *testing.T) {
+	app := testApp()
+	for _, shell := range append(Shells, "pwsh", "BASH") {
+		script, err := app.CompletionScript(shell)
+		if err != nil {
+			t.Errorf("CompletionScript(%s): %v", shell, err)
+			continue
+		}
+		if strings.Contains(script, "{{") || !strings.Contains(script, "jdkvm "+CompleteCommand) && !strings.Contains(script, "'jdkvm' "+CompleteCommand) {
+			t.Errorf("CompletionScript(%s) is not filled in:\n%s", shell, script)
+		}
+	}
+	for _, shell := range []string{"", "tcsh"} {
+		if _, err := app.CompletionScript(shell); err == nil {
+			t.Errorf("CompletionScript(%q) succeeded", shell)
+		}
+	}
+}
@@ -0,0 +1,562 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+	"jdkvm/arch"
+	"jdkvm/cli"
+	"jdkvm/java"
+	"jdkvm/web"
+)
+
+// assumeYes answers every confirmation with yes (--yes)
+var assumeYes bool
+
+var (
+	archFlag   = cli.Flag{Name: "arch", Value: "<arch>", Usage: "CPU architecture: " + strings.Join(arch.Names(), ", "), Complete: arch.Names}
+	vendorFlag = cli.Flag{Name: "vendor", Value: "<vendor>", Usage: "Only consider builds from a vendor, e.g. temurin or zulu", Complete: installedVendors}
+	imageFlag  = cli.Flag{Name: "image", Value: "<image>", Usage: "Image type: " + strings.Join(java.Images, ", "), Complete: func() []string { return java.Images }}
+	osFlag     = cli.Flag{Name: "os", Value: "<os>", Usage: "Operating system of the build, e.g. linux, alpine-linux, mac, windows", Complete: osNames}
+	// catalogVendorFlag is --vendor for builds that are not installed yet
+	catalogVendorFlag = cli.Flag{Name: "vendor", Value: "<vendor>", Usage: vendorFlag.Usage, Complete: catalogVendors}
+)
+
+// newApp returns the jdkvm command tree
+func newApp() *cli.App {
+	app := &cli.App{
+		Name:  "jdkvm",
+		Usage: "jdkvm [options] <command> [arguments]",
+		Flags: []cli.Flag{
+			{Name: "help", Short: "h", Usage: "Show help for a command"},
+			{Name: "output", Value: "<format>", Usage: "Output format: text or json", Complete: func() []string { return []string{outputText, outputJSON} }},
+			{Name: "set", Value: "<key=value>", Usage: "Override a setting for this run; may be repeated", Complete: settingOverrides},
+			{Name: "yes", Short: "y", Usage: "Answer yes to confirmations"},
+			{Name: "quiet", Short: "q", Usage: "Only print errors while installing, switching or removing versions"},
+			{Name: "verbose", Short: "v", Usage: "Explain which catalog and mirrors are used, on stderr"},
+		},
+		Examples: []string{
+			"jdkvm install 17",
+			"jdkvm install 21 aarch64",
+			"jdkvm install 17 --os alpine-linux",
+			"jdkvm install 17 --image jre",
+			"jdkvm use 17",
+			"jdkvm use 17 --vendor zulu",
+			"jdkvm exec 21 --arch x64 -- java -version",
+			"jdkvm list available --all",
+			"jdkvm proxy http://127.0.0.1:7890",
+			"jdkvm proxy test https://github.com/",
+			"jdkvm config list --show-origin",
+			"jdkvm --set proxy=direct install 17",
+			"jdkvm java_mirror tuna",
+			"jdkvm java_mirror http://lan-host:8080/,tuna",
+			"jdkvm uninstall 17 --yes",
+			"jdkvm list --output json",
+			"jdkvm bundle create 17 21 --os linux --arch x64 -o jdks.tar",
+			"jdkvm serve --addr :8080",
+			"jdkvm upgrade --all --remove-old",
+			"jdkvm help install",
+		},
+		Footer: `Exit status:
+  0  success
+  1  failure
+  2  usage error: unknown command or option, missing argument
+  3  the version is not installed, or not in the catalog
+  4  network failure
+  5  verification failure: checksum, signature or architecture
+
+Run 'jdkvm help <command>' for the options of a command.`,
+		Before:   setup,
+		ExitCode: exitCode,
+	}
+
+	app.Commands = []*cli.Command{
+		{
+			Name: "install", Aliases: []string{"i"}, Args: "<version> [arch]", Progress: true,
+			Summary: "Install a Java version",
+			Help: `The version is a feature release (17), an exact release (17.0.9+9)
+or an exact release without its build (17.0.9).
+Without --arch the configured or host architecture is used. Without a
+version, a terminal shows the catalog's releases to choose from.`,
+			Flags:    []cli.Flag{archFlag, catalogVendorFlag, osFlag, imageFlag},
+			Complete: completeVersion(catalogVersions),
+			Run: func(c *cli.Context) error {
+				cpuarch, err := archOption(c)
+				if err != nil {
+					return err
+				}
+				image, err := imageOption(c)
+				if err != nil {
+					return err
+				}
+				osName := c.String("os")
+				if osName == "" {
+					osName = web.HostOS()
+				}
+				version, vendor := c.Arg(0), vendorOption(c)
+				if canPick(version) {
+					if version, vendor, err = pickRelease(vendor); err != nil {
+						return err
+					}
+				}
+				return install(version, cpuarch, vendor, osName, image)
+			},
+		},
+		{
+			Name: "use", Aliases: []string{"u"}, Args: "<version> [arch]", Progress: true,
+			Summary: "Switch to an installed Java version",
+			Help: `Sets JAVA_HOME and puts the version's bin directory first on PATH.
+Without a version, a terminal shows the installed versions to choose from.`,
+			Flags:    []cli.Flag{archFlag, vendorFlag, imageFlag},
+			Complete: completeVersion(installedVersions),
+			Run: func(c *cli.Context) error {
+				q, err := installQuery(c)
+				if err != nil {
+					return err
+				}
+				if canPick(c.Arg(0)) {
+					inst, err := pickInstalled(q)
+					if err != nil {
+						return err
+					}
+					return use(inst.Version, java.Query{Vendor: inst.Vendor, Arch: inst.Arch, Image: inst.Image})
+				}
+				return use(c.Arg(0), q)
+			},
+		},
+		{
+			Name: "uninstall", Aliases: []string{"rm"}, Args: "<version> [arch]", Progress: true,
+			Summary:  "Uninstall a Java version",
+			Help:     "Asks for confirmation in a terminal unless --yes is given.",
+			Flags:    []cli.Flag{archFlag, vendorFlag, imageFlag},
+			Complete: completeVersion(installedVersions),
+			Run: func(c *cli.Context) error {
+				q, err := installQuery(c)
+				if err != nil {
+					return err
+				}
+				return uninstall(c.Arg(0), q)
+			},
+		},
+		{
+			Name: "list", Aliases: []string{"ls"}, Args: "[installed|available]",
+			Summary:  "List installed or available Java versions",
+			Flags:    []cli.Flag{{Name: "all", Usage: "Also list end-of-life versions without long-term support"}},
+			Complete: completeActions("installed", "available"),
+			Run: func(c *cli.Context) error {
+				return list(c.Arg(0), c.Bool("all"))
+			},
+		},
+		{
+			Name:    "current",
+			Summary: "Show the current Java version",
+			Run: func(c *cli.Context) error {
+				current()
+				return nil
+			},
+		},
+		{
+			Name:    "outdated",
+			Summary: "Show installed versions behind their newest patch release",
+			Help:    "Exits with status 1 when an install is outdated or end of life, so CI can gate on it.",
+			Run: func(c *cli.Context) error {
+				return outdated()
+			},
+		},
+		{
+			Name: "upgrade", Args: "[version]", Progress: true,
+			Summary: "Upgrade installed versions to their newest patch release",
+			Flags: []cli.Flag{
+				{Name: "all", Usage: "Upgrade every installed version"},
+				{Name: "remove-old", Usage: "Remove the superseded install"},
+				{Name: "keep-old", Usage: "Keep the superseded install"},
+				{Name: "move-default", Usage: "Move JAVA_HOME to the new release"},
+				{Name: "keep-default", Usage: "Leave JAVA_HOME alone"},
+				archFlag, imageFlag,
+			},
+			Complete: completeFirst(installedVersions),
+			Run: func(c *cli.Context) error {
+				cpuarch, err := archOption(c)
+				if err != nil {
+					return err
+				}
+				image, err := imageOption(c)
+				if err != nil {
+					return err
+				}
+				return upgrade(c.Arg(0), cpuarch, image, upgradeOptions{
+					all:         c.Bool("all"),
+					removeOld:   c.Bool("remove-old"),
+					keepOld:     c.Bool("keep-old"),
+					moveDefault: c.Bool("move-default"),
+					keepDefault: c.Bool("keep-default"),
+				})
+			},
+		},
+		{
+			Name: "exec", Args: "<version> [--] <command> [arguments]", Passthrough: 1,
+			Summary: "Run a command with a Java version",
+			Help: `Options after the command belong to it: jdkvm exec 21 java -version.
+The command's exit status becomes jdkvm's.`,
+			Flags:    []cli.Flag{archFlag, vendorFlag, imageFlag},
+			Complete: completeVersion(installedVersions),
+			Run: func(c *cli.Context) error {
+				q, err := installQuery(c)
+				if err != nil {
+					return err
+				}
+				var command []string
+				if len(c.Args) > 1 {
+					command = c.Args[1:]
+				}
+				return execJava(c.Arg(0), q, command)
+			},
+		},
+		{
+			Name: "which", Args: "<tool>",
+			Summary: "Print the path of a JDK tool",
+			Help:    "Without --version the tool comes from the active JDK (JAVA_HOME).",
+			Flags: []cli.Flag{
+				{Name: "version", Value: "<version>", Usage: "Look in an installed version instead", Complete: installedVersions},
+				archFlag, vendorFlag, imageFlag,
+			},
+			Run: func(c *cli.Context) error {
+				q, err := installQuery(c)
+				if err != nil {
+					return err
+				}
+				return which(c.Arg(0), c.String("version"), q)
+			},
+		},
+		{
+			Name: "home", Args: "<version>",
+			Summary:  "Print the install directory of a version",
+			Flags:    []cli.Flag{archFlag, vendorFlag, imageFlag},
+			Complete: completeFirst(installedVersions),
+			Run: func(c *cli.Context) error {
+				q, err := installQuery(c)
+				if err != nil {
+					return err
+				}
+				return home(c.Arg(0), q)
+			},
+		},
+		{
+			Name: "bundle", Args: "create|install|list ...", Progress: true,
+			Summary: "Create or install offline bundles for air-gapped machines",
+			Help: `jdkvm bundle create <version> [version...] -o <file>
+jdkvm bundle install <file> [version...]
+jdkvm bundle list <file>
+
+Installed archives are verified against the checksums of the local catalog.
+The bundle's own are used when the catalog lists the same download without
+one, as the embedded catalog does. Use --trust-bundle for downloads the
+catalog does not list.`,
+			Flags: []cli.Flag{
+				archFlag, osFlag, imageFlag,
+				{Name: "file", Short: "o", Value: "<file>", Usage: "Bundle to create"},
+				{Name: "trust-bundle", Usage: "Accept the bundle's checksums for downloads the catalog does not list"},
+			},
+			Complete: func(args []string) []string {
+				if len(args) == 0 {
+					return []string{"create", "install", "list"}
+				}
+				if args[0] == "create" {
+					return catalogVersions()
+				}
+				return nil
+			},
+			Run: func(c *cli.Context) error {
+				cpuarch, err := archOption(c)
+				if err != nil {
+					return err
+				}
+				image, err := imageOption(c)
+				if err != nil {
+					return err
+				}
+				osName := c.String("os")
+				if osName == "" {
+					osName = web.HostOS()
+				}
+				return bundle(c.Args, cpuarch, osName, image, c.String("file"), c.Bool("trust-bundle"))
+			},
+		},
+		{
+			Name:    "serve",
+			Summary: "Serve the catalog and cached archives to other machines",
+			Flags: []cli.Flag{
+				{Name: "addr", Value: "<address>", Usage: "Address to listen on (default :8080)"},
+				{Name: "advertise", Value: "<url>", Usage: "URL other machines reach this one at (default http://<hostname>:<port>)"},
+			},
+			Run: func(c *cli.Context) error {
+				addr := c.String("addr")
+				if addr == "" {
+					addr = ":8080"
+				}
+				if err := web.Serve(addr, c.String("advertise"), filepath.Join(env.cacheDir, "cache")); err != nil {
+					return fmt.Errorf("failed to serve: %v", err)
+				}
+				return nil
+			},
+		},
+		{
+			Name:    "doctor",
+			Summary: "Diagnose the setup: directories, installs, JAVA_HOME, PATH, network",
+			Flags:   []cli.Flag{{Name: "offline", Usage: "Skip the network checks"}},
+			Run: func(c *cli.Context) error {
+				return doctor(c.Bool("offline"))
+			},
+		},
+		{
+			Name: "policy", Args: "check|show",
+			Summary:  "Show the version policy or audit installs against it",
+			Complete: completeActions("check", "show"),
+			Run: func(c *cli.Context) error {
+				return policyCommand(c.Args)
+			},
+		},
+		{
+			Name: "catalog", Args: "where|validate|upgrade|update|keygen|sign ...",
+			Summary: "Inspect or update the catalog of Java releases",
+			Help: `jdkvm catalog where
+jdkvm catalog validate <file>
+jdkvm catalog upgrade <file> [output]
+jdkvm catalog update [url]
+jdkvm catalog keygen <private-key-file>
+jdkvm catalog sign <file> <private-key-file>`,
+			Complete: completeActions("where", "validate", "upgrade", "update", "keygen", "sign"),
+			Run: func(c *cli.Context) error {
+				return catalog(c.Args)
+			},
+		},
+		{
+			Name: "config", Args: "list|get|set|unset|dirs ...",
+			Summary: "Show or change settings",
+			Help: `jdkvm config list
+jdkvm config get <key>
+jdkvm config set <key> <value>
+jdkvm config unset <key>
+jdkvm config dirs
+
+Sources, lowest precedence first: default, system file, user file, project
+file (.jdkvm.toml), JDKVM_* environment variables, --set key=value.`,
+			Flags: []cli.Flag{
+				{Name: "show-origin", Usage: "Show where each value comes from"},
+				{Name: "system", Usage: "Change the system file"},
+				{Name: "project", Usage: "Change the project file (.jdkvm.toml)"},
+			},
+			Complete: func(args []string) []string {
+				switch {
+				case len(args) == 0:
+					return []string{"list", "get", "set", "unset", "dirs"}
+				case len(args) == 1 && args[0] != "list" && args[0] != "dirs":
+					return configKeys()
+				}
+				return nil
+			},
+			Run: func(c *cli.Context) error {
+				return configCommand(c.Args, c.Bool("show-origin"), c.Bool("system"), c.Bool("project"))
+			},
+		},
+		{
+			Name: "proxy", Args: "[<url>|none|direct|test <url>|login <user>|logout]",
+			Summary:  "Set, show or test proxy settings",
+			Complete: completeActions("none", "direct", "test", "login", "logout"),
+			Run: func(c *cli.Context) error {
+				return proxy(c.Args)
+			},
+		},
+		{
+			Name: "java_mirror", Args: "[mirror]",
+			Summary: "Set or show Java download mirrors",
+			Run: func(c *cli.Context) error {
+				return javaMirror(strings.Join(c.Args, " "))
+			},
+		},
+		{
+			Name:    "version",
+			Summary: "Show the jdkvm version",
+			Run: func(c *cli.Context) error {
+				fmt.Println(JdkvmVersion)
+				return nil
+			},
+		},
+	}
+	app.Commands = append(app.Commands, app.CompletionCommands()...)
+	app.Commands = append(app.Commands, &cli.Command{
+		Name: "help", Args: "[command]",
+		Summary: "Show help for a command",
+		Complete: completeFirst(func() []string {
+			var names []string
+			for _, cmd := range app.Commands {
+				if !cmd.Hidden {
+					names = append(names, cmd.Name)
+				}
+			}
+			return names
+		}),
+		Run: app.Help,
+	})
+	return app
+}
+
+// localCommands only read the installs, so they never download a catalog
+var localCommands = map[string]bool{"which": true, "home": true, "current": true, "exec": true}
+
+// setup applies the global options and loads the environment before a command runs
+func setup(c *cli.Context) error {
+	name := c.Command.Name
+	switch name {
+	case "help", "version", "completion":
+		return nil
+	case cli.CompleteCommand:
+		// Completion reads the installs and cached catalog without a word
+		// besides the candidates, and never downloads anything
+		env.offline, env.completing = true, true
+		stdout, stderr := os.Stdout, os.Stderr
+		if devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+			os.Stdout, os.Stderr = devNull, devNull
+		}
+		initializeEnvironment(nil)
+		os.Stdout, os.Stderr = stdout, stderr
+		return nil
+	}
+
+	format := c.String("output")
+	if name == "config" && c.Arg(0) != "list" && format == outputJSON {
+		return cli.Usage("--output json is only supported by config list")
+	}
+	if err := setOutput(format, name); err != nil {
+		return cli.Usage("%v", err)
+	}
+	overrides, err := parseOverrides(c.Strings("set"))
+	if err != nil {
+		return err
+	}
+	env.offline = localCommands[name]
+	assumeYes = c.Bool("yes")
+	web.Verbose = c.Bool("verbose")
+	if c.Bool("quiet") && c.Command.Progress {
+		if devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+			os.Stdout = devNull
+		}
+	}
+
+	initializeEnvironment(overrides)
+
+	// Commands that may need admin privileges
+	if name == "install" || name == "use" || name == "uninstall" {
+		checkAdminPrivileges()
+	}
+	return nil
+}
+
+// exitCode maps the kinds of failure to the documented exit statuses
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, web.ErrNotFound):
+		return cli.ExitNotInstalled
+	case errors.Is(err, web.ErrNetwork):
+		return cli.ExitNetwork
+	case errors.Is(err, web.ErrVerification):
+		return cli.ExitVerification
+	}
+	return cli.ExitFailure
+}
+
+// parseOverrides turns --set key=value options into configuration overrides
+func parseOverrides(values []string) (map[string]string, error) {
+	overrides := map[string]string{}
+	for _, value := range values {
+		key, v, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, cli.Usage("--set %s: expected key=value", value)
+		}
+		overrides[strings.TrimSpace(key)] = strings.TrimSpace(v)
+	}
+	return overrides, nil
+}
+
+// archOption returns --arch, or the architecture given after the version,
+// normalized. An empty architecture means "not specified": install then uses
+// the host architecture while use/uninstall accept whichever build is installed.
+func archOption(c *cli.Context) (string, error) {
+	value := c.String("arch")
+	if value == "" && (c.Command.Name == "install" || c.Command.Name == "use" || c.Command.Name == "uninstall") {
+		value = c.Arg(1)
+	}
+	if value == "" {
+		return "", nil
+	}
+	a := arch.Normalize(value)
+	if a == arch.Unknown {
+		return "", cli.Usage("unknown architecture %q; use one of: %s", value, strings.Join(arch.Names(), ", "))
+	}
+	return a, nil
+}
+
+// imageOption returns --image: jdk, jre, or an add-on image for an installed JDK
+func imageOption(c *cli.Context) (string, error) {
+	image := strings.ToLower(c.String("image"))
+	if image != "" && !java.IsImage(image) {
+		return "", cli.Usage("unknown image type %q; use one of: %s", image, strings.Join(java.Images, ", "))
+	}
+	return image, nil
+}
+
+func vendorOption(c *cli.Context) string {
+	return strings.ToLower(c.String("vendor"))
+}
+
+// installQuery returns the filters that select among installed builds
+func installQuery(c *cli.Context) (java.Query, error) {
+	cpuarch, err := archOption(c)
+	if err != nil {
+		return java.Query{}, err
+	}
+	image, err := imageOption(c)
+	if err != nil {
+		return java.Query{}, err
+	}
+	return java.Query{Vendor: vendorOption(c), Arch: cpuarch, Image: image}, nil
+}
+
+// notInstalled is the error for a version without a matching install
+func notInstalled(version string, q java.Query) error {
+	var details []string
+	for _, d := range []string{q.Vendor, q.Arch, q.Image} {
+		if d != "" {
+			details = append(details, d)
+		}
+	}
+	if len(details) > 0 {
+		return cli.Exit(cli.ExitNotInstalled, "Java version %s (%s) is not installed", version, strings.Join(details, ", "))
+	}
+	return cli.Exit(cli.ExitNotInstalled, "Java version %s is not installed", version)
+}
+
+// unknownAction is the error for a missing or unknown subcommand
+func unknownAction(action string) error {
+	if action == "" {
+		return cli.Usage("missing subcommand")
+	}
+	return cli.Usage("unknown subcommand %q", action)
+}
+
+// confirm asks a yes/no question on the terminal. Without a terminal, or
+// with --yes, the answer is yes, so scripts are never blocked.
+func confirm(question string) bool {
+	if assumeYes || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return true
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"jdkvm/arch"
+	"jdkvm/config"
+	"jdkvm/java"
+	"jdkvm/web"
+)
+
+// Candidates for shell completion. They come from the installs and the
+// cached catalog only, so completing never waits for the network.
+
+// installedVersions returns the installed versions and their feature releases
+func installedVersions() []string {
+	var versions []string
+	for _, inst := range java.GetInstallations(env.root) {
+		versions = append(versions, java.Major(inst.Version), inst.Version)
+	}
+	return versions
+}
+
+func installedVendors() []string {
+	var vendors []string
+	for _, inst := range java.GetInstallations(env.root) {
+		vendors = append(vendors, inst.Vendor)
+	}
+	return vendors
+}
+
+// catalogVersions returns the feature releases in the catalog and their releases
+func catalogVersions() []string {
+	var versions []string
+	for _, v := range web.GetAvailableVersions() {
+		versions = append(versions, v)
+		for _, r := range web.GetReleases(v, "") {
+			versions = append(versions, r.Latest)
+		}
+	}
+	return versions
+}
+
+func catalogVendors() []string {
+	if len(web.GetAvailableVersions()) == 0 {
+		return nil
+	}
+	return web.CurrentCatalog.Vendors()
+}
+
+func configKeys() []string {
+	keys := make([]string, len(config.Keys))
+	for i, k := range config.Keys {
+		keys[i] = k.Name
+	}
+	return keys
+}
+
+// settingOverrides completes --set with "key="
+func settingOverrides() []string {
+	keys := configKeys()
+	for i := range keys {
+		keys[i] += "="
+	}
+	return keys
+}
+
+func osNames() []string {
+	return []string{web.OSWindows, web.OSMac, web.OSLinux, web.OSAlpine}
+}
+
+// completeFirst completes the first argument of a command
+func completeFirst(candidates func() []string) func(args []string) []string {
+	return func(args []string) []string {
+		if len(args) == 0 {
+			return candidates()
+		}
+		return nil
+	}
+}
+
+// completeActions completes the subcommand of a command
+func completeActions(actions ...string) func(args []string) []string {
+	return completeFirst(func() []string { return actions })
+}
+
+// completeVersion completes "<version> [arch]"
+func completeVersion(versions func() []string) func(args []string) []string {
+	return func(args []string) []string {
+		switch len(args) {
+		case 0:
+			return versions()
+		case 1:
+			return arch.Names()
+		}
+		return nil
+	}
+}
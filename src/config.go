@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jdkvm/cli"
+	"jdkvm/config"
+	"jdkvm/web"
+)
+
+// configCheckers validate values beyond their schema type before they are saved
+var configCheckers = map[string]func(string) error{
+	"java_mirror": web.SetJavaMirror,
+	"proxy": func(v string) error {
+		if v == "" || v == "none" || v == web.ProxyDirect {
+			return nil
+		}
+		_, err := web.ParseProxyURL(v)
+		return err
+	},
+	"ca_bundle":   checkFileExists,
+	"client_cert": checkFileExists,
+	"client_key":  checkFileExists,
+}
+
+// configCommand reads and writes the layered configuration:
+//
+//	jdkvm config list [--show-origin]
+//	jdkvm config get <key> [--show-origin]
+//	jdkvm config set <key> <value> [--system|--project]
+//	jdkvm config unset <key> [--system|--project]
+//	jdkvm config dirs
+func configCommand(args []string, showOrigin bool, system bool, project bool) error {
+	action := ""
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	switch action {
+	case "list":
+		if jsonOutput() {
+			settings := make([]settingEntry, 0, len(config.Keys))
+			for _, k := range config.Keys {
+				st, _ := env.config.Setting(k.Name)
+				settings = append(settings, settingEntry{Key: k.Name, Value: st.Value, Layer: st.Layer, Source: st.Source})
+			}
+			emit(settings)
+			return nil
+		}
+		for _, k := range config.Keys {
+			printSetting(k.Name, showOrigin)
+		}
+	case "get":
+		if len(args) != 1 {
+			return cli.Usage("config get needs a key")
+		}
+		if _, ok := config.Lookup(args[0]); !ok {
+			return unknownKey(args[0])
+		}
+		if showOrigin {
+			printSetting(args[0], true)
+		} else {
+			fmt.Println(env.config.Get(args[0]))
+		}
+	case "set":
+		if len(args) != 2 {
+			return cli.Usage("config set needs a key and a value")
+		}
+		key, value := args[0], args[1]
+		k, ok := config.Lookup(key)
+		if !ok {
+			return unknownKey(key)
+		}
+		value, err := k.Parse(value)
+		if err == nil && configCheckers[key] != nil {
+			err = configCheckers[key](value)
+		}
+		if err != nil {
+			return cli.Usage("invalid value for %s: %v", key, err)
+		}
+		target := configTarget(system, project)
+		if err := config.SetInFile(target, key, value); err != nil {
+			return fmt.Errorf("could not save %s: %w", key, err)
+		}
+		fmt.Printf("Set %s = %s in %s\n", key, value, target)
+		warnIfOverridden(key, target)
+	case "dirs":
+		fmt.Printf("data:   %s\nconfig: %s\ncache:  %s\n", env.root, env.configDir, env.cacheDir)
+	case "unset":
+		if len(args) != 1 {
+			return cli.Usage("config unset needs a key")
+		}
+		if _, ok := config.Lookup(args[0]); !ok {
+			return unknownKey(args[0])
+		}
+		target := configTarget(system, project)
+		removed, err := config.UnsetInFile(target, args[0])
+		if err != nil {
+			return fmt.Errorf("could not update %s: %w", target, err)
+		}
+		if removed {
+			fmt.Printf("Removed %s from %s\n", args[0], target)
+		} else {
+			fmt.Printf("%s is not set in %s\n", args[0], target)
+		}
+	default:
+		return unknownAction(action)
+	}
+	return nil
+}
+
+// settingEntry is one key in the JSON result of config list
+type settingEntry struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Layer  string `json:"layer"`
+	Source string `json:"source,omitempty"`
+}
+
+func printSetting(key string, showOrigin bool) {
+	s, _ := env.config.Setting(key)
+	if showOrigin {
+		fmt.Printf("%-22s %-30q %s\n", key, s.Value, s.Origin())
+		return
+	}
+	fmt.Printf("%s = %q\n", key, s.Value)
+}
+
+// configTarget picks the file config set and unset change: the user file
+// unless --system or --project is given
+func configTarget(system bool, project bool) string {
+	if system {
+		return config.SystemFile()
+	}
+	if project {
+		cwd, _ := os.Getwd()
+		if found := config.FindProjectFile(cwd); found != "" {
+			return found
+		}
+		return filepath.Join(cwd, config.ProjectFile)
+	}
+	return env.configFile
+}
+
+// warnIfOverridden points out when a saved value is shadowed by a source
+// with higher precedence and so does not take effect
+func warnIfOverridden(key string, target string) {
+	s, _ := env.config.Setting(key)
+	switch s.Layer {
+	case config.LayerProject, config.LayerEnv, config.LayerFlag:
+		if s.Source == target {
+			return
+		}
+		fmt.Printf("Note: %s is overridden by %s\n", key, s.Origin())
+	}
+}
+
+// unknownKey returns the usage error for a key that is not a setting, listing the known ones
+func unknownKey(key string) error {
+	var known strings.Builder
+	for _, k := range config.Keys {
+		fmt.Fprintf(&known, "\n  %-22s %s", k.Name, k.Description)
+	}
+	return cli.Usage("unknown configuration key %q. Known keys:%s", key, known.String())
+}
+
+func checkFileExists(path string) error {
+	if path == "" {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	return nil
+}
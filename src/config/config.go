@@ -0,0 +1,157 @@
+// Package config loads jdkvm settings from layered sources. Later layers win:
+//
+//	default < system file < user file < project file < JDKVM_* environment < --set flags
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Layer names, lowest precedence first
+const (
+	LayerDefault = "default"
+	LayerSystem  = "system"
+	LayerUser    = "user"
+	LayerProject = "project"
+	LayerEnv     = "env"
+	LayerFlag    = "flag"
+)
+
+// ProjectFile is looked up in the working directory and its parents
+const ProjectFile = ".jdkvm.toml"
+
+// EnvPrefix plus the upper-cased key name overrides a key, e.g. JDKVM_JAVA_MIRROR
+const EnvPrefix = "JDKVM_"
+
+// Sources lists where configuration is read from. Empty paths are skipped.
+type Sources struct {
+	System  string
+	User    string
+	Project string
+	Flags   map[string]string
+}
+
+// Setting is the effective value of a key and where it came from
+type Setting struct {
+	Value  string
+	Layer  string
+	Source string
+}
+
+// Origin describes where a setting came from, e.g. "user (/home/me/.config/jdkvm/config.toml)"
+func (s Setting) Origin() string {
+	if s.Source == "" {
+		return s.Layer
+	}
+	return s.Layer + " (" + s.Source + ")"
+}
+
+// Config holds the effective settings
+type Config struct {
+	settings map[string]Setting
+	// Warnings lists values that were ignored because they failed validation
+	Warnings []string
+}
+
+// Load merges every source. Invalid values are skipped with a warning so one
+// bad entry does not take the rest of the configuration down with it.
+func Load(src Sources) *Config {
+	c := &Config{settings: map[string]Setting{}}
+	for _, k := range Keys {
+		c.settings[k.Name] = Setting{Value: k.Default, Layer: LayerDefault}
+	}
+
+	for _, file := range []struct{ layer, path string }{
+		{LayerSystem, src.System},
+		{LayerUser, src.User},
+		{LayerProject, src.Project},
+	} {
+		if file.path == "" {
+			continue
+		}
+		values, errs := ReadFile(file.path)
+		for _, err := range errs {
+			c.Warnings = append(c.Warnings, fmt.Sprintf("%s: %v", file.path, err))
+		}
+		for key, value := range values {
+			c.settings[key] = Setting{Value: value, Layer: file.layer, Source: file.path}
+		}
+	}
+
+	for _, k := range Keys {
+		name := EnvVar(k.Name)
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		value, err := k.Parse(raw)
+		if err != nil {
+			c.Warnings = append(c.Warnings, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		c.settings[k.Name] = Setting{Value: value, Layer: LayerEnv, Source: name}
+	}
+
+	for key, raw := range src.Flags {
+		k, ok := Lookup(key)
+		if !ok {
+			c.Warnings = append(c.Warnings, fmt.Sprintf("--set: unknown key %q", key))
+			continue
+		}
+		value, err := k.Parse(raw)
+		if err != nil {
+			c.Warnings = append(c.Warnings, fmt.Sprintf("--set: %v", err))
+			continue
+		}
+		c.settings[key] = Setting{Value: value, Layer: LayerFlag, Source: "--set " + key}
+	}
+	return c
+}
+
+// Get returns the effective value of a key
+func (c *Config) Get(key string) string {
+	return c.settings[key].Value
+}
+
+// Bool returns the effective value of a boolean key
+func (c *Config) Bool(key string) bool {
+	return c.settings[key].Value == "true"
+}
+
+// Setting returns the effective value of a key together with its origin
+func (c *Config) Setting(key string) (Setting, bool) {
+	s, ok := c.settings[key]
+	return s, ok
+}
+
+// EnvVar returns the environment variable that overrides a key
+func EnvVar(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// SystemFile returns the machine-wide configuration file
+func SystemFile() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "jdkvm", "config.toml")
+	}
+	return "/etc/jdkvm/config.toml"
+}
+
+// FindProjectFile returns the nearest .jdkvm.toml in dir or its parents, or ""
+func FindProjectFile(dir string) string {
+	for {
+		candidate := filepath.Join(dir, ProjectFile)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
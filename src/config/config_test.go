@@ -0,0 +1,341 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clearEnv removes every JDKVM_* override for the duration of a test
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, k := range Keys {
+		name := EnvVar(k.Name)
+		if _, ok := os.LookupEnv(name); ok {
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayers(t *testing.T) {
+	clearEnv(t)
+	dir := t.TempDir()
+	src := Sources{
+		System: writeFile(t, filepath.Join(dir, "system.toml"), "java_mirror = \"system\"\nproxy = \"system\"\nno_proxy = \"system\"\ntls_min_version = \"1.3\"\n"),
+		User:   writeFile(t, filepath.Join(dir, "user.toml"), "proxy = \"user\"\nno_proxy = \"user\"\nmirror_probe = true\n"),
+		Project: writeFile(t, filepath.Join(dir, "project.toml"),
+			"no_proxy = \"project\"\ncatalog_url = \"project\"\n"),
+		Flags: map[string]string{"catalog_url": "flag", "arch": "amd64"},
+	}
+	t.Setenv(EnvVar("catalog_url"), "env")
+	t.Setenv(EnvVar("policy"), "env")
+
+	c := Load(src)
+	if len(c.Warnings) > 0 {
+		t.Fatalf("Load warnings = %v", c.Warnings)
+	}
+
+	tests := []struct {
+		key       string
+		want      string
+		wantLayer string
+	}{
+		{"verifyssl", "true", LayerDefault},
+		{"java_mirror", "system", LayerSystem},
+		{"tls_min_version", "1.3", LayerSystem},
+		{"proxy", "user", LayerUser},
+		{"mirror_probe", "true", LayerUser},
+		{"no_proxy", "project", LayerProject},
+		{"policy", "env", LayerEnv},
+		{"catalog_url", "flag", LayerFlag},
+		{"arch", "x64", LayerFlag},
+	}
+	for _, tt := range tests {
+		s, ok := c.Setting(tt.key)
+		if !ok {
+			t.Errorf("Setting(%q) missing", tt.key)
+			continue
+		}
+		if s.Value != tt.want || s.Layer != tt.wantLayer {
+			t.Errorf("Setting(%q) = %q from %s, want %q from %s", tt.key, s.Value, s.Origin(), tt.want, tt.wantLayer)
+		}
+	}
+
+	if !c.Bool("mirror_probe") || c.Bool("upgrade_remove_old") {
+		t.Errorf("Bool: mirror_probe = %v, upgrade_remove_old = %v", c.Bool("mirror_probe"), c.Bool("upgrade_remove_old"))
+	}
+	if s, _ := c.Setting("proxy"); s.Origin() != LayerUser+" ("+src.User+")" {
+		t.Errorf("Origin = %q", s.Origin())
+	}
+	if s, _ := c.Setting("verifyssl"); s.Origin() != LayerDefault {
+		t.Errorf("Origin = %q, want %q", s.Origin(), LayerDefault)
+	}
+}
+
+func TestLoadSkipsInvalidValues(t *testing.T) {
+	clearEnv(t)
+	dir := t.TempDir()
+	src := Sources{
+		User:    writeFile(t, filepath.Join(dir, "user.toml"), "verifyssl = false\nproxy = \"user\"\n"),
+		Project: writeFile(t, filepath.Join(dir, "project.toml"), "verifyssl = \"no\"\ntls_min_version = \"0.9\"\nunknown_key = 1\n"),
+		Flags:   map[string]string{"nope": "x", "arch": "mips"},
+	}
+	t.Setenv(EnvVar("mirror_probe"), "maybe")
+
+	c := Load(src)
+	tests := []struct {
+		key       string
+		want      string
+		wantLayer string
+	}{
+		{"verifyssl", "false", LayerUser},
+		{"tls_min_version", "1.2", LayerDefault},
+		{"mirror_probe", "false", LayerDefault},
+		{"arch", "", LayerDefault},
+		{"proxy", "user", LayerUser},
+	}
+	for _, tt := range tests {
+		s, _ := c.Setting(tt.key)
+		if s.Value != tt.want || s.Layer != tt.wantLayer {
+			t.Errorf("Setting(%q) = %q from %s, want %q from %s", tt.key, s.Value, s.Layer, tt.want, tt.wantLayer)
+		}
+	}
+
+	for _, want := range []string{"verifyssl", "tls_min_version", `unknown key "unknown_key"`, EnvVar("mirror_probe"), `--set: unknown key "nope"`, "arch"} {
+		found := false
+		for _, w := range c.Warnings {
+			if strings.Contains(w, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("no warning mentions %q in %v", want, c.Warnings)
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		want     map[string]string
+		wantErrs int
+	}{
+		{"valid", "verifyssl = false\narch = \"aarch64\"\n", map[string]string{"verifyssl": "false", "arch": "aarch64"}, 0},
+		{"arch is normalized", "arch = \"arm64\"\n", map[string]string{"arch": "aarch64"}, 0},
+		{"unknown key", "verifyssl = true\nmirror = \"x\"\n", map[string]string{"verifyssl": "true"}, 1},
+		{"quoted boolean", "verifyssl = \"false\"\n", map[string]string{}, 1},
+		{"boolean string", "proxy = true\n", map[string]string{}, 1},
+		{"number", "proxy = 8080\n", map[string]string{}, 1},
+		{"bad enum", "tls_min_version = \"1.4\"\nproxy = \"direct\"\n", map[string]string{"proxy": "direct"}, 1},
+		{"invalid TOML", "verifyssl = \n", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, filepath.Join(t.TempDir(), "config.toml"), tt.content)
+			got, errs := ReadFile(path)
+			if len(errs) != tt.wantErrs {
+				t.Errorf("ReadFile errors = %v, want %d", errs, tt.wantErrs)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ReadFile = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("ReadFile[%q] = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+
+	if values, errs := ReadFile(filepath.Join(t.TempDir(), "missing.toml")); values != nil || errs != nil {
+		t.Errorf("ReadFile of a missing file = %v, %v", values, errs)
+	}
+}
+
+func TestSetInFile(t *testing.T) {
+	const existing = "# my settings\nproxy = \"http://old:3128\" # corporate\n\n[extra]\nproxy = \"table\"\n"
+	tests := []struct {
+		name    string
+		content string
+		key     string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"new file", "", "verifyssl", "no", fileHeader + "verifyssl = false\n", false},
+		{"replace", existing, "proxy", "direct",
+			"# my settings\nproxy = \"direct\"\n\n[extra]\nproxy = \"table\"\n", false},
+		{"add before table", existing, "java_mirror", "https://mirror.example.com/",
+			"# my settings\nproxy = \"http://old:3128\" # corporate\n\njava_mirror = \"https://mirror.example.com/\"\n[extra]\nproxy = \"table\"\n", false},
+		{"quotes are escaped", "", "no_proxy", `a"b`, fileHeader + "no_proxy = \"a\\\"b\"\n", false},
+		{"unknown key", existing, "mirror", "x", existing, true},
+		{"invalid value", existing, "tls_min_version", "2.0", existing, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.toml")
+			if tt.content != "" {
+				writeFile(t, path, tt.content)
+			}
+			err := SetInFile(path, tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetInFile error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got, _ := os.ReadFile(path); string(got) != tt.want {
+				t.Errorf("file =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsetInFile(t *testing.T) {
+	const content = "# my settings\nverifyssl = false\nproxy = \"direct\"\n\n[extra]\nverifyssl = true\n"
+	tests := []struct {
+		key         string
+		wantRemoved bool
+		want        string
+	}{
+		{"verifyssl", true, "# my settings\nproxy = \"direct\"\n\n[extra]\nverifyssl = true\n"},
+		{"java_mirror", false, content},
+	}
+	for _, tt := range tests {
+		path := writeFile(t, filepath.Join(t.TempDir(), "config.toml"), content)
+		removed, err := UnsetInFile(path, tt.key)
+		if err != nil {
+			t.Fatalf("UnsetInFile(%q): %v", tt.key, err)
+		}
+		if removed != tt.wantRemoved {
+			t.Errorf("UnsetInFile(%q) = %v, want %v", tt.key, removed, tt.wantRemoved)
+		}
+		if got, _ := os.ReadFile(path); string(got) != tt.want {
+			t.Errorf("UnsetInFile(%q) left\n%s\nwant\n%s", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	dir := t.TempDir()
+	legacy := writeFile(t, filepath.Join(dir, "settings.txt"),
+		"# old settings\nVERIFYSSL=no\nproxy = direct\ntls_min_version=1.2\narch=amd64\nunknown=1\nmirror_probe=maybe\nbroken line\n")
+	user := filepath.Join(dir, "config", "config.toml")
+
+	migrated, err := MigrateLegacy(legacy, user)
+	if err != nil || !migrated {
+		t.Fatalf("MigrateLegacy = %v, %v", migrated, err)
+	}
+	values, errs := ReadFile(user)
+	if len(errs) > 0 {
+		t.Fatalf("migrated file: %v", errs)
+	}
+	want := map[string]string{"verifyssl": "false", "proxy": "direct", "arch": "x64"}
+	if len(values) != len(want) {
+		t.Errorf("migrated %v, want %v", values, want)
+	}
+	for k, v := range want {
+		if values[k] != v {
+			t.Errorf("migrated %s = %q, want %q", k, values[k], v)
+		}
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Error("settings.txt was not renamed")
+	}
+	if _, err := os.Stat(legacy + ".bak"); err != nil {
+		t.Errorf("settings.txt.bak: %v", err)
+	}
+
+	// An existing user file is never overwritten
+	writeFile(t, legacy, "proxy=none\n")
+	if migrated, err := MigrateLegacy(legacy, user); migrated || err != nil {
+		t.Errorf("second MigrateLegacy = %v, %v", migrated, err)
+	}
+	if migrated, err := MigrateLegacy(filepath.Join(dir, "missing.txt"), filepath.Join(dir, "other.toml")); migrated || err != nil {
+		t.Errorf("MigrateLegacy without settings.txt = %v, %v", migrated, err)
+	}
+}
+
+func TestFindProjectFile(t *testing.T) {
+	root := t.TempDir()
+	project := writeFile(t, filepath.Join(root, "repo", ProjectFile), "arch = \"x64\"\n")
+	nested := filepath.Join(root, "repo", "module", "src")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// A directory with the project file's name does not count
+	if err := os.MkdirAll(filepath.Join(root, "repo", "module", ProjectFile), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{filepath.Join(root, "repo"), project},
+		{nested, project},
+		{root, ""},
+	}
+	for _, tt := range tests {
+		got := FindProjectFile(tt.dir)
+		if tt.want == "" && got != "" && strings.HasPrefix(got, root) {
+			t.Errorf("FindProjectFile(%q) = %q, want none", tt.dir, got)
+		}
+		if tt.want != "" && got != tt.want {
+			t.Errorf("FindProjectFile(%q) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"verifyssl", "true", "true", false},
+		{"verifyssl", "Yes", "true", false},
+		{"verifyssl", "on", "true", false},
+		{"verifyssl", "1", "true", false},
+		{"verifyssl", " OFF ", "false", false},
+		{"verifyssl", "no", "false", false},
+		{"verifyssl", "0", "false", false},
+		{"verifyssl", "maybe", "", true},
+		{"verifyssl", "", "", true},
+		{"tls_min_version", "1.3", "1.3", false},
+		{"tls_min_version", "1.4", "", true},
+		{"arch", "", "", false},
+		{"arch", "amd64", "x64", false},
+		{"arch", "x86_64", "x64", false},
+		{"arch", "arm64", "aarch64", false},
+		{"arch", "mips", "", true},
+		{"proxy", "http://proxy:3128", "http://proxy:3128", false},
+	}
+	for _, tt := range tests {
+		k, ok := Lookup(tt.key)
+		if !ok {
+			t.Fatalf("Lookup(%q) failed", tt.key)
+		}
+		got, err := k.Parse(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q, %q) error = %v, wantErr %v", tt.key, tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Dirs are the directories jdkvm keeps its files in: installed JDKs in Data,
+// settings and credentials in Config, and anything that can be rebuilt in Cache
+type Dirs struct {
+	Data   string
+	Config string
+	Cache  string
+}
+
+// Files that move from the legacy home to the config and cache directories
+var (
+	configFiles = []string{"config.toml", "settings.txt", "settings.txt.bak", "proxy-credentials.json"}
+	cacheFiles  = []string{"cache", "mirrors.json"}
+)
+
+// HomeDirs puts everything under one directory, as JDKVM_HOME always has
+func HomeDirs(home string) Dirs {
+	return Dirs{Data: home, Config: home, Cache: home}
+}
+
+// DefaultDirs returns the platform's conventional directories:
+//
+//	Linux:   $XDG_DATA_HOME/jdkvm, $XDG_CONFIG_HOME/jdkvm, $XDG_CACHE_HOME/jdkvm
+//	macOS:   ~/Library/Application Support/jdkvm, ~/Library/Preferences/jdkvm, ~/Library/Caches/jdkvm
+//	Windows: %LOCALAPPDATA%\jdkvm\data, ...\config, ...\cache
+func DefaultDirs() Dirs {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		base := os.Getenv("LOCALAPPDATA")
+		if base == "" {
+			base = filepath.Join(home, "AppData", "Local")
+		}
+		base = filepath.Join(base, "jdkvm")
+		return Dirs{Data: filepath.Join(base, "data"), Config: filepath.Join(base, "config"), Cache: filepath.Join(base, "cache")}
+	case "darwin":
+		library := filepath.Join(home, "Library")
+		return Dirs{
+			Data:   filepath.Join(library, "Application Support", "jdkvm"),
+			Config: filepath.Join(library, "Preferences", "jdkvm"),
+			Cache:  filepath.Join(library, "Caches", "jdkvm"),
+		}
+	}
+	return Dirs{
+		Data:   filepath.Join(xdgDir("XDG_DATA_HOME", filepath.Join(home, ".local", "share")), "jdkvm"),
+		Config: filepath.Join(xdgDir("XDG_CONFIG_HOME", filepath.Join(home, ".config")), "jdkvm"),
+		Cache:  filepath.Join(xdgDir("XDG_CACHE_HOME", filepath.Join(home, ".cache")), "jdkvm"),
+	}
+}
+
+// xdgDir reads an XDG base directory variable; relative paths are invalid
+// per the specification and fall back to the default
+func xdgDir(name string, fallback string) string {
+	if dir := os.Getenv(name); filepath.IsAbs(dir) {
+		return dir
+	}
+	return fallback
+}
+
+// LegacyHome is where releases before the platform directories kept everything
+func LegacyHome() string {
+	home := os.Getenv("USERPROFILE")
+	if home == "" {
+		home, _ = os.UserHomeDir()
+	}
+	return filepath.Join(home, ".jdkvm")
+}
+
+// MigrateHome moves a legacy home into the platform directories. It does
+// nothing when there is no legacy home or Data already has content, so it
+// runs once. It reports whether the installs moved; an error after that
+// means some settings or cache files stayed behind in Data.
+func MigrateHome(legacy string, dirs Dirs) (bool, error) {
+	if info, err := os.Stat(legacy); err != nil || !info.IsDir() {
+		return false, nil
+	}
+	if entries, err := os.ReadDir(dirs.Data); err == nil && len(entries) > 0 {
+		return false, nil
+	}
+
+	// The installs move with a single rename, so a failure leaves the
+	// legacy home untouched
+	os.Remove(dirs.Data)
+	if err := os.MkdirAll(filepath.Dir(dirs.Data), os.ModePerm); err != nil {
+		return false, err
+	}
+	if err := os.Rename(legacy, dirs.Data); err != nil {
+		return false, fmt.Errorf("could not move %s to %s: %v", legacy, dirs.Data, err)
+	}
+
+	for _, group := range []struct {
+		names []string
+		dir   string
+	}{{configFiles, dirs.Config}, {cacheFiles, dirs.Cache}} {
+		for _, name := range group.names {
+			from := filepath.Join(dirs.Data, name)
+			if _, err := os.Stat(from); err != nil {
+				continue
+			}
+			if err := os.MkdirAll(group.dir, os.ModePerm); err != nil {
+				return true, err
+			}
+			if err := os.Rename(from, filepath.Join(group.dir, name)); err != nil {
+				return true, fmt.Errorf("could not move %s: %v", from, err)
+			}
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestXDGDir(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", "/fallback"},
+		{"relative/dir", "/fallback"},
+		{"/xdg/data", "/xdg/data"},
+	}
+	for _, tt := range tests {
+		t.Setenv("XDG_DATA_HOME", tt.value)
+		if got := xdgDir("XDG_DATA_HOME", "/fallback"); got != tt.want {
+			t.Errorf("xdgDir with %q = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMigrateHome(t *testing.T) {
+	root := t.TempDir()
+	legacy := filepath.Join(root, ".jdkvm")
+	writeFile(t, filepath.Join(legacy, "v17.0.11+9_temurin_x64", "release"), "JAVA_VERSION=\"17.0.11\"\n")
+	writeFile(t, filepath.Join(legacy, "config.toml"), "verifyssl = false\n")
+	writeFile(t, filepath.Join(legacy, "mirrors.json"), "{}")
+	writeFile(t, filepath.Join(legacy, "cache", "catalog.json"), "{}")
+	dirs := Dirs{
+		Data:   filepath.Join(root, "data", "jdkvm"),
+		Config: filepath.Join(root, "config", "jdkvm"),
+		Cache:  filepath.Join(root, "cache", "jdkvm"),
+	}
+
+	moved, err := MigrateHome(legacy, dirs)
+	if err != nil || !moved {
+		t.Fatalf("MigrateHome = %v, %v", moved, err)
+	}
+	for _, path := range []string{
+		filepath.Join(dirs.Data, "v17.0.11+9_temurin_x64", "release"),
+		filepath.Join(dirs.Config, "config.toml"),
+		filepath.Join(dirs.Cache, "mirrors.json"),
+		filepath.Join(dirs.Cache, "cache", "catalog.json"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was not moved: %v", path, err)
+		}
+	}
+	for _, path := range []string{legacy, filepath.Join(dirs.Data, "config.toml"), filepath.Join(dirs.Data, "mirrors.json")} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s is still there", path)
+		}
+	}
+
+	// Data already has content, so a new legacy home is left alone
+	writeFile(t, filepath.Join(legacy, "v21.0.3+9_temurin_x64", "release"), "")
+	if moved, err := MigrateHome(legacy, dirs); moved || err != nil {
+		t.Errorf("second MigrateHome = %v, %v", moved, err)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Errorf("legacy home was touched: %v", err)
+	}
+	if moved, err := MigrateHome(filepath.Join(root, "missing"), dirs); moved || err != nil {
+		t.Errorf("MigrateHome without a legacy home = %v, %v", moved, err)
+	}
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// fileHeader starts every configuration file jdkvm creates
+const fileHeader = "# jdkvm configuration. See `jdkvm config list` for every key.\n"
+
+// ReadFile decodes a TOML configuration file and validates it against the
+// schema. Valid keys are returned even when others fail. A missing file is
+// not an error.
+func ReadFile(path string) (map[string]string, []error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	raw := map[string]interface{}{}
+	if _, err := toml.Decode(string(content), &raw); err != nil {
+		return nil, []error{err}
+	}
+
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := map[string]string{}
+	var errs []error
+	for _, name := range names {
+		k, ok := Lookup(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown key %q", name))
+			continue
+		}
+		value, err := k.check(raw[name])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		values[name] = value
+	}
+	return values, errs
+}
+
+// SetInFile writes a key into a configuration file. Only the line holding
+// the key changes, so comments and the rest of the file are kept.
+func SetInFile(path string, key string, value string) error {
+	k, ok := Lookup(key)
+	if !ok {
+		return fmt.Errorf("unknown key %q", key)
+	}
+	value, err := k.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	line := key + " = " + tomlValue(k, value)
+
+	lines, err := readLines(path)
+	if err != nil {
+		return err
+	}
+	if i := findKey(lines, key); i >= 0 {
+		lines[i] = line
+	} else {
+		// Top-level keys must come before the first table header
+		at := len(lines)
+		for i, l := range lines {
+			if strings.HasPrefix(strings.TrimSpace(l), "[") {
+				at = i
+				break
+			}
+		}
+		lines = append(lines[:at], append([]string{line}, lines[at:]...)...)
+	}
+	return writeLines(path, lines)
+}
+
+// UnsetInFile removes a key from a configuration file. It reports whether
+// the key was present.
+func UnsetInFile(path string, key string) (bool, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return false, err
+	}
+	i := findKey(lines, key)
+	if i < 0 {
+		return false, nil
+	}
+	return true, writeLines(path, append(lines[:i], lines[i+1:]...))
+}
+
+// findKey returns the line index of a top-level key, or -1
+func findKey(lines []string, key string) int {
+	pattern := regexp.MustCompile(`^\s*"?` + regexp.QuoteMeta(key) + `"?\s*=`)
+	for i, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), "[") {
+			break
+		}
+		if pattern.MatchString(l) {
+			return i
+		}
+	}
+	return -1
+}
+
+func readLines(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return strings.Split(strings.TrimSuffix(fileHeader, "\n"), "\n"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n"), nil
+}
+
+// writeLines replaces a file through a temporary copy so an interrupted
+// write never leaves a truncated configuration behind
+func writeLines(path string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	content := strings.Join(lines, "\n") + "\n"
+	if _, err := toml.Decode(content, &map[string]interface{}{}); err != nil {
+		return fmt.Errorf("refusing to write invalid TOML to %s: %v", path, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// MigrateLegacy converts the key=value settings.txt of older releases into
+// a TOML user file. Values equal to the default are dropped, and the old file
+// is kept as settings.txt.bak. It reports whether anything was migrated.
+func MigrateLegacy(legacy string, user string) (bool, error) {
+	content, err := os.ReadFile(legacy)
+	if err != nil {
+		return false, nil
+	}
+	if _, err := os.Stat(user); err == nil {
+		return false, nil
+	}
+
+	lines, _ := readLines(user)
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		k, ok := Lookup(strings.ToLower(strings.TrimSpace(parts[0])))
+		if !ok {
+			continue
+		}
+		value, err := k.Parse(strings.TrimSpace(parts[1]))
+		if err != nil || value == k.Default {
+			continue
+		}
+		lines = append(lines, k.Name+" = "+tomlValue(k, value))
+	}
+
+	if err := writeLines(user, lines); err != nil {
+		return false, err
+	}
+	return true, os.Rename(legacy, legacy+".bak")
+}
+
+// tomlValue encodes a canonical value as a TOML literal
+func tomlValue(k Key, value string) string {
+	if k.Kind == KindBool {
+		return value
+	}
+	buf := &bytes.Buffer{}
+	toml.NewEncoder(buf).Encode(map[string]string{"v": value})
+	return strings.TrimPrefix(strings.TrimSpace(buf.String()), "v = ")
+}
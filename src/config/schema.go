@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"jdkvm/arch"
+)
+
+// Value types a key can hold
+const (
+	KindString = "string"
+	KindBool   = "bool"
+	KindEnum   = "enum"
+)
+
+// Key describes one configuration setting
+type Key struct {
+	Name        string
+	Kind        string
+	Default     string
+	Choices     []string
+	Description string
+}
+
+// Keys is the schema: every setting jdkvm understands
+var Keys = []Key{
+	{Name: "arch", Kind: KindEnum, Default: "", Choices: []string{"", arch.X86, arch.X64, arch.AArch64, arch.ARM, arch.PPC64LE, arch.S390X, arch.RISCV64}, Description: "architecture installed when none is given; empty means the host's"},
+	{Name: "catalog_url", Kind: KindString, Default: "", Description: "URL of a live catalog, e.g. http://<host>:8080/version_mapping.json from jdkvm serve"},
+	{Name: "catalog_update_url", Kind: KindString, Default: "", Description: "URL of the signed catalog jdkvm catalog update fetches; the signature is at the same URL plus .sig"},
+	{Name: "catalog_public_key", Kind: KindString, Default: "", Description: "base64 ed25519 public key catalog signatures must match"},
+	{Name: "java_mirror", Kind: KindString, Default: "", Description: "comma-separated download mirrors, presets or rewrite rules"},
+	{Name: "mirror_probe", Kind: KindBool, Default: "false", Description: "probe mirrors with HEAD requests and try the fastest first"},
+	{Name: "policy", Kind: KindString, Default: "", Description: "path or URL of the policy file restricting which releases may be installed and used"},
+	{Name: "proxy", Kind: KindString, Default: "none", Description: "proxy URL, \"none\" to use the environment, or \"direct\""},
+	{Name: "no_proxy", Kind: KindString, Default: "", Description: "comma-separated hosts reached without the proxy"},
+	{Name: "proxy_pac", Kind: KindString, Default: "", Description: "path or URL of a proxy auto-config file"},
+	{Name: "verifyssl", Kind: KindBool, Default: "true", Description: "verify TLS certificates"},
+	{Name: "ca_bundle", Kind: KindString, Default: "", Description: "PEM file of extra root certificates"},
+	{Name: "client_cert", Kind: KindString, Default: "", Description: "PEM client certificate"},
+	{Name: "client_key", Kind: KindString, Default: "", Description: "PEM key of the client certificate"},
+	{Name: "tls_min_version", Kind: KindEnum, Default: "1.2", Choices: []string{"1.0", "1.1", "1.2", "1.3"}, Description: "lowest accepted TLS version"},
+	{Name: "upgrade_move_default", Kind: KindBool, Default: "true", Description: "upgrade moves JAVA_HOME to the new patch release"},
+	{Name: "upgrade_remove_old", Kind: KindBool, Default: "false", Description: "upgrade removes the old patch release"},
+}
+
+// Lookup returns the schema entry of a key
+func Lookup(name string) (Key, bool) {
+	for _, k := range Keys {
+		if k.Name == name {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// Parse validates a value given as text, as on the command line or in an
+// environment variable, and returns its canonical form
+func (k Key) Parse(value string) (string, error) {
+	switch k.Kind {
+	case KindBool:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "true", "yes", "on", "1":
+			return "true", nil
+		case "false", "no", "off", "0":
+			return "false", nil
+		}
+		return "", fmt.Errorf("%s must be true or false, got %q", k.Name, value)
+	case KindEnum:
+		if k.Name == "arch" && value != "" {
+			value = arch.Normalize(value)
+		}
+		for _, c := range k.Choices {
+			if c == value {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("%s must be one of %s, got %q", k.Name, strings.Join(quoted(k.Choices), ", "), value)
+	}
+	return value, nil
+}
+
+// check validates a value decoded from a TOML file, where booleans must be
+// real booleans and everything else a string
+func (k Key) check(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case bool:
+		if k.Kind != KindBool {
+			return "", fmt.Errorf("%s must be a string, got a boolean", k.Name)
+		}
+		return fmt.Sprint(v), nil
+	case string:
+		if k.Kind == KindBool {
+			return "", fmt.Errorf("%s must be a boolean (true or false without quotes)", k.Name)
+		}
+		return k.Parse(v)
+	}
+	return "", fmt.Errorf("%s must be a %s, got %T", k.Name, k.Kind, value)
+}
+
+func quoted(list []string) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = fmt.Sprintf("%q", s)
+	}
+	return out
+}
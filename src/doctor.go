@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"jdkvm/cli"
+	"jdkvm/file"
+	"jdkvm/java"
+	"jdkvm/utility"
+	"jdkvm/web"
+)
+
+// Results of a doctor check
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// doctorCheck is the outcome of one diagnostic, with a suggested fix when it
+// did not pass
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// doctor diagnoses why the selected Java might not be the one that runs:
+//
+//	jdkvm doctor [--offline]
+func doctor(offline bool) error {
+
+	checks := runDoctor(offline)
+	failed, warned := 0, 0
+	for _, c := range checks {
+		switch c.Status {
+		case checkFail:
+			failed++
+		case checkWarn:
+			warned++
+		}
+	}
+	if jsonOutput() {
+		emit(struct {
+			Checks []doctorCheck `json:"checks"`
+			Passed int           `json:"passed"`
+			Warned int           `json:"warned"`
+			Failed int           `json:"failed"`
+		}{checks, len(checks) - failed - warned, warned, failed})
+		return doctorResult(failed)
+	}
+
+	for _, c := range checks {
+		fmt.Printf("[%s] %s: %s\n", strings.ToUpper(c.Status), c.Name, c.Message)
+		if c.Fix != "" {
+			fmt.Printf("       fix: %s\n", c.Fix)
+		}
+	}
+	fmt.Printf("\n%d checks: %d passed, %d warnings, %d failed\n", len(checks), len(checks)-failed-warned, warned, failed)
+	return doctorResult(failed)
+}
+
+// doctorResult sets exit status 1 when a check failed; the report has
+// already said which
+func doctorResult(failed int) error {
+	if failed > 0 {
+		return cli.Reported(fmt.Errorf("%d checks failed", failed))
+	}
+	return nil
+}
+
+func runDoctor(offline bool) []doctorCheck {
+	var checks []doctorCheck
+	checks = append(checks, checkDirectories()...)
+	checks = append(checks, checkInstalls()...)
+	checks = append(checks, checkCatalog())
+	checks = append(checks, checkPolicy())
+	checks = append(checks, checkJavaHome()...)
+	checks = append(checks, checkJavaOnPath())
+	checks = append(checks, checkPathEntries()...)
+	if offline {
+		checks = append(checks, doctorCheck{Name: "network", Status: checkPass, Message: "skipped (--offline)"})
+	} else {
+		checks = append(checks, checkNetwork()...)
+	}
+	return checks
+}
+
+// checkDirectories makes sure jdkvm can write where it keeps its files
+func checkDirectories() []doctorCheck {
+	var checks []doctorCheck
+	for _, dir := range []struct{ name, path string }{{"data", env.root}, {"config", env.configDir}, {"cache", env.cacheDir}} {
+		name := dir.name + " directory"
+		info, err := os.Stat(dir.path)
+		if err != nil || !info.IsDir() {
+			checks = append(checks, doctorCheck{Name: name, Status: checkFail, Message: dir.path + " does not exist",
+				Fix: "create it, or set JDKVM_HOME to a directory you own"})
+			continue
+		}
+		probe, err := os.CreateTemp(dir.path, ".doctor-*")
+		if err != nil {
+			checks = append(checks, doctorCheck{Name: name, Status: checkFail, Message: dir.path + " is not writable: " + err.Error(),
+				Fix: "fix the permissions of " + dir.path + ", or set JDKVM_HOME to a directory you own"})
+			continue
+		}
+		probe.Close()
+		os.Remove(probe.Name())
+		checks = append(checks, doctorCheck{Name: name, Status: checkPass, Message: dir.path})
+	}
+	return checks
+}
+
+// checkInstalls finds install directories without a java launcher, which
+// an interrupted install or a partial delete leaves behind
+func checkInstalls() []doctorCheck {
+	entries, _ := os.ReadDir(env.root)
+	var checks []doctorCheck
+	complete := 0
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "v") {
+			continue
+		}
+		dir := filepath.Join(env.root, entry.Name())
+		if !file.Exists(java.Executable(dir)) {
+			checks = append(checks, doctorCheck{Name: "installs", Status: checkFail, Message: entry.Name() + " is incomplete: it has no " + filepath.Base(java.Executable(dir)),
+				Fix: "delete " + dir + " and install the version again"})
+			continue
+		}
+		if !file.Exists(filepath.Join(dir, ".jdkvm.json")) {
+			checks = append(checks, doctorCheck{Name: "installs", Status: checkWarn, Message: entry.Name() + " has no install metadata; its vendor and image are guessed from its name",
+				Fix: "reinstall it to record the metadata"})
+		}
+		complete++
+	}
+	if len(checks) == 0 {
+		checks = append(checks, doctorCheck{Name: "installs", Status: checkPass, Message: fmt.Sprintf("%d complete installs", complete)})
+	}
+	return checks
+}
+
+// checkCatalog reports which catalog is in use and any source that failed
+func checkCatalog() doctorCheck {
+	if len(web.JavaVersionMapping) == 0 {
+		return doctorCheck{Name: "catalog", Status: checkFail, Message: "no catalog could be loaded", Fix: "run jdkvm catalog where to see why"}
+	}
+	var used web.CatalogCandidate
+	var broken []string
+	for _, c := range web.CatalogReport() {
+		if c.Used {
+			used = c
+			break
+		}
+		if c.Location != "" && !strings.HasPrefix(c.Status, "not ") {
+			broken = append(broken, c.Source+" ("+c.Status+")")
+		}
+	}
+	message := fmt.Sprintf("using the %s catalog, %d feature releases", used.Source, len(web.JavaVersionMapping))
+	if len(broken) > 0 {
+		return doctorCheck{Name: "catalog", Status: checkWarn, Message: message + "; skipped " + strings.Join(broken, ", "),
+			Fix: "run jdkvm catalog where and fix or remove the skipped catalogs"}
+	}
+	return doctorCheck{Name: "catalog", Status: checkPass, Message: message}
+}
+
+// checkPolicy makes sure a configured policy can be read
+func checkPolicy() doctorCheck {
+	location := env.config.Get("policy")
+	if location == "" {
+		return doctorCheck{Name: "policy", Status: checkPass, Message: "no policy configured"}
+	}
+	if _, err := loadPolicy(location); err != nil {
+		return doctorCheck{Name: "policy", Status: checkFail, Message: "cannot load " + location + ": " + err.Error(),
+			Fix: "fix the policy file, or point the policy setting at a readable one"}
+	}
+	return doctorCheck{Name: "policy", Status: checkPass, Message: location}
+}
+
+// checkJavaHome compares JAVA_HOME in this process with the value new
+// shells get, and checks that it is a usable install
+func checkJavaHome() []doctorCheck {
+	current := os.Getenv("JAVA_HOME")
+	persisted, err := utility.GetPersistedEnvironmentVariable("JAVA_HOME")
+	hasPersisted := err == nil
+
+	if current == "" && persisted == "" {
+		return []doctorCheck{{Name: "JAVA_HOME", Status: checkWarn, Message: "is not set", Fix: "run jdkvm use <version>"}}
+	}
+
+	var checks []doctorCheck
+	home := current
+	if home == "" {
+		home = persisted
+	}
+	switch {
+	case !file.Exists(java.Executable(home)):
+		checks = append(checks, doctorCheck{Name: "JAVA_HOME", Status: checkFail, Message: home + " is not a Java install",
+			Fix: "run jdkvm use <version> to point it at an installed version"})
+	case !isUnder(home, env.root):
+		checks = append(checks, doctorCheck{Name: "JAVA_HOME", Status: checkWarn, Message: home + " is not managed by jdkvm",
+			Fix: "run jdkvm use <version> if jdkvm should pick the Java"})
+	default:
+		checks = append(checks, doctorCheck{Name: "JAVA_HOME", Status: checkPass, Message: home})
+	}
+
+	if hasPersisted && !samePath(current, persisted) {
+		checks = append(checks, doctorCheck{Name: "JAVA_HOME", Status: checkWarn,
+			Message: fmt.Sprintf("this shell has %q but new shells get %q", current, persisted),
+			Fix:     "open a new terminal, or run jdkvm use <version> again"})
+	}
+	return checks
+}
+
+// checkJavaOnPath finds the java that actually runs and whether it belongs
+// to JAVA_HOME
+func checkJavaOnPath() doctorCheck {
+	found, err := exec.LookPath("java")
+	if err != nil {
+		return doctorCheck{Name: "java on PATH", Status: checkFail, Message: "no java on PATH",
+			Fix: "run jdkvm use <version>, then open a new terminal"}
+	}
+	home := os.Getenv("JAVA_HOME")
+	if home == "" {
+		return doctorCheck{Name: "java on PATH", Status: checkWarn, Message: found + " runs, but JAVA_HOME is not set",
+			Fix: "run jdkvm use <version>"}
+	}
+	if !samePath(filepath.Dir(resolvePath(found)), filepath.Join(resolvePath(home), "bin")) {
+		return doctorCheck{Name: "java on PATH", Status: checkFail,
+			Message: fmt.Sprintf("%s comes earlier in PATH and shadows %s", found, java.Executable(home)),
+			Fix:     fmt.Sprintf("remove %s from PATH or move %s before it", filepath.Dir(found), filepath.Join(home, "bin"))}
+	}
+	return doctorCheck{Name: "java on PATH", Status: checkPass, Message: found}
+}
+
+// checkPathEntries looks for duplicate PATH entries, entries that no longer
+// exist and more than one jdkvm install on PATH
+func checkPathEntries() []doctorCheck {
+	var checks []doctorCheck
+	seen := map[string]bool{}
+	var installs []string
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key := filepath.Clean(entry)
+		if runtime.GOOS == "windows" {
+			key = strings.ToLower(key)
+		}
+		if seen[key] {
+			checks = append(checks, doctorCheck{Name: "PATH", Status: checkWarn, Message: entry + " is listed more than once",
+				Fix: "remove the duplicate from PATH"})
+			continue
+		}
+		seen[key] = true
+
+		if _, err := os.Stat(entry); err != nil {
+			status := checkWarn
+			if isUnder(entry, env.root) {
+				status = checkFail
+			}
+			checks = append(checks, doctorCheck{Name: "PATH", Status: status, Message: entry + " does not exist",
+				Fix: "remove it from PATH"})
+			continue
+		}
+		if isUnder(entry, env.root) {
+			installs = append(installs, entry)
+		}
+	}
+	if len(installs) > 1 {
+		checks = append(checks, doctorCheck{Name: "PATH", Status: checkWarn, Message: "several jdkvm installs are on PATH: " + strings.Join(installs, ", "),
+			Fix: "run jdkvm use <version> to keep only one"})
+	}
+	if len(checks) == 0 {
+		checks = append(checks, doctorCheck{Name: "PATH", Status: checkPass, Message: "no duplicate or missing entries"})
+	}
+	return checks
+}
+
+// checkNetwork makes a HEAD request to every host jdkvm downloads from,
+// through the configured proxy and TLS settings
+func checkNetwork() []doctorCheck {
+	var targets []string
+	for _, info := range web.JavaVersionMapping {
+		if len(info.Artifacts) > 0 {
+			if u, err := url.Parse(info.Artifacts[0].URL); err == nil {
+				targets = append(targets, u.Scheme+"://"+u.Host+"/")
+			}
+			break
+		}
+	}
+	for _, key := range []string{"catalog_url", "catalog_update_url", "policy"} {
+		if value := env.config.Get(key); strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+			targets = append(targets, value)
+		}
+	}
+
+	var checks []doctorCheck
+	for _, target := range targets {
+		choice, err := web.ProxyFor(target)
+		if err != nil {
+			checks = append(checks, doctorCheck{Name: "network", Status: checkFail, Message: target + ": " + err.Error(),
+				Fix: "check the proxy settings with jdkvm proxy"})
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		request, _ := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+		response, err := web.Do(request)
+		cancel()
+		if err != nil {
+			checks = append(checks, doctorCheck{Name: "network", Status: checkFail, Message: fmt.Sprintf("%s via %s: %v", target, choice, err),
+				Fix: "check the proxy (jdkvm proxy test <url>) and TLS settings (ca_bundle, verifyssl), or run jdkvm doctor --offline"})
+			continue
+		}
+		response.Body.Close()
+		checks = append(checks, doctorCheck{Name: "network", Status: checkPass, Message: fmt.Sprintf("%s via %s: %s", target, choice, response.Status)})
+	}
+	return checks
+}
+
+// isUnder reports whether path is inside dir
+func isUnder(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+func samePath(a string, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// resolvePath follows symbolic links, keeping the path when it cannot
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
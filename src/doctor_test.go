@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"jdkvm/arch"
+	"jdkvm/java"
+	"jdkvm/web"
+)
+
+// writeJava creates a Java install outside the install root
+func writeJava(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(java.Executable(home), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
+// statuses returns the status of every check with a name
+func statuses(checks []doctorCheck, name string) []string {
+	var list []string
+	for _, c := range checks {
+		if c.Name == name {
+			list = append(list, c.Status)
+		}
+	}
+	return list
+}
+
+func TestCheckJavaHome(t *testing.T) {
+	root := useTestEnv(t)
+	managed := writeInstall(t, root, java.Installation{Version: "17.0.11+9", Vendor: "temurin", Arch: arch.Host()})
+	outside := writeJava(t)
+
+	tests := []struct {
+		name     string
+		javaHome string
+		want     string
+		message  string
+	}{
+		{"not set", "", checkWarn, "is not set"},
+		{"managed install", managed.Dir, checkPass, managed.Dir},
+		{"outside the installs", outside, checkWarn, "is not managed by jdkvm"},
+		{"not a Java install", t.TempDir(), checkFail, "is not a Java install"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JAVA_HOME", tt.javaHome)
+			checks := checkJavaHome()
+			if len(checks) != 1 {
+				t.Fatalf("checks = %+v, want one", checks)
+			}
+			if checks[0].Status != tt.want || !strings.Contains(checks[0].Message, tt.message) {
+				t.Errorf("check = %+v, want %s containing %q", checks[0], tt.want, tt.message)
+			}
+			if tt.want != checkPass && checks[0].Fix == "" {
+				t.Error("no fix was suggested")
+			}
+		})
+	}
+}
+
+func TestCheckJavaOnPath(t *testing.T) {
+	root := useTestEnv(t)
+	managed := writeInstall(t, root, java.Installation{Version: "17.0.11+9", Vendor: "temurin", Arch: arch.Host()})
+	other := writeJava(t)
+
+	tests := []struct {
+		name     string
+		javaHome string
+		path     []string
+		want     string
+		message  string
+	}{
+		{"no java on PATH", managed.Dir, []string{t.TempDir()}, checkFail, "no java on PATH"},
+		{"JAVA_HOME first", managed.Dir, []string{filepath.Join(managed.Dir, "bin"), filepath.Join(other, "bin")}, checkPass, java.Executable(managed.Dir)},
+		{"shadowed by another java", managed.Dir, []string{filepath.Join(other, "bin"), filepath.Join(managed.Dir, "bin")}, checkFail, "shadows"},
+		{"JAVA_HOME not set", "", []string{filepath.Join(managed.Dir, "bin")}, checkWarn, "JAVA_HOME is not set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("JAVA_HOME", tt.javaHome)
+			t.Setenv("PATH", strings.Join(tt.path, string(os.PathListSeparator)))
+			check := checkJavaOnPath()
+			if check.Status != tt.want || !strings.Contains(check.Message, tt.message) {
+				t.Errorf("check = %+v, want %s containing %q", check, tt.want, tt.message)
+			}
+		})
+	}
+}
+
+func TestRunDoctorOffline(t *testing.T) {
+	root := useTestEnv(t)
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+	useTestCatalog(t, map[string]web.FeatureRelease{
+		"17": {Vendor: "temurin", LTS: true, Releases: []web.Release{{Version: "17.0.11+9", Artifacts: []web.CatalogArtifact{
+			{OS: web.HostOS(), Arch: arch.Host(), Image: java.ImageJDK, Package: web.PackageTarGz, URL: server.URL + "/jdk.tar.gz"},
+		}}}},
+	})
+	managed := writeInstall(t, root, java.Installation{Version: "17.0.11+9", Vendor: "temurin", Arch: arch.Host()})
+	t.Setenv("JAVA_HOME", managed.Dir)
+	t.Setenv("PATH", filepath.Join(managed.Dir, "bin"))
+
+	checks := runDoctor(true)
+	if got := statuses(checks, "network"); len(got) != 1 || got[0] != checkPass {
+		t.Errorf("network checks = %v, want one skipped check", got)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("--offline made %d requests", hits.Load())
+	}
+	for _, c := range checks {
+		if c.Status != checkPass {
+			t.Errorf("check %s = %s: %s", c.Name, c.Status, c.Message)
+		}
+	}
+
+	checks = runDoctor(false)
+	if got := statuses(checks, "network"); len(got) != 1 || got[0] != checkPass {
+		t.Errorf("network checks = %v, want one passed check", got)
+	}
+	if hits.Load() != 1 {
+		t.Errorf("the network check made %d requests, want 1", hits.Load())
+	}
+}
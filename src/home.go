@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jdkvm/config"
+	"jdkvm/utility"
+)
+
+func (e *Environment) setDirs(dirs config.Dirs) {
+	e.root = dirs.Data
+	e.configDir = dirs.Config
+	e.cacheDir = dirs.Cache
+}
+
+// migrateLegacyHome moves ~/.jdkvm into the platform directories once. When
+// the move is impossible, e.g. across file systems, jdkvm keeps using the
+// old directory rather than starting over with an empty one.
+func migrateLegacyHome() {
+	legacy := config.LegacyHome()
+	dirs := config.Dirs{Data: env.root, Config: env.configDir, Cache: env.cacheDir}
+	moved, err := config.MigrateHome(legacy, dirs)
+	if !moved {
+		if err != nil {
+			warnf("Could not migrate %s: %v", legacy, err)
+			fmt.Fprintf(os.Stderr, "Continuing to use %s; set JDKVM_HOME to keep this location.\n", legacy)
+			env.setDirs(config.HomeDirs(legacy))
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Moved %s to the platform directories:\n", legacy)
+	fmt.Fprintf(os.Stderr, "  data:   %s\n  config: %s\n  cache:  %s\n", dirs.Data, dirs.Config, dirs.Cache)
+	if err != nil {
+		warnf("%v", err)
+	}
+	repointEnvironment(legacy, dirs.Data)
+}
+
+// repointEnvironment updates JAVA_HOME and PATH entries that still point
+// into the old home after its installs moved
+func repointEnvironment(from string, to string) {
+	for _, name := range []string{"JAVA_HOME", "PATH"} {
+		value, err := utility.GetEnvironmentVariable(name)
+		if err != nil || !strings.Contains(value, from+string(filepath.Separator)) {
+			continue
+		}
+		updated := strings.ReplaceAll(value, from+string(filepath.Separator), to+string(filepath.Separator))
+		os.Setenv(name, updated)
+		if err := utility.SetEnvironmentVariable(name, updated); err != nil {
+			warnf("Could not update %s persistently (%v); it should now be %s", name, err, updated)
+		} else {
+			fmt.Fprintf(os.Stderr, "Updated %s to point at %s\n", name, to)
+		}
+	}
+}
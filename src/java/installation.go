@@ -0,0 +1,314 @@
+package java
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"jdkvm/arch"
+	"jdkvm/file"
+)
+
+// DefaultVendor is assumed for installs made before the vendor was recorded
+const DefaultVendor = "temurin"
+
+// metadataFile is written into every install directory by jdkvm install
+const metadataFile = ".jdkvm.json"
+
+// Image types Adoptium publishes for each release
+const (
+	ImageJDK        = "jdk"
+	ImageJRE        = "jre"
+	ImageDebug      = "debugimage"
+	ImageTest       = "testimage"
+	ImageStaticLibs = "staticlibs"
+)
+
+// Images lists the supported image types
+var Images = []string{ImageJDK, ImageJRE, ImageDebug, ImageTest, ImageStaticLibs}
+
+// IsImage reports whether image is a supported image type
+func IsImage(image string) bool {
+	for _, i := range Images {
+		if i == image {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOverlay reports whether an image is an add-on unpacked into an existing
+// JDK install rather than a runtime of its own
+func IsOverlay(image string) bool {
+	return image == ImageDebug || image == ImageTest || image == ImageStaticLibs
+}
+
+// Installation describes one Java build installed under JDKVM_HOME
+type Installation struct {
+	Version string `json:"version"`
+	Vendor  string `json:"vendor"`
+	Arch    string `json:"arch"`
+	OS      string `json:"os,omitempty"`
+	Image   string `json:"image,omitempty"`
+	// Extras lists the overlay images (debugimage, testimage, staticlibs) added to a JDK
+	Extras []string `json:"extras,omitempty"`
+	Dir    string   `json:"-"`
+}
+
+// HasExtra reports whether an overlay image has been added to the install
+func (inst Installation) HasExtra(image string) bool {
+	for _, e := range inst.Extras {
+		if e == image {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageLabel describes the image and its overlays, e.g. "jdk+staticlibs"
+func (inst Installation) ImageLabel() string {
+	return strings.Join(append([]string{inst.Image}, inst.Extras...), "+")
+}
+
+// Query selects installations. Empty fields match anything.
+type Query struct {
+	Version string
+	Vendor  string
+	Arch    string
+	Image   string
+}
+
+// DirName returns the install directory name for a build, so the same
+// version can be installed for several vendors, architectures and images
+// side by side
+func DirName(version string, vendor string, cpu string, image string) string {
+	name := "v" + version + "_" + vendor + "_" + cpu
+	if image != "" && image != ImageJDK {
+		name += "_" + image
+	}
+	return name
+}
+
+// parseDirName splits a name written by DirName back into its fields. The
+// version may itself contain underscores (1.8.0_412), so the name is taken
+// apart from the end.
+func parseDirName(name string) (Installation, bool) {
+	parts := strings.Split(strings.TrimPrefix(name, "v"), "_")
+	image := ImageJDK
+	if last := parts[len(parts)-1]; IsImage(last) && last != ImageJDK {
+		image, parts = last, parts[:len(parts)-1]
+	}
+	if len(parts) < 3 {
+		return Installation{}, false
+	}
+	n := len(parts)
+	inst := Installation{Version: strings.Join(parts[:n-2], "_"), Vendor: parts[n-2], Arch: parts[n-1], Image: image}
+	if !strings.HasPrefix(name, "v") || CheckBuild(inst.Version, inst.Vendor, inst.Arch, inst.Image) != nil {
+		return Installation{}, false
+	}
+	return inst, true
+}
+
+var (
+	versionPattern = regexp.MustCompile(`^[0-9][0-9A-Za-z.+_-]*$`)
+	vendorPattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+)
+
+// ValidVersion reports whether a version string such as 17.0.11+9 or
+// 8u412-b08 is well formed
+func ValidVersion(version string) bool {
+	return versionPattern.MatchString(version)
+}
+
+// ValidVendor reports whether a vendor name such as temurin is well formed
+func ValidVendor(vendor string) bool {
+	return vendorPattern.MatchString(vendor)
+}
+
+// CheckBuild returns an error unless a build's fields are safe to put in an
+// install directory name. They come from catalogs and bundles, so a version
+// such as "../../x" must be refused before it reaches a path.
+func CheckBuild(version string, vendor string, cpu string, image string) error {
+	switch {
+	case !ValidVersion(version):
+		return fmt.Errorf("invalid version %q", version)
+	case !ValidVendor(vendor):
+		return fmt.Errorf("invalid vendor %q", vendor)
+	case cpu == arch.Unknown || arch.Normalize(cpu) != cpu:
+		return fmt.Errorf("invalid architecture %q", cpu)
+	case image != "" && !IsImage(image):
+		return fmt.Errorf("invalid image %q", image)
+	}
+	return nil
+}
+
+// WriteInstallation records the metadata of an install in its directory
+func WriteInstallation(inst Installation) error {
+	content, err := json.MarshalIndent(inst, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(inst.Dir, metadataFile), content, 0644)
+}
+
+// ReadInstallation loads the metadata of the install in dir. Without it the
+// fields are taken from the DirName layout, or for directories from the old
+// v<version> layout from the name and the launcher's executable header.
+func ReadInstallation(dir string) Installation {
+	inst := Installation{}
+	content, err := os.ReadFile(filepath.Join(dir, metadataFile))
+	if err != nil || json.Unmarshal(content, &inst) != nil {
+		var ok bool
+		if inst, ok = parseDirName(filepath.Base(dir)); ok {
+			inst.Dir = dir
+			return inst
+		}
+		inst = Installation{
+			Version: strings.TrimPrefix(filepath.Base(dir), "v"),
+			Vendor:  DefaultVendor,
+			Arch:    arch.Detect(dir),
+		}
+	}
+	if inst.Image == "" {
+		inst.Image = ImageJDK
+	}
+	inst.Dir = dir
+	return inst
+}
+
+// GetInstallations returns every complete install under root, newest version first
+func GetInstallations(root string) []Installation {
+	list := make([]Installation, 0)
+	files, _ := os.ReadDir(root)
+
+	for _, f := range files {
+		if !f.IsDir() || !strings.HasPrefix(f.Name(), "v") {
+			continue
+		}
+		dir := filepath.Join(root, f.Name())
+		if !file.Exists(Executable(dir)) {
+			continue
+		}
+		list = append(list, ReadInstallation(dir))
+	}
+
+	// Sort versions in descending order
+	sort.Slice(list, func(i, j int) bool {
+		if c := Compare(list[i].Version, list[j].Version); c != 0 {
+			return c > 0
+		}
+		return list[i].Arch < list[j].Arch
+	})
+
+	return list
+}
+
+// Major returns the feature release of a version string: "17" for
+// "17.0.11+9", "8" for "8u412-b08" and for "1.8.0_412"
+func Major(version string) string {
+	version = strings.TrimPrefix(version, "v")
+	version = strings.TrimPrefix(version, "1.")
+	end := 0
+	for end < len(version) && version[end] >= '0' && version[end] <= '9' {
+		end++
+	}
+	return version[:end]
+}
+
+// Compare orders two Java version strings numerically, returning -1, 0 or 1.
+// It understands both "17.0.11+9" and "8u412-b08" styles.
+func Compare(a string, b string) int {
+	pa, pb := numbers(a), numbers(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// numbers extracts the numeric components of a version string
+func numbers(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	version = strings.TrimPrefix(version, "1.")
+	parts := make([]int, 0, 4)
+	n, inNumber := 0, false
+	for _, c := range version {
+		if c >= '0' && c <= '9' {
+			n = n*10 + int(c-'0')
+			inNumber = true
+			continue
+		}
+		if inNumber {
+			parts = append(parts, n)
+		}
+		n, inNumber = 0, false
+	}
+	if inNumber {
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// Matches reports whether an installed version satisfies a requested one,
+// which is a feature release ("17"), an exact version ("17.0.11+9") or one
+// without its build ("17.0.11", "8u412")
+func Matches(installed string, requested string) bool {
+	if installed == requested {
+		return true
+	}
+	if requested == Major(requested) {
+		return Major(installed) == requested
+	}
+	rest, ok := strings.CutPrefix(installed, requested)
+	return ok && (strings.HasPrefix(rest, "+") || strings.HasPrefix(rest, "-"))
+}
+
+// Find returns the newest install matching q. When several builds match,
+// one for the host architecture wins, and a JDK wins over a JRE.
+func Find(root string, q Query) (Installation, bool) {
+	var found []Installation
+	for _, inst := range GetInstallations(root) {
+		if !Matches(inst.Version, q.Version) {
+			continue
+		}
+		if q.Vendor != "" && inst.Vendor != q.Vendor {
+			continue
+		}
+		if q.Arch != "" && inst.Arch != q.Arch {
+			continue
+		}
+		if q.Image != "" && inst.Image != q.Image {
+			continue
+		}
+		found = append(found, inst)
+	}
+	if len(found) == 0 {
+		return Installation{}, false
+	}
+
+	best := found[0]
+	host := arch.Host()
+	for _, inst := range found {
+		if best.Arch != host && inst.Arch == host {
+			best = inst
+		} else if inst.Arch == best.Arch && inst.Version == best.Version && best.Image != ImageJDK && inst.Image == ImageJDK {
+			best = inst
+		}
+	}
+	return best, true
+}
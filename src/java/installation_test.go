@@ -0,0 +1,244 @@
+package java
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jdkvm/arch"
+)
+
+func TestMajor(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"17", "17"},
+		{"17.0.11+9", "17"},
+		{"v21.0.3+9", "21"},
+		{"8u412-b08", "8"},
+		{"1.8.0_412", "8"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Major(tt.version); got != tt.want {
+			t.Errorf("Major(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"17.0.11+9", "17.0.11+9", 0},
+		{"17.0.9+9", "17.0.11+9", -1},
+		{"17.0.11+10", "17.0.11+9", 1},
+		{"21", "17.0.11+9", 1},
+		{"17", "17.0.0", 0},
+		{"8u412-b08", "8u402-b06", 1},
+		{"11.0.2", "11.0.10", -1},
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := Compare(tt.b, tt.a); got != -tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		installed string
+		requested string
+		want      bool
+	}{
+		{"17.0.11+9", "17", true},
+		{"17.0.11+9", "17.0.11+9", true},
+		{"17.0.11+9", "17.0.11", true},
+		{"17.0.11+9", "17.0.1", false},
+		{"17.0.11+9", "1", false},
+		{"17.0.11+9", "21", false},
+		{"8u412-b08", "8", true},
+		{"8u412-b08", "8u412", true},
+		{"8u412-b08", "8u41", false},
+		{"21.0.3+9", "21.0.3+7", false},
+	}
+	for _, tt := range tests {
+		if got := Matches(tt.installed, tt.requested); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.installed, tt.requested, got, tt.want)
+		}
+	}
+}
+
+func TestCheckBuild(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		vendor  string
+		cpu     string
+		image   string
+		wantErr bool
+	}{
+		{"jdk", "17.0.11+9", "temurin", arch.X64, ImageJDK, false},
+		{"no image", "8u412-b08", "temurin", arch.AArch64, "", false},
+		{"old style version", "1.8.0_412", "zulu", arch.X86, ImageJRE, false},
+		{"version with path", "../../x", "temurin", arch.X64, ImageJDK, true},
+		{"version with separator", "17/evil", "temurin", arch.X64, ImageJDK, true},
+		{"empty version", "", "temurin", arch.X64, ImageJDK, true},
+		{"vendor with path", "17.0.11+9", "../temurin", arch.X64, ImageJDK, true},
+		{"upper case vendor", "17.0.11+9", "Temurin", arch.X64, ImageJDK, true},
+		{"unknown arch", "17.0.11+9", "temurin", arch.Unknown, ImageJDK, true},
+		{"unnormalized arch", "17.0.11+9", "temurin", "amd64", ImageJDK, true},
+		{"unknown image", "17.0.11+9", "temurin", arch.X64, "sdk", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckBuild(tt.version, tt.vendor, tt.cpu, tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckBuild error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDirName(t *testing.T) {
+	tests := []struct {
+		version, vendor, cpu, image string
+		want                        string
+	}{
+		{"17.0.11+9", "temurin", arch.X64, ImageJDK, "v17.0.11+9_temurin_x64"},
+		{"17.0.11+9", "temurin", arch.X64, "", "v17.0.11+9_temurin_x64"},
+		{"21.0.3+9", "temurin", arch.AArch64, ImageJRE, "v21.0.3+9_temurin_aarch64_jre"},
+	}
+	for _, tt := range tests {
+		if got := DirName(tt.version, tt.vendor, tt.cpu, tt.image); got != tt.want {
+			t.Errorf("DirName = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	host := arch.Host()
+	other := arch.X86
+	if host == other {
+		other = arch.X64
+	}
+	installs := []Installation{
+		{Version: "17.0.11+9", Vendor: "temurin", Arch: other, Image: ImageJDK},
+		{Version: "17.0.11+9", Vendor: "temurin", Arch: host, Image: ImageJRE},
+		{Version: "17.0.11+9", Vendor: "temurin", Arch: host, Image: ImageJDK},
+		{Version: "17.0.9+9", Vendor: "temurin", Arch: host, Image: ImageJDK},
+		{Version: "21.0.3+9", Vendor: "zulu", Arch: host, Image: ImageJDK},
+	}
+	for _, inst := range installs {
+		inst.Dir = filepath.Join(root, DirName(inst.Version, inst.Vendor, inst.Arch, inst.Image))
+		if err := os.MkdirAll(filepath.Join(inst.Dir, "bin"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(Executable(inst.Dir), nil, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteInstallation(inst); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		q       Query
+		want    string
+		wantOK  bool
+		wantImg string
+	}{
+		{"feature release prefers host JDK", Query{Version: "17"}, "17.0.11+9", true, ImageJDK},
+		{"release without build", Query{Version: "17.0.9"}, "17.0.9+9", true, ImageJDK},
+		{"image", Query{Version: "17", Image: ImageJRE}, "17.0.11+9", true, ImageJRE},
+		{"vendor", Query{Version: "21", Vendor: "zulu"}, "21.0.3+9", true, ImageJDK},
+		{"other vendor", Query{Version: "21", Vendor: "temurin"}, "", false, ""},
+		{"missing", Query{Version: "11"}, "", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst, ok := Find(root, tt.q)
+			if ok != tt.wantOK {
+				t.Fatalf("Find ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if inst.Version != tt.want || inst.Image != tt.wantImg || inst.Arch != host {
+				t.Errorf("Find = %s %s %s, want %s %s %s", inst.Version, inst.Image, inst.Arch, tt.want, tt.wantImg, host)
+			}
+		})
+	}
+}
+
+func TestParseDirName(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   Installation
+		wantOK bool
+	}{
+		{"v17.0.11+9_temurin_x64", Installation{Version: "17.0.11+9", Vendor: "temurin", Arch: arch.X64, Image: ImageJDK}, true},
+		{"v21.0.3+9_temurin_aarch64_jre", Installation{Version: "21.0.3+9", Vendor: "temurin", Arch: arch.AArch64, Image: ImageJRE}, true},
+		{"v1.8.0_412_zulu_x86", Installation{Version: "1.8.0_412", Vendor: "zulu", Arch: arch.X86, Image: ImageJDK}, true},
+		{"v8u412-b08_temurin_x64", Installation{Version: "8u412-b08", Vendor: "temurin", Arch: arch.X64, Image: ImageJDK}, true},
+		{"v17.0.11+9", Installation{}, false},
+		{"v1.8.0_412", Installation{}, false},
+		{"v17.0.11+9_temurin_amd64", Installation{}, false},
+		{"v17.0.11+9_Temurin_x64", Installation{}, false},
+		{"17.0.11+9_temurin_x64", Installation{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseDirName(tt.name)
+		if ok != tt.wantOK || got.Version != tt.want.Version || got.Vendor != tt.want.Vendor || got.Arch != tt.want.Arch || got.Image != tt.want.Image {
+			t.Errorf("parseDirName(%q) = %+v, %v, want %+v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	// DirName and parseDirName are inverses
+	for _, image := range Images {
+		name := DirName("17.0.11+9", "temurin", arch.AArch64, image)
+		if got, ok := parseDirName(name); !ok || got.Image != image || got.Version != "17.0.11+9" {
+			t.Errorf("parseDirName(%q) = %+v, %v", name, got, ok)
+		}
+	}
+}
+
+func TestReadInstallationWithoutMetadata(t *testing.T) {
+	root := t.TempDir()
+	tests := []struct {
+		dir         string
+		wantVersion string
+		wantVendor  string
+		wantArch    string
+		wantImage   string
+	}{
+		{"v17.0.11+9_zulu_aarch64_jre", "17.0.11+9", "zulu", arch.AArch64, ImageJRE},
+		{"v21.0.3+9_temurin_x64", "21.0.3+9", "temurin", arch.X64, ImageJDK},
+		// The old layout has only the version; the launcher here is not a binary
+		{"v11.0.23+9", "11.0.23+9", DefaultVendor, arch.Unknown, ImageJDK},
+	}
+	for _, tt := range tests {
+		dir := filepath.Join(root, tt.dir)
+		if err := os.MkdirAll(filepath.Join(dir, "bin"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(Executable(dir), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		inst := ReadInstallation(dir)
+		if inst.Version != tt.wantVersion || inst.Vendor != tt.wantVendor || inst.Arch != tt.wantArch || inst.Image != tt.wantImage || inst.Dir != dir {
+			t.Errorf("ReadInstallation(%s) = %+v", tt.dir, inst)
+		}
+	}
+
+	if _, ok := Find(root, Query{Version: "17", Vendor: "zulu", Image: ImageJRE}); !ok {
+		t.Error("Find did not match an install without metadata")
+	}
+}
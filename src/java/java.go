@@ -6,14 +6,14 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
-	"sort"
+	"runtime"
 	"strings"
 
-	"jdkvm/file"
+	"jdkvm/arch"
 )
 
 /**
- * Returns version, architecture
+ * Returns version, architecture; the version is empty when no java is on PATH
  */
 func GetCurrentVersion() (string, string) {
 	cmd := exec.Command("java", "-version")
@@ -40,73 +40,66 @@ func GetCurrentVersion() (string, string) {
 			output := stderr.String()
 			reArch := regexp.MustCompile(`os\.arch=([a-zA-Z0-9_]+)`)
 			archMatches := reArch.FindStringSubmatch(output)
-			var bit string
+			var cpu string
 			if len(archMatches) > 1 {
-				if archMatches[1] == "amd64" || archMatches[1] == "x86_64" {
-					bit = "64"
-				} else if archMatches[1] == "arm64" {
-					bit = "arm64"
-				} else {
-					bit = "32"
-				}
+				cpu = arch.Normalize(archMatches[1])
 			} else {
-				bit = "Unknown"
+				cpu = arch.Unknown
 			}
-			return v, bit
+			return v, cpu
 		}
 	}
-	return "Unknown", ""
+	return "", ""
 }
 
-func IsVersionInstalled(root string, version string, cpu string) bool {
-	// First check if exact version exists
-	versionDir := filepath.Join(root, "v"+version)
-	if file.Exists(versionDir) {
-		javaExe := filepath.Join(versionDir, "bin", "java.exe")
-		if file.Exists(javaExe) {
-			return true
+// Current returns the install the java on PATH belongs to, or the one
+// JAVA_HOME points at when there is no java on PATH
+func Current(root string) (Installation, bool) {
+	home := os.Getenv("JAVA_HOME")
+	if path, err := exec.LookPath("java"); err == nil {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
 		}
+		home = filepath.Dir(filepath.Dir(path))
 	}
-
-	// If exact version not found, check if it's a major version (like 17, 11, 8)
-	// and find any installed version that starts with this major version
-	if !strings.Contains(version, ".") {
-		files, _ := os.ReadDir(root)
-		for _, f := range files {
-			if f.IsDir() && strings.HasPrefix(f.Name(), "v"+version+".") {
-				versionDir := filepath.Join(root, f.Name())
-				javaExe := filepath.Join(versionDir, "bin", "java.exe")
-				if file.Exists(javaExe) {
-					return true
-				}
-			}
+	if home == "" {
+		return Installation{}, false
+	}
+	current, err := os.Stat(home)
+	if err != nil {
+		return Installation{}, false
+	}
+	for _, inst := range GetInstallations(root) {
+		if info, err := os.Stat(inst.Dir); err == nil && os.SameFile(info, current) {
+			return inst, true
 		}
 	}
+	return Installation{}, false
+}
+
+// Executable returns the path of the java launcher inside an install directory
+func Executable(installDir string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(installDir, "bin", "java.exe")
+	}
+	return filepath.Join(installDir, "bin", "java")
+}
 
-	return false
+// IsVersionInstalled reports whether a build of version is installed. An
+// empty cpu matches any architecture.
+func IsVersionInstalled(root string, version string, cpu string) bool {
+	_, ok := Find(root, Query{Version: version, Arch: cpu})
+	return ok
 }
 
+// GetInstalled returns the distinct installed versions, newest first
 func GetInstalled(root string) []string {
 	list := make([]string, 0)
-	files, _ := os.ReadDir(root)
-
-	for i := len(files) - 1; i >= 0; i-- {
-		if files[i].IsDir() {
-			name := files[i].Name()
-			// Check if the directory name starts with "v"
-			if strings.HasPrefix(name, "v") {
-				// Remove the "v" prefix to get the version string
-				versionStr := strings.TrimPrefix(name, "v")
-				list = append(list, versionStr)
-			}
+	for _, inst := range GetInstallations(root) {
+		if len(list) == 0 || list[len(list)-1] != inst.Version {
+			list = append(list, inst.Version)
 		}
 	}
-
-	// Sort versions in descending order (simple string sort for now)
-	sort.Slice(list, func(i, j int) bool {
-		return list[i] > list[j]
-	})
-
 	return list
 }
 
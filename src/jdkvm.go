@@ -1,13 +1,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"jdkvm/arch"
-	"jdkvm/file"
+	"jdkvm/cli"
+	"jdkvm/config"
 	"jdkvm/java"
 	"jdkvm/utility"
 	"jdkvm/web"
@@ -17,210 +21,214 @@ import (
 var JdkvmVersion = "0.1.0"
 
 type Environment struct {
-	settings        string
-	root            string
-	symlink         string
-	arch            string
-	java_mirror     string
-	proxy           string
+	// configFile is the user configuration file `jdkvm config set` writes to
+	configFile string
+	config     *config.Config
+	// root holds the installs; configDir and cacheDir are the same directory
+	// when JDKVM_HOME is set
+	root        string
+	configDir   string
+	cacheDir    string
+	symlink     string
+	arch        string
+	java_mirror string
+	proxy       string
+	// hosts reached without the proxy, and a proxy auto-config file
+	noProxy         string
+	proxyPAC        string
 	originalpath    string
 	originalversion string
 	verifyssl       bool
+	// TLS settings for corporate networks: extra root CAs, client
+	// certificates and the lowest accepted protocol version
+	caBundle      string
+	clientCert    string
+	clientKey     string
+	tlsMinVersion string
+	// upgrade moves JAVA_HOME to the new patch release and removes the old one
+	upgradeMoveDefault bool
+	upgradeRemoveOld   bool
+	// probe mirrors with HEAD requests and try the fastest first
+	mirrorProbe bool
+	// offline uses cached catalogs only, for shell completion and the
+	// commands that only read the installs
+	offline bool
+	// completing is set while answering shell completion, which must not
+	// move anything on disk behind the user's back
+	completing bool
 }
 
-var home = filepath.Join(os.Getenv("JDKVM_HOME"), "settings.txt")
 var symlink = filepath.Clean(os.Getenv("JDKVM_SYMLINK"))
 
 var env = &Environment{
-	settings:        home,
 	root:            "",
 	symlink:         symlink,
-	arch:            strings.ToLower(os.Getenv("PROCESSOR_ARCHITECTURE")),
+	arch:            arch.Host(),
 	java_mirror:     "",
 	proxy:           "none",
 	originalpath:    "",
 	originalversion: "",
 	verifyssl:       true,
+
+	upgradeMoveDefault: true,
+	upgradeRemoveOld:   false,
 }
 
 func main() {
-	// Initialize environment
-	initializeEnvironment()
-
-	// Check admin privileges for commands that need it
-	args := os.Args
-	if len(args) > 1 {
-		command := args[1]
-		// Commands that require admin privileges
-		needsAdmin := []string{"use", "u", "install", "i", "uninstall", "rm"}
-		for _, cmd := range needsAdmin {
-			if command == cmd {
-				checkAdminPrivileges()
-				break
-			}
-		}
-	}
-
-	// Parse arguments
-	detail := ""
-	procarch := arch.Validate(env.arch)
-
-	// Capture any additional arguments
-	if len(args) > 2 {
-		detail = args[2]
-	}
-	if len(args) > 3 {
-		if args[3] == "32" || args[3] == "arm64" || args[3] == "64" {
-			procarch = args[3]
-		}
-	}
-	if len(args) < 2 {
-		help()
-		return
-	}
-
-	// Run the appropriate method
-	switch args[1] {
-	case "i":
-		fallthrough
-	case "install":
-		install(detail, procarch)
-	case "rm":
-		fallthrough
-	case "uninstall":
-		uninstall(detail)
-	case "u":
-		fallthrough
-	case "use":
-		use(detail, procarch)
-	case "ls":
-		fallthrough
-	case "list":
-		list(detail)
-	case "current":
-		current()
-	case "proxy":
-		proxy(detail)
-	case "version":
-		fmt.Println(JdkvmVersion)
-	default:
-		fmt.Printf(`"%s" is not a valid command.`+"\n", args[1])
-		help()
-	}
+	os.Exit(newApp().Run(os.Args[1:]))
 }
 
 // Initialize the environment and set up default values
-func initializeEnvironment() {
-	// Set default JDKVM_HOME if not set
-	if os.Getenv("JDKVM_HOME") == "" {
-		defaultHome := filepath.Join(os.Getenv("USERPROFILE"), ".jdkvm")
-		os.Setenv("JDKVM_HOME", defaultHome)
-		env.root = defaultHome
-		fmt.Printf("JDKVM_HOME not set, using default: %s\n", defaultHome)
+func initializeEnvironment(overrides map[string]string) {
+	// JDKVM_HOME keeps everything in one directory as older releases did;
+	// otherwise data, settings and cache follow the platform's conventions
+	if home := os.Getenv("JDKVM_HOME"); home != "" {
+		env.setDirs(config.HomeDirs(home))
 	} else {
-		env.root = os.Getenv("JDKVM_HOME")
+		env.setDirs(config.DefaultDirs())
+		if !env.completing {
+			migrateLegacyHome()
+		}
+		os.Setenv("JDKVM_HOME", env.root)
 	}
 
 	// Set default JDKVM_SYMLINK if not set (though we're not using symlinks anymore)
 	if os.Getenv("JDKVM_SYMLINK") == "" {
-		defaultSymlink := filepath.Join(os.Getenv("USERPROFILE"), ".jdkvm", "symlink")
+		defaultSymlink := filepath.Join(env.root, "symlink")
 		os.Setenv("JDKVM_SYMLINK", defaultSymlink)
 		env.symlink = defaultSymlink
 	}
 
 	// Create necessary directories
-	os.MkdirAll(env.root, os.ModeDir)
-	
-	// Load version mapping
+	for _, dir := range []string{env.root, env.configDir, env.cacheDir} {
+		os.MkdirAll(dir, os.ModePerm)
+	}
+
+	// Load configuration from the system, user and project files
+	loadConfig(overrides)
+
+	// Apply TLS and proxy settings
+	applyTLS()
+	if err := web.SetProxy(proxySettings()); err != nil {
+		warnf("Ignoring proxy setting: %v", err)
+	}
+
+	// Apply mirror settings
+	if err := web.SetJavaMirror(env.java_mirror); err != nil {
+		warnf("Ignoring java_mirror setting: %v", err)
+	}
+	web.ConfigureMirrorHealth(filepath.Join(env.cacheDir, "mirrors.json"), env.mirrorProbe)
+
+	// Load the catalog last, since a remote catalog needs the network settings
+	web.ConfigureCatalog(web.CatalogSources{
+		SignedFile: signedCatalogFile(),
+		PublicKey:  env.config.Get("catalog_public_key"),
+		UserFile:   filepath.Join(env.configDir, "catalog.json"),
+		RemoteURL:  env.config.Get("catalog_url"),
+		CacheFile:  filepath.Join(env.cacheDir, "remote-catalog.json"),
+		Offline:    env.offline,
+	})
 	if err := web.LoadVersionMapping(); err != nil {
-		fmt.Printf("Warning: Could not load version mapping: %v\n", err)
-		fmt.Println("You may need to specify full version numbers instead of just major versions.")
-	}
-	
-	// Load configuration from settings.txt
-	loadSettings()
-	
-	// Apply proxy settings
-	web.SetProxy(env.proxy, env.verifyssl)
+		warnf("Could not load version mapping: %v", err)
+		fmt.Fprintln(os.Stderr, "You may need to specify full version numbers instead of just major versions.")
+	}
+}
+
+// warnf reports a problem with the setup on stderr, so it never mixes with
+// the paths and documents scripts read from stdout
+func warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
 }
 
 // Check if we have admin privileges, and try to elevate if needed
 func checkAdminPrivileges() {
 	if !utility.IsAdmin() && !utility.IsElevated() {
-		fmt.Println("Warning: JDKVM may require administrator privileges for some operations.")
-		fmt.Println("If you encounter permission errors, run this command again as Administrator.")
+		warnf("JDKVM may require administrator privileges for some operations.")
+		fmt.Fprintln(os.Stderr, "If you encounter permission errors, run this command again as Administrator.")
 	}
 }
 
 // ===============================================================
 // BEGIN | CLI functions
 // ===============================================================
-func install(version string, cpuarch string) {
-	fmt.Printf("Installing Java version %s (%s-bit)...\n", version, cpuarch)
-	
+func install(version string, cpuarch string, vendor string, osName string, image string) error {
 	// Validate version
 	if version == "" {
-		fmt.Println("Please specify a version to install.")
-		return
+		return failed(cli.Usage("please specify a version to install"))
 	}
+	if cpuarch == "" {
+		cpuarch = arch.Validate(env.arch)
+	}
+	if image == "" {
+		image = java.ImageJDK
+	}
+	if err := enforceCatalogPolicy(version, vendor); err != nil {
+		return failed(err)
+	}
+	fmt.Printf("Installing Java version %s (%s, %s)...\n", version, image, cpuarch)
 
 	// Check if version is already installed
-	if java.IsVersionInstalled(env.root, version, cpuarch) {
-		fmt.Printf("Java version %s (%s-bit) is already installed.\n", version, cpuarch)
-		return
+	query := java.Query{Version: version, Vendor: vendor, Arch: cpuarch, Image: image}
+	if java.IsOverlay(image) {
+		query.Image = java.ImageJDK
+	}
+	if inst, ok := java.Find(env.root, query); ok && (!java.IsOverlay(image) || inst.HasExtra(image)) {
+		fmt.Printf("Java version %s (%s, %s) is already installed.\n", version, image, cpuarch)
+		emit(newInstallResult(inst, image, false))
+		return nil
 	}
 
 	// Download Java - web.GetJava will handle directory creation with the correct full version
-	fmt.Printf("Downloading Java version %s (%s-bit)...\n", version, cpuarch)
-	success := web.GetJava(env.root, version, cpuarch)
-	if !success {
-		fmt.Printf("Failed to download Java version %s (%s-bit).\n", version, cpuarch)
-		return
+	fmt.Printf("Downloading Java version %s (%s)...\n", version, cpuarch)
+	if err := web.GetJava(env.root, version, vendor, cpuarch, osName, image); err != nil {
+		return failed(fmt.Errorf("failed to install Java version %s (%s, %s): %w", version, image, cpuarch, err))
 	}
 
-	fmt.Printf("Java version %s (%s-bit) installed successfully.\n", version, cpuarch)
+	fmt.Printf("Java version %s (%s, %s) installed successfully.\n", version, image, cpuarch)
+	warnIfEOL(version)
 	fmt.Printf("To use this version, type: jdkvm use %s\n", version)
+	if inst, ok := java.Find(env.root, query); ok {
+		emit(newInstallResult(inst, image, true))
+	}
+	return nil
+}
+
+// installResult is the JSON result of install and uninstall
+type installResult struct {
+	Version   string `json:"version"`
+	Vendor    string `json:"vendor"`
+	Arch      string `json:"arch"`
+	Image     string `json:"image"`
+	Dir       string `json:"dir"`
+	Installed bool   `json:"installed"`
 }
 
-func use(version string, cpuarch string) {
+func newInstallResult(inst java.Installation, image string, installed bool) installResult {
+	return installResult{Version: inst.Version, Vendor: inst.Vendor, Arch: inst.Arch, Image: image, Dir: inst.Dir, Installed: installed}
+}
+
+// use makes an install the default Java. q filters the installs by vendor,
+// arch and image.
+func use(version string, q java.Query) error {
 	if version == "" {
-		fmt.Println("Please specify a version to use.")
-		return
+		return cli.Usage("please specify a version to use")
 	}
 
-	// Determine the actual version to use
-	var actualVersion string
-	if !strings.Contains(version, ".") {
-		// If it's a major version (like 17, 11, 8), find the installed version
-		files, _ := os.ReadDir(env.root)
-		for _, f := range files {
-			if f.IsDir() && strings.HasPrefix(f.Name(), "v"+version+".") {
-				// Extract the version number from the directory name
-				actualVersion = strings.TrimPrefix(f.Name(), "v")
-				break
-			}
-		}
-		
-		if actualVersion == "" {
-			fmt.Printf("Java version %s (%s-bit) is not installed.\n", version, cpuarch)
-			return
-		}
-	} else {
-		// Exact version specified
-		actualVersion = version
-		if !java.IsVersionInstalled(env.root, actualVersion, cpuarch) {
-			fmt.Printf("Java version %s (%s-bit) is not installed.\n", actualVersion, cpuarch)
-			return
-		}
+	// Resolve a major version (like 17, 11, 8) or an exact version to an install
+	q.Version = version
+	inst, ok := java.Find(env.root, q)
+	if !ok {
+		return notInstalled(version, q)
+	}
+	if err := enforcePolicy(inst.Vendor, inst.Version); err != nil {
+		return err
 	}
+	actualVersion := inst.Version
+	cpuarch := inst.Arch
 
 	// Instead of using symlinks (which require admin rights), we'll directly set JAVA_HOME
 	// and add the bin directory to PATH
-	installDir := filepath.Join(env.root, "v"+actualVersion)
-	if !file.Exists(installDir) {
-		fmt.Printf("Java installation directory not found: %s\n", installDir)
-		return
-	}
+	installDir := inst.Dir
 
 	// Set JAVA_HOME environment variable
 	fmt.Println("Setting JAVA_HOME environment variable...")
@@ -237,12 +245,12 @@ func use(version string, cpuarch string) {
 	// Get the new bin directory
 	javaBinDir := filepath.Join(installDir, "bin")
 	fmt.Printf("Using Java bin directory: %s\n", javaBinDir)
-	
+
 	// Update current process PATH for immediate use
 	currentPath := os.Getenv("PATH")
-	
+
 	// Remove any existing Java bin directories from PATH
-	paths := strings.Split(currentPath, ";")
+	paths := filepath.SplitList(currentPath)
 	newPaths := make([]string, 0)
 	for _, path := range paths {
 		trimmedPath := strings.TrimSpace(path)
@@ -251,14 +259,14 @@ func use(version string, cpuarch string) {
 			newPaths = append(newPaths, trimmedPath)
 		}
 	}
-	
+
 	// Add the new bin directory to the beginning of PATH
 	newPaths = append([]string{javaBinDir}, newPaths...)
-	newPath := strings.Join(newPaths, ";")
-	
+	newPath := strings.Join(newPaths, string(os.PathListSeparator))
+
 	// Set the new PATH for current process
 	os.Setenv("PATH", newPath)
-	
+
 	// Try to set the system PATH (may require admin rights)
 	fmt.Printf("Updating PATH environment variable to include %s\n", javaBinDir)
 	err = utility.SetEnvironmentVariable("PATH", newPath)
@@ -270,11 +278,21 @@ func use(version string, cpuarch string) {
 		fmt.Printf("Successfully updated PATH environment variable.\n")
 	}
 
-	fmt.Printf("Now using Java version %s (%s-bit)\n", actualVersion, cpuarch)
+	fmt.Printf("Now using Java version %s (%s)\n", actualVersion, cpuarch)
+	warnIfEOL(actualVersion)
 	fmt.Println("Note: You may need to restart your command prompt for changes to take effect.")
+	return nil
+}
+
+// Warn when the feature release of version is past its vendor's end of support
+func warnIfEOL(version string) {
+	info, ok := web.JavaVersionMapping[java.Major(version)]
+	if ok && info.IsEOL(time.Now()) {
+		fmt.Printf("Warning: Java %s reached end of support on %s and no longer receives security updates.\n", java.Major(version), info.EOL)
+	}
 }
 
-func list(listtype string) {
+func list(listtype string, showAll bool) error {
 	if listtype == "" {
 		listtype = "installed"
 	}
@@ -282,169 +300,286 @@ func list(listtype string) {
 	if listtype == "installed" {
 		fmt.Println("")
 		installed := java.GetInstalled(env.root)
+		entries := []installedEntry{}
 		if len(installed) == 0 {
 			fmt.Println("No installations recognized.")
-			return
+			emit(entries)
+			return nil
 		}
 
-		current, _ := java.GetCurrentVersion()
-		for _, version := range installed {
+		current, hasCurrent := java.Current(env.root)
+		for _, inst := range java.GetInstallations(env.root) {
+			inUse := hasCurrent && inst.Dir == current.Dir
 			status := "    "
-			if version == current {
+			if inUse {
 				status = "  * "
 			}
-			fmt.Printf("%s%s\n", status, version)
+			fmt.Printf("%s%-20s %-10s %-8s %s\n", status, inst.Version, inst.Vendor, inst.Arch, inst.ImageLabel())
+			entries = append(entries, installedEntry{Version: inst.Version, Vendor: inst.Vendor, Arch: inst.Arch, Image: inst.Image,
+				Extras: inst.Extras, Dir: inst.Dir, Current: inUse})
 		}
+		emit(entries)
 	} else if listtype == "available" {
 		fmt.Println("\nAvailable Java versions:")
 		// Get available versions from version mapping
 		availableVersions := web.GetAvailableVersions()
+		entries := []availableEntry{}
 		if len(availableVersions) > 0 {
+			hidden := 0
 			for _, version := range availableVersions {
-				if web.JavaVersionMapping != nil && web.JavaVersionMapping[version].Latest != "" {
-					fmt.Printf("%s (latest: %s)\n", version, web.JavaVersionMapping[version].Latest)
-				} else {
-					fmt.Printf("%s\n", version)
+				info := web.JavaVersionMapping[version]
+				eol := info.IsEOL(time.Now())
+				// Feature releases without long-term support are of no use once they are out of support
+				if eol && !info.LTS && !showAll {
+					hidden++
+					continue
+				}
+
+				entries = append(entries, availableEntry{Feature: version, Latest: info.Latest, Vendor: info.VendorName(),
+					LTS: info.LTS, EOL: info.EOL, EndOfLife: eol})
+				line := version
+				if info.Latest != "" {
+					line += fmt.Sprintf(" (latest: %s)", info.Latest)
 				}
+				if info.LTS {
+					line += " LTS"
+				}
+				if eol {
+					line += fmt.Sprintf(" [end of life since %s]", info.EOL)
+				} else if info.EOL != "" {
+					line += fmt.Sprintf(" [supported until %s]", info.EOL)
+				}
+				fmt.Println(line)
+			}
+			if hidden > 0 {
+				fmt.Printf("\n%d end-of-life non-LTS version(s) hidden. Use 'jdkvm list available --all' to show them.\n", hidden)
 			}
 		} else {
-			fmt.Println("Could not load available versions. Please check your version_mapping.json file.")
+			return failed(errors.New("could not load available versions. Run 'jdkvm catalog where' to check the catalog"))
 		}
 		fmt.Println("\nYou can install any of these versions by typing: jdkvm install <version>")
-	fmt.Println("For example: jdkvm install 17")
+		fmt.Println("For example: jdkvm install 17")
+		emit(entries)
 	} else {
-		fmt.Println("\nInvalid list option.\n\nPlease use one of the following\n  - jdkvm list\n  - jdkvm list installed\n  - jdkvm list available")
+		return failed(cli.Usage("invalid list option %q; use installed or available", listtype))
 	}
+	return nil
+}
+
+// installedEntry is one install in the JSON result of list installed
+type installedEntry struct {
+	Version string   `json:"version"`
+	Vendor  string   `json:"vendor"`
+	Arch    string   `json:"arch"`
+	Image   string   `json:"image"`
+	Extras  []string `json:"extras,omitempty"`
+	Dir     string   `json:"dir"`
+	Current bool     `json:"current"`
+}
+
+// availableEntry is one feature release in the JSON result of list available
+type availableEntry struct {
+	Feature   string `json:"feature"`
+	Latest    string `json:"latest"`
+	Vendor    string `json:"vendor"`
+	LTS       bool   `json:"lts"`
+	EOL       string `json:"eol,omitempty"`
+	EndOfLife bool   `json:"end_of_life"`
 }
 
-func uninstall(version string) {
+func uninstall(version string, q java.Query) error {
 	if version == "" {
-		fmt.Println("Please specify a version to uninstall.")
-		return
+		return failed(cli.Usage("please specify a version to uninstall"))
 	}
 
 	// Check if version is installed
-	if !java.IsVersionInstalled(env.root, version, "32") && !java.IsVersionInstalled(env.root, version, "64") {
-		fmt.Printf("Java version %s is not installed.\n", version)
-		return
+	q.Version = version
+	inst, ok := java.Find(env.root, q)
+	if !ok {
+		return failed(notInstalled(version, q))
+	}
+	version = fmt.Sprintf("%s (%s, %s)", inst.Version, inst.Image, inst.Arch)
+	if !confirm(fmt.Sprintf("Uninstall Java %s from %s?", version, inst.Dir)) {
+		return failed(errors.New("uninstall cancelled"))
 	}
 
 	// Remove installation directory
-	installDir := filepath.Join(env.root, "v"+version)
-	err := os.RemoveAll(installDir)
+	err := os.RemoveAll(inst.Dir)
 	if err != nil {
-		fmt.Printf("Failed to uninstall Java version %s: %v\n", version, err)
-		return
+		return failed(fmt.Errorf("failed to uninstall Java version %s: %v", version, err))
 	}
 
 	fmt.Printf("Java version %s uninstalled successfully.\n", version)
+	emit(newInstallResult(inst, inst.Image, false))
+	return nil
 }
 
 func current() {
-	inuse, arch := java.GetCurrentVersion()
-	if inuse == "Unknown" {
+	inuse, cpu := java.GetCurrentVersion()
+	if inuse == "" {
 		fmt.Println("No current version. Run 'jdkvm use x.x.x' to set a version.")
+		emit(currentResult{})
 		return
 	}
 
-	fmt.Printf("Java version %s (%s-bit) is currently in use.\n", inuse, arch)
+	fmt.Printf("Java version %s (%s) is currently in use.\n", inuse, cpu)
+	emit(currentResult{Active: true, Version: inuse, Arch: cpu, JavaHome: os.Getenv("JAVA_HOME")})
 }
 
-// Load settings from configuration file
-func loadSettings() {
-	if !file.Exists(env.settings) {
-		// Create default settings file
-		saveSettings()
-		return
+// currentResult is the JSON result of current
+type currentResult struct {
+	Active   bool   `json:"active"`
+	Version  string `json:"version,omitempty"`
+	Arch     string `json:"arch,omitempty"`
+	JavaHome string `json:"java_home,omitempty"`
+}
+
+// Run a command with JAVA_HOME and PATH pointing at an installed version,
+// without changing the persistent environment. The command's own exit status
+// becomes jdkvm's.
+func execJava(version string, q java.Query, command []string) error {
+	if version == "" || len(command) == 0 {
+		return cli.Usage("exec needs a version and a command")
 	}
 
-	content, err := os.ReadFile(env.settings)
-	if err != nil {
-		fmt.Printf("Warning: Could not read settings file: %v\n", err)
-		return
+	q.Version = version
+	inst, ok := java.Find(env.root, q)
+	if !ok {
+		return notInstalled(version, q)
 	}
 
-	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
+	binDir := filepath.Join(inst.Dir, "bin")
+	os.Setenv("JAVA_HOME", inst.Dir)
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
 
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
+	// Resolve the command against the new PATH so "java" means the selected build
+	name := command[0]
+	if resolved, err := exec.LookPath(name); err == nil {
+		name = resolved
+	}
+
+	cmd := exec.Command(name, command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		// The command has printed its own errors; only its status is passed on
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return cli.Reported(cli.Exit(exitErr.ExitCode(), "%s exited with status %d", command[0], exitErr.ExitCode()))
 		}
+		return fmt.Errorf("failed to run %s: %v", command[0], err)
+	}
+	return nil
+}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
+// loadConfig reads the layered configuration into env. The settings.txt of
+// older releases is converted to config.toml the first time.
+func loadConfig(overrides map[string]string) {
+	env.configFile = filepath.Join(env.configDir, "config.toml")
+	legacy := filepath.Join(env.configDir, "settings.txt")
+	if migrated, err := config.MigrateLegacy(legacy, env.configFile); err != nil {
+		warnf("Could not migrate %s: %v", legacy, err)
+	} else if migrated {
+		fmt.Fprintf(os.Stderr, "Migrated %s to %s\n", legacy, env.configFile)
+	}
 
-		switch strings.ToLower(key) {
-		case "proxy":
-			env.proxy = value
-		case "java_mirror":
-			env.java_mirror = value
-		case "verifyssl":
-			env.verifyssl = value == "true"
-		}
+	cwd, _ := os.Getwd()
+	env.config = config.Load(config.Sources{
+		System:  config.SystemFile(),
+		User:    env.configFile,
+		Project: config.FindProjectFile(cwd),
+		Flags:   overrides,
+	})
+	for _, w := range env.config.Warnings {
+		warnf("Ignoring configuration %s", w)
+	}
+
+	c := env.config
+	if a := c.Get("arch"); a != "" {
+		env.arch = a
 	}
+	env.java_mirror = c.Get("java_mirror")
+	env.mirrorProbe = c.Bool("mirror_probe")
+	env.proxy = c.Get("proxy")
+	env.noProxy = c.Get("no_proxy")
+	env.proxyPAC = c.Get("proxy_pac")
+	env.verifyssl = c.Bool("verifyssl")
+	env.caBundle = c.Get("ca_bundle")
+	env.clientCert = c.Get("client_cert")
+	env.clientKey = c.Get("client_key")
+	env.tlsMinVersion = c.Get("tls_min_version")
+	env.upgradeMoveDefault = c.Bool("upgrade_move_default")
+	env.upgradeRemoveOld = c.Bool("upgrade_remove_old")
 }
 
-// Save settings to configuration file
-func saveSettings() {
-	content := "# JDKVM configuration file\n"
-	content += fmt.Sprintf("proxy=%s\n", env.proxy)
-	content += fmt.Sprintf("java_mirror=%s\n", env.java_mirror)
-	content += fmt.Sprintf("verifyssl=%t\n", env.verifyssl)
+// saveSetting writes a key to the user configuration file
+func saveSetting(key string, value string) {
+	if err := config.SetInFile(env.configFile, key, value); err != nil {
+		fmt.Printf("Warning: Could not save %s: %v\n", key, err)
+		return
+	}
+	warnIfOverridden(key, env.configFile)
+}
 
-	err := os.WriteFile(env.settings, []byte(content), 0644)
+// applyTLS hands the TLS settings to the shared HTTP client. Invalid
+// settings keep certificate verification on rather than aborting.
+func applyTLS() {
+	if !env.verifyssl {
+		warnf("TLS certificate verification is disabled (verifyssl=false).")
+	}
+	err := web.SetTLS(web.TLSOptions{
+		Verify:     env.verifyssl,
+		CABundle:   env.caBundle,
+		ClientCert: env.clientCert,
+		ClientKey:  env.clientKey,
+		MinVersion: env.tlsMinVersion,
+	})
 	if err != nil {
-		fmt.Printf("Warning: Could not save settings file: %v\n", err)
+		warnf("Ignoring TLS settings: %v", err)
 	}
 }
 
-// Handle proxy command
-func proxy(proxyUrl string) {
-	if proxyUrl == "" {
-		// Show current proxy settings
-		fmt.Printf("Current proxy: %s\n", env.proxy)
-		if env.proxy != "none" {
-			fmt.Println("To remove proxy, use: jdkvm proxy none")
-		}
-	} else {
-		// Set new proxy settings
-		env.proxy = proxyUrl
-		
-		// Apply proxy settings to HTTP client
-		web.SetProxy(proxyUrl, env.verifyssl)
-		
-		// Save to configuration file
-		saveSettings()
-		
-		if proxyUrl == "none" {
-			fmt.Println("Proxy removed.")
+// Handle java_mirror command
+func javaMirror(mirror string) error {
+	if mirror == "" {
+		// Show current mirror settings
+		if env.java_mirror == "" || env.java_mirror == "none" {
+			fmt.Println("Current Java mirror: none")
 		} else {
-			fmt.Printf("Proxy set to: %s\n", proxyUrl)
+			fmt.Printf("Current Java mirror: %s\n", env.java_mirror)
+			fmt.Println("To remove the mirror, use: jdkvm java_mirror none")
+		}
+
+		// Show what previous downloads learned about each mirror
+		report := web.MirrorHealthReport()
+		if len(report) > 0 {
+			fmt.Println("\nMirror health:")
+			for host, h := range report {
+				status := "ok"
+				if h.Failures > 0 {
+					status = fmt.Sprintf("%d consecutive failure(s), last at %s", h.Failures, h.LastFailure.Format("2006-01-02 15:04"))
+				}
+				latency := ""
+				if h.LatencyMs > 0 {
+					latency = fmt.Sprintf(" (%d ms)", h.LatencyMs)
+				}
+				fmt.Printf("  %s%s: %s\n", host, latency, status)
+			}
 		}
+		return nil
 	}
-}
 
-func help() {
-	fmt.Println("\nUsage: jdkvm [command] [arguments]\n")
-	fmt.Println("Commands:")
-	fmt.Println("  install, i    Install a specific Java version")
-	fmt.Println("  use, u        Switch to a specific Java version")
-	fmt.Println("  uninstall, rm Uninstall a specific Java version")
-	fmt.Println("  list, ls      List installed or available Java versions")
-	fmt.Println("  current       Show current Java version")
-	fmt.Println("  proxy         Set or show proxy settings")
-	fmt.Println("  version       Show JDKVM version")
-
-	fmt.Println("\nExamples:")
-	fmt.Println("  jdkvm install 17")
-	fmt.Println("  jdkvm use 17")
-	fmt.Println("  jdkvm list installed")
-	fmt.Println("  jdkvm proxy http://127.0.0.1:7890")
-	fmt.Println("  jdkvm proxy none")
-	fmt.Println("  jdkvm uninstall 17")
+	// Validate before saving so a typo does not silently disable the mirror
+	if err := web.SetJavaMirror(mirror); err != nil {
+		return cli.Usage("invalid mirror: %v", err)
+	}
+	env.java_mirror = mirror
+	saveSetting("java_mirror", mirror)
+
+	if mirror == "none" {
+		fmt.Println("Java mirror removed.")
+	} else {
+		fmt.Printf("Java mirror set to: %s\n", mirror)
+	}
+	return nil
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"jdkvm/web"
+)
+
+// captureStdout returns what fn prints to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	saved := os.Stdout
+	os.Stdout = f
+	defer func() { os.Stdout = saved }()
+	fn()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// useEOLCatalog lists feature releases with support ending yesterday, today,
+// tomorrow and never
+func useEOLCatalog(t *testing.T) (string, string, string) {
+	now := time.Now()
+	yesterday, today, tomorrow := now.AddDate(0, 0, -1).Format("2006-01-02"), now.Format("2006-01-02"), now.AddDate(0, 0, 1).Format("2006-01-02")
+	useTestCatalog(t, map[string]web.FeatureRelease{
+		"8":  {Vendor: "temurin", LTS: true, EOL: yesterday, Releases: releases("8.0.412+8")},
+		"17": {Vendor: "temurin", LTS: true, Releases: releases("17.0.11+9")},
+		"20": {Vendor: "temurin", EOL: yesterday, Releases: releases("20.0.2+9")},
+		"21": {Vendor: "temurin", LTS: true, EOL: tomorrow, Releases: releases("21.0.3+9")},
+		"22": {Vendor: "temurin", EOL: today, Releases: releases("22.0.1+8")},
+	})
+	return yesterday, today, tomorrow
+}
+
+func TestListAvailable(t *testing.T) {
+	useTestEnv(t)
+	yesterday, today, tomorrow := useEOLCatalog(t)
+
+	lines := map[string]string{
+		"8":  "8 (latest: 8.0.412+8) LTS [end of life since " + yesterday + "]",
+		"17": "17 (latest: 17.0.11+9) LTS",
+		"20": "20 (latest: 20.0.2+9) [end of life since " + yesterday + "]",
+		"21": "21 (latest: 21.0.3+9) LTS [supported until " + tomorrow + "]",
+		"22": "22 (latest: 22.0.1+8) [supported until " + today + "]",
+	}
+	tests := []struct {
+		name       string
+		showAll    bool
+		want       []string
+		wantHidden bool
+	}{
+		{"end-of-life non-LTS hidden", false, []string{"8", "17", "21", "22"}, true},
+		{"all", true, []string{"8", "17", "20", "21", "22"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			out := captureStdout(t, func() { err = list("available", tt.showAll) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			printed := map[string]bool{}
+			for _, line := range strings.Split(out, "\n") {
+				printed[line] = true
+			}
+			for feature, line := range lines {
+				want := false
+				for _, f := range tt.want {
+					want = want || f == feature
+				}
+				if printed[line] != want {
+					t.Errorf("line %q printed = %v, want %v\noutput:\n%s", line, printed[line], want, out)
+				}
+			}
+			if hidden := strings.Contains(out, "1 end-of-life non-LTS version(s) hidden"); hidden != tt.wantHidden {
+				t.Errorf("hidden note = %v, want %v", hidden, tt.wantHidden)
+			}
+		})
+	}
+}
+
+func TestListAvailableJSON(t *testing.T) {
+	useTestEnv(t)
+	useEOLCatalog(t)
+	f := useJSONOutput(t, "list")
+	if err := list("available", true); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []availableEntry
+	if err := json.Unmarshal(readDocument(t, f).Data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]struct{ lts, endOfLife bool }{
+		"8": {true, true}, "17": {true, false}, "20": {false, true}, "21": {true, false}, "22": {false, false},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("%d entries, want %d", len(entries), len(want))
+	}
+	for _, e := range entries {
+		if w := want[e.Feature]; e.LTS != w.lts || e.EndOfLife != w.endOfLife {
+			t.Errorf("%s: lts = %v, end_of_life = %v, want %v, %v", e.Feature, e.LTS, e.EndOfLife, w.lts, w.endOfLife)
+		}
+	}
+}
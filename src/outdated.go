@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"jdkvm/cli"
+	"jdkvm/java"
+	"jdkvm/web"
+)
+
+// outdatedEntry describes one install compared against the catalog
+type outdatedEntry struct {
+	Version string `json:"version"`
+	Feature string `json:"feature"`
+	Vendor  string `json:"vendor"`
+	Arch    string `json:"arch"`
+	Image   string `json:"image"`
+	// LatestPatch is the newest release of the install's vendor in its
+	// feature release, Latest the newest of any vendor
+	LatestPatch string `json:"latest_patch"`
+	Latest      string `json:"latest"`
+	// NewerLTS is the newest LTS feature release when it is newer than the install's
+	NewerLTS string `json:"newer_lts,omitempty"`
+	Outdated bool   `json:"outdated"`
+	EOL      bool   `json:"eol"`
+}
+
+// outdated reports installs that are behind the newest patch of their feature
+// release. It never changes anything and exits with status 1 when something is
+// outdated or end-of-life, so CI can gate images on it, or 4 without a catalog.
+func outdated() error {
+	if len(web.GetAvailableVersions()) == 0 {
+		return failed(cli.Exit(cli.ExitNetwork, "could not load the catalog. Run 'jdkvm catalog where' to check it"))
+	}
+
+	entries := outdatedReport(java.GetInstallations(env.root))
+	stale := false
+	for _, e := range entries {
+		if e.Outdated || e.EOL {
+			stale = true
+		}
+	}
+
+	if jsonOutput() {
+		emit(entries)
+	} else if len(entries) == 0 {
+		fmt.Println("No installations recognized.")
+	} else {
+		fmt.Printf("\n%-20s %-8s %-8s %-20s %-20s %s\n", "INSTALLED", "ARCH", "IMAGE", "LATEST PATCH", "LATEST", "STATUS")
+		for _, e := range entries {
+			status := "up to date"
+			if e.Outdated {
+				status = "outdated"
+			}
+			if e.EOL {
+				status += ", end of life"
+			}
+			if e.NewerLTS != "" {
+				status += fmt.Sprintf(", newer LTS available (%s)", e.NewerLTS)
+			}
+			fmt.Printf("%-20s %-8s %-8s %-20s %-20s %s\n", e.Version, e.Arch, e.Image, e.LatestPatch, e.Latest, status)
+		}
+	}
+
+	if stale {
+		return cli.Reported(errors.New("installs are outdated or end of life"))
+	}
+	return nil
+}
+
+// outdatedReport compares installs with their vendor's releases in the
+// catalog and with the newest release of their feature release from any
+// vendor, and points out a newer LTS feature release. A feature release past its support end date, or no longer in the
+// catalog, is reported as end-of-life.
+func outdatedReport(installed []java.Installation) []outdatedEntry {
+	latestLTS := ""
+	for _, v := range web.GetAvailableVersions() {
+		if info, ok := web.FindRelease(v, ""); ok && info.LTS && (latestLTS == "" || java.Compare(v, latestLTS) > 0) {
+			latestLTS = v
+		}
+	}
+
+	entries := make([]outdatedEntry, 0, len(installed))
+	for _, inst := range installed {
+		e := outdatedEntry{
+			Version: inst.Version,
+			Feature: java.Major(inst.Version),
+			Vendor:  inst.Vendor,
+			Arch:    inst.Arch,
+			Image:   inst.ImageLabel(),
+		}
+		if latestLTS != "" && java.Compare(latestLTS, e.Feature) > 0 {
+			e.NewerLTS = latestLTS
+		}
+		if info, ok := web.FindRelease(e.Feature, inst.Vendor); ok {
+			e.LatestPatch = info.Latest
+			e.Outdated = java.Compare(inst.Version, info.Latest) < 0
+		}
+		if releases := web.GetReleases(e.Feature, ""); len(releases) > 0 {
+			// Other vendors' builds still tell the support end
+			e.Latest = releases[0].Latest
+			e.EOL = releases[0].IsEOL(time.Now())
+		} else {
+			e.EOL = true
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"jdkvm/arch"
+	"jdkvm/cli"
+	"jdkvm/java"
+	"jdkvm/web"
+)
+
+// useOutdatedCatalog lists 11 past its support end, 17 with a newer build
+// from another vendor, LTS 21 and the newer non-LTS 22
+func useOutdatedCatalog(t *testing.T) {
+	useTestCatalog(t, map[string]web.FeatureRelease{
+		"11": {Vendor: "temurin", LTS: true, EOL: "2020-01-01", Releases: releases("11.0.23+9")},
+		"17": {Vendor: "temurin", LTS: true, Releases: append(releases("17.0.12+7", "17.0.11+9"),
+			web.Release{Version: "17.0.13+11", Vendor: "zulu"})},
+		"21": {Vendor: "temurin", LTS: true, Releases: releases("21.0.3+9")},
+		"22": {Vendor: "temurin", Releases: releases("22.0.1+8")},
+	})
+}
+
+func TestOutdatedReport(t *testing.T) {
+	root := useTestEnv(t)
+	useOutdatedCatalog(t)
+	for _, inst := range []java.Installation{
+		{Version: "17.0.9+9", Vendor: "temurin", Arch: arch.Host()},
+		{Version: "17.0.12+7", Vendor: "temurin", Arch: arch.Host()},
+		{Version: "11.0.23+9", Vendor: "temurin", Arch: arch.Host()},
+		{Version: "21.0.3+9", Vendor: "temurin", Arch: arch.Host()},
+		{Version: "22.0.1+8", Vendor: "temurin", Arch: arch.Host()},
+		{Version: "8.0.412+8", Vendor: "zulu", Arch: arch.Host()},
+	} {
+		writeInstall(t, root, inst)
+	}
+
+	want := map[string]outdatedEntry{
+		"17.0.9+9":  {Feature: "17", LatestPatch: "17.0.12+7", Latest: "17.0.13+11", NewerLTS: "21", Outdated: true},
+		"17.0.12+7": {Feature: "17", LatestPatch: "17.0.12+7", Latest: "17.0.13+11", NewerLTS: "21"},
+		"11.0.23+9": {Feature: "11", LatestPatch: "11.0.23+9", Latest: "11.0.23+9", NewerLTS: "21", EOL: true},
+		"21.0.3+9":  {Feature: "21", LatestPatch: "21.0.3+9", Latest: "21.0.3+9"},
+		"22.0.1+8":  {Feature: "22", LatestPatch: "22.0.1+8", Latest: "22.0.1+8"},
+		"8.0.412+8": {Feature: "8", NewerLTS: "21", EOL: true},
+	}
+	entries := outdatedReport(java.GetInstallations(root))
+	if len(entries) != len(want) {
+		t.Fatalf("%d entries, want %d", len(entries), len(want))
+	}
+	for _, e := range entries {
+		w := want[e.Version]
+		w.Version, w.Vendor, w.Arch, w.Image = e.Version, e.Vendor, e.Arch, e.Image
+		if e != w {
+			t.Errorf("entry for %s =\n%+v\nwant\n%+v", e.Version, e, w)
+		}
+	}
+}
+
+func TestOutdatedExitStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		installs   []string
+		wantStatus int
+	}{
+		{"up to date", []string{"21.0.3+9", "17.0.12+7"}, cli.ExitOK},
+		{"newer LTS only", []string{"17.0.12+7"}, cli.ExitOK},
+		{"outdated", []string{"21.0.3+9", "17.0.9+9"}, cli.ExitFailure},
+		{"end of life", []string{"11.0.23+9"}, cli.ExitFailure},
+		{"nothing installed", nil, cli.ExitOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := useTestEnv(t)
+			useOutdatedCatalog(t)
+			for _, v := range tt.installs {
+				writeInstall(t, root, java.Installation{Version: v, Vendor: "temurin", Arch: arch.Host()})
+			}
+			f := useJSONOutput(t, "outdated")
+
+			err := outdated()
+			if status := statusOf(err); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d (%v)", status, tt.wantStatus, err)
+			}
+			doc := readDocument(t, f)
+			if !doc.OK || doc.Command != "outdated" {
+				t.Errorf("document = %+v", doc)
+			}
+			var entries []outdatedEntry
+			if err := json.Unmarshal(doc.Data, &entries); err != nil || len(entries) != len(tt.installs) {
+				t.Errorf("data = %s (%v), want %d entries", doc.Data, err, len(tt.installs))
+			}
+		})
+	}
+
+	t.Run("no catalog", func(t *testing.T) {
+		useTestEnv(t)
+		useTestCatalog(t, map[string]web.FeatureRelease{})
+		f := useJSONOutput(t, "outdated")
+		err := outdated()
+		if status := statusOf(err); status != cli.ExitNetwork {
+			t.Errorf("status = %d, want %d", status, cli.ExitNetwork)
+		}
+		if doc := readDocument(t, f); doc.OK || doc.Error == "" {
+			t.Errorf("document = %+v", doc)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"jdkvm/cli"
+)
+
+// outputSchemaVersion is bumped whenever a JSON document changes
+// incompatibly; adding fields does not change it
+const outputSchemaVersion = 1
+
+// Output formats for --output
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// jsonCommands maps the commands that can render JSON, and their aliases,
+// to the name their documents carry
+var jsonCommands = map[string]string{
+	"list": "list", "ls": "list", "current": "current", "install": "install", "i": "install",
+	"uninstall": "uninstall", "rm": "uninstall", "outdated": "outdated", "doctor": "doctor", "config": "config list",
+}
+
+var (
+	outputFormat  = outputText
+	outputCommand string
+	// resultOut receives the JSON document; while it is being produced,
+	// everything else printed goes to stderr
+	resultOut = os.Stdout
+)
+
+// outputDocument wraps every JSON result
+type outputDocument struct {
+	SchemaVersion int         `json:"schema_version"`
+	Command       string      `json:"command"`
+	OK            bool        `json:"ok"`
+	Error         string      `json:"error,omitempty"`
+	Data          interface{} `json:"data,omitempty"`
+}
+
+// setOutput selects the output format. For JSON, progress messages printed
+// along the way are moved to stderr, so stdout carries only the document.
+func setOutput(format string, command string) error {
+	switch format {
+	case "", outputText:
+		return nil
+	case outputJSON:
+		name, supported := jsonCommands[command]
+		if !supported {
+			return fmt.Errorf("--output json is not supported by %q (supported: list, current, install, uninstall, outdated, doctor, config list)", command)
+		}
+		outputFormat, outputCommand = outputJSON, name
+		resultOut = os.Stdout
+		os.Stdout = os.Stderr
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, use text or json", format)
+}
+
+func jsonOutput() bool {
+	return outputFormat == outputJSON
+}
+
+// emit writes a command's result as a JSON document. It does nothing for
+// text output, where the command has already printed its result.
+func emit(data interface{}) {
+	if !jsonOutput() {
+		return
+	}
+	writeDocument(outputDocument{SchemaVersion: outputSchemaVersion, Command: outputCommand, OK: true, Data: data})
+}
+
+// failed reports a failed command. For JSON output the error becomes the
+// document; either way the error still decides the exit status.
+func failed(err error) error {
+	if !jsonOutput() {
+		return err
+	}
+	writeDocument(outputDocument{SchemaVersion: outputSchemaVersion, Command: outputCommand, Error: strings.TrimSpace(err.Error())})
+	return cli.Reported(err)
+}
+
+func writeDocument(doc outputDocument) {
+	content, _ := json.MarshalIndent(doc, "", "  ")
+	fmt.Fprintln(resultOut, string(content))
+}
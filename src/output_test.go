@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"jdkvm/cli"
+	"jdkvm/web"
+)
+
+// decodedDocument is an outputDocument with its data left undecoded
+type decodedDocument struct {
+	SchemaVersion int             `json:"schema_version"`
+	Command       string          `json:"command"`
+	OK            bool            `json:"ok"`
+	Error         string          `json:"error"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// useJSONOutput selects --output json for a command and returns the file
+// the document is written to
+func useJSONOutput(t *testing.T, command string) *os.File {
+	t.Helper()
+	savedFormat, savedCommand, savedOut, savedStdout := outputFormat, outputCommand, resultOut, os.Stdout
+	t.Cleanup(func() {
+		outputFormat, outputCommand, resultOut, os.Stdout = savedFormat, savedCommand, savedOut, savedStdout
+	})
+	if err := setOutput(outputJSON, command); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.CreateTemp(t.TempDir(), "document")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	resultOut = f
+	return f
+}
+
+// readDocument decodes the single document written to f
+func readDocument(t *testing.T, f *os.File) decodedDocument {
+	t.Helper()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	var doc decodedDocument
+	dec := json.NewDecoder(f)
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatalf("decoding the document: %v", err)
+	}
+	if dec.More() {
+		t.Error("more than one document was written")
+	}
+	return doc
+}
+
+// statusOf returns the exit status the app would report for err
+func statusOf(err error) int {
+	if err == nil {
+		return cli.ExitOK
+	}
+	var exitErr *cli.Error
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return exitCode(err)
+}
+
+// documentKeys returns the top-level keys of the document written to f
+func documentKeys(t *testing.T, f *os.File) map[string]bool {
+	t.Helper()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(f).Decode(&fields); err != nil {
+		t.Fatal(err)
+	}
+	keys := map[string]bool{}
+	for k := range fields {
+		keys[k] = true
+	}
+	return keys
+}
+
+func TestEmit(t *testing.T) {
+	f := useJSONOutput(t, "ls")
+	fmt.Println("progress goes to stderr")
+	emit([]string{"17.0.11+9"})
+
+	doc := readDocument(t, f)
+	if doc.SchemaVersion != outputSchemaVersion || doc.Command != "list" || !doc.OK || doc.Error != "" {
+		t.Errorf("document = %+v", doc)
+	}
+	var data []string
+	if err := json.Unmarshal(doc.Data, &data); err != nil || len(data) != 1 || data[0] != "17.0.11+9" {
+		t.Errorf("data = %s (%v)", doc.Data, err)
+	}
+	keys := documentKeys(t, f)
+	for _, k := range []string{"schema_version", "command", "ok", "data"} {
+		if !keys[k] {
+			t.Errorf("the document has no %q", k)
+		}
+	}
+	if keys["error"] {
+		t.Error("a successful document has an error")
+	}
+}
+
+func TestFailed(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantError  string
+		wantStatus int
+	}{
+		{"exit code", cli.Exit(cli.ExitNotInstalled, "Java 17 is not installed\n"), "Java 17 is not installed", cli.ExitNotInstalled},
+		{"network error", fmt.Errorf("could not download: %w", web.ErrNetwork), "could not download: " + web.ErrNetwork.Error(), cli.ExitNetwork},
+		{"other error", errors.New("disk full"), "disk full", cli.ExitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := useJSONOutput(t, "install")
+			err := failed(tt.err)
+			if status := statusOf(err); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Error("the returned error does not wrap the original")
+			}
+
+			doc := readDocument(t, f)
+			if doc.SchemaVersion != outputSchemaVersion || doc.Command != "install" || doc.OK || doc.Error != tt.wantError {
+				t.Errorf("document = %+v, want error %q", doc, tt.wantError)
+			}
+			keys := documentKeys(t, f)
+			if !keys["ok"] || !keys["error"] || keys["data"] {
+				t.Errorf("document keys = %v", keys)
+			}
+		})
+	}
+}
+
+func TestTextOutput(t *testing.T) {
+	savedOut := resultOut
+	t.Cleanup(func() { resultOut = savedOut })
+	f, err := os.CreateTemp(t.TempDir(), "document")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	resultOut = f
+
+	emit("ignored")
+	cause := errors.New("failure")
+	if err := failed(cause); err != cause {
+		t.Errorf("failed = %v, want the error unchanged", err)
+	}
+	if info, _ := f.Stat(); info.Size() != 0 {
+		t.Error("text output wrote a document")
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	tests := []struct {
+		format  string
+		command string
+		wantErr string
+	}{
+		{"", "list", ""},
+		{"text", "use", ""},
+		{"json", "use", "not supported"},
+		{"yaml", "list", "unknown output format"},
+	}
+	for _, tt := range tests {
+		err := setOutput(tt.format, tt.command)
+		if (err == nil) != (tt.wantErr == "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("setOutput(%q, %q) = %v, want %q", tt.format, tt.command, err, tt.wantErr)
+		}
+		if jsonOutput() {
+			t.Errorf("setOutput(%q, %q) selected JSON", tt.format, tt.command)
+		}
+	}
+}
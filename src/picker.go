@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"jdkvm/cli"
+	"jdkvm/java"
+	"jdkvm/tui"
+	"jdkvm/web"
+)
+
+// canPick reports whether a missing version can be asked for interactively
+// instead of failing
+func canPick(version string) bool {
+	return version == "" && !jsonOutput() && tui.Interactive()
+}
+
+// pickInstalled lets the user choose among the installs matching q, with the
+// one in use marked
+func pickInstalled(q java.Query) (java.Installation, error) {
+	var installs []java.Installation
+	for _, inst := range java.GetInstallations(env.root) {
+		if (q.Vendor == "" || inst.Vendor == q.Vendor) && (q.Arch == "" || inst.Arch == q.Arch) && (q.Image == "" || inst.Image == q.Image) {
+			installs = append(installs, inst)
+		}
+	}
+	if len(installs) == 0 {
+		return java.Installation{}, cli.Exit(cli.ExitNotInstalled, "no matching Java version is installed; install one with: jdkvm install <version>")
+	}
+
+	current, hasCurrent := java.Current(env.root)
+	items := make([]tui.Item, len(installs))
+	for i, inst := range installs {
+		marker := "  "
+		if hasCurrent && inst.Dir == current.Dir {
+			marker = "* "
+		}
+		items[i] = tui.Item{Label: fmt.Sprintf("%s%-20s %-10s %-8s %s", marker, inst.Version, inst.Vendor, inst.Arch, inst.ImageLabel())}
+	}
+	i, err := tui.Pick("Choose the Java version to use (* is in use)", items)
+	if err != nil {
+		return java.Installation{}, pickFailed(err)
+	}
+	return installs[i], nil
+}
+
+// pickRelease lets the user choose a feature release from the catalog,
+// grouped by vendor with the long-term support releases first. It returns
+// the version and the vendor to install it from.
+func pickRelease(vendor string) (string, string, error) {
+	type release struct {
+		version string
+		info    web.JavaVersionInfo
+	}
+	vendors := []string{vendor}
+	if vendor == "" {
+		vendors = catalogVendors()
+	}
+	var releases []release
+	for _, v := range web.GetAvailableVersions() {
+		for _, name := range vendors {
+			info, ok := web.FindRelease(v, name)
+			// Like list available, skip what is of no use any more
+			if !ok || (info.IsEOL(time.Now()) && !info.LTS) {
+				continue
+			}
+			releases = append(releases, release{v, info})
+		}
+	}
+	if len(releases) == 0 {
+		return "", "", cli.Exit(cli.ExitNotInstalled, "the catalog has no matching Java versions. Run 'jdkvm catalog where' to check the catalog")
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		a, b := releases[i], releases[j]
+		if a.info.VendorName() != b.info.VendorName() {
+			return a.info.VendorName() < b.info.VendorName()
+		}
+		if a.info.LTS != b.info.LTS {
+			return a.info.LTS
+		}
+		return java.Compare(a.version, b.version) > 0
+	})
+
+	items := make([]tui.Item, len(releases))
+	for i, r := range releases {
+		group := r.info.VendorName()
+		if r.info.LTS {
+			group += " (LTS)"
+		}
+		label := fmt.Sprintf("%-4s latest %-16s", r.version, r.info.Latest)
+		if r.info.IsEOL(time.Now()) {
+			label += fmt.Sprintf(" end of life since %s", r.info.EOL)
+		} else if r.info.EOL != "" {
+			label += fmt.Sprintf(" supported until %s", r.info.EOL)
+		}
+		if _, ok := java.Find(env.root, java.Query{Version: r.info.Latest, Vendor: r.info.VendorName()}); ok {
+			label += "  [installed]"
+		}
+		items[i] = tui.Item{Label: label, Group: group}
+	}
+	i, err := tui.Pick("Choose the Java version to install", items)
+	if err != nil {
+		return "", "", pickFailed(err)
+	}
+	return releases[i].version, releases[i].info.VendorName(), nil
+}
+
+func pickFailed(err error) error {
+	if errors.Is(err, tui.ErrCancelled) {
+		return errors.New("no version chosen")
+	}
+	return fmt.Errorf("could not show the version list: %v", err)
+}
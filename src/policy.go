@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jdkvm/java"
+	"jdkvm/policy"
+	"jdkvm/web"
+)
+
+var (
+	loadedPolicy *policy.Policy
+	policyLoaded bool
+)
+
+// policyCommand shows or audits against the organization policy:
+//
+//	jdkvm policy check
+//	jdkvm policy show
+func policyCommand(args []string) error {
+	action := ""
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "check":
+		p, err := requirePolicy()
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			fmt.Println("No policy is configured. Set one with: jdkvm config set policy <path or URL>")
+			return nil
+		}
+		installed := java.GetInstallations(env.root)
+		violating := 0
+		for _, inst := range installed {
+			violations := p.Check(inst.Vendor, inst.Version)
+			if len(violations) == 0 {
+				fmt.Printf("  ok      %s (%s, %s)\n", inst.Version, inst.Vendor, inst.Arch)
+				continue
+			}
+			violating++
+			fmt.Printf("  denied  %s (%s, %s)\n", inst.Version, inst.Vendor, inst.Arch)
+			for _, v := range violations {
+				fmt.Printf("          %s\n", v)
+			}
+		}
+		if violating > 0 {
+			return fmt.Errorf("%d of %d installs violate the policy. Upgrade or uninstall them", violating, len(installed))
+		}
+		fmt.Printf("\nAll %d installs comply with the policy.\n", len(installed))
+	case "show":
+		location := env.config.Get("policy")
+		if location == "" {
+			fmt.Println("No policy is configured.")
+			return nil
+		}
+		p, err := requirePolicy()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Policy: %s\n", location)
+		if len(p.AllowedVendors) > 0 {
+			fmt.Printf("  allowed vendors: %s\n", strings.Join(p.AllowedVendors, ", "))
+		}
+		for major, min := range p.MinPatch {
+			fmt.Printf("  Java %s: at least %s\n", major, min)
+		}
+		for _, ban := range p.Banned {
+			fmt.Printf("  banned %s: %s\n", ban.Version, ban.Reason)
+		}
+	default:
+		return unknownAction(action)
+	}
+	return nil
+}
+
+// requirePolicy returns the configured policy, or nil when there is none.
+// A policy that is configured but cannot be read is an error rather than
+// allowing everything.
+func requirePolicy() (*policy.Policy, error) {
+	if policyLoaded {
+		return loadedPolicy, nil
+	}
+	p, err := loadPolicy(env.config.Get("policy"))
+	if err != nil {
+		return nil, fmt.Errorf("could not load the policy: %w", err)
+	}
+	loadedPolicy, policyLoaded = p, true
+	return p, nil
+}
+
+// loadPolicy reads a policy file or downloads it. A downloaded policy is
+// cached, so installs keep being checked while offline.
+func loadPolicy(location string) (*policy.Policy, error) {
+	if location == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		content, err := os.ReadFile(location)
+		if err != nil {
+			return nil, err
+		}
+		return policy.Parse(content)
+	}
+
+	cacheFile := filepath.Join(env.cacheDir, "policy.toml")
+	content, err := web.GetRemoteTextFile(location)
+	if err == nil {
+		p, parseErr := policy.Parse([]byte(content))
+		if parseErr != nil {
+			return nil, fmt.Errorf("%s: %v", location, parseErr)
+		}
+		os.WriteFile(cacheFile, []byte(content), 0644)
+		return p, nil
+	}
+	cached, cacheErr := os.ReadFile(cacheFile)
+	if cacheErr != nil {
+		return nil, err
+	}
+	fmt.Printf("Warning: Could not download the policy (%v); using the cached copy.\n", err)
+	return policy.Parse(cached)
+}
+
+// policyDenial explains why the policy forbids a release, or returns "" when
+// it is allowed
+func policyDenial(vendor string, version string) (string, error) {
+	p, err := requirePolicy()
+	if err != nil {
+		return "", err
+	}
+	violations := p.Check(vendor, version)
+	if len(violations) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("Java %s (%s) is not allowed by the policy:\n  %s", version, vendor, strings.Join(violations, "\n  ")), nil
+}
+
+// enforcePolicy returns an error when the policy forbids a release
+func enforcePolicy(vendor string, version string) error {
+	denial, err := policyDenial(vendor, version)
+	if err == nil && denial != "" {
+		err = errors.New(denial)
+	}
+	return err
+}
+
+// enforceCatalogPolicy returns an error when the catalog release a version
+// and vendor resolve to is forbidden. Versions missing from the catalog are
+// left to the installer to report.
+func enforceCatalogPolicy(version string, vendor string) error {
+	if info, ok := web.FindRelease(version, vendor); ok {
+		return enforcePolicy(info.VendorName(), info.Latest)
+	}
+	return nil
+}
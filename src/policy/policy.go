@@ -0,0 +1,118 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"jdkvm/java"
+)
+
+// Policy restricts which Java releases may be installed and used. A zero
+// Policy allows everything.
+//
+//	allowed_vendors = ["temurin"]
+//
+//	[min_patch]
+//	17 = "17.0.10"
+//
+//	[[banned]]
+//	version = "17.0.9+9"
+//	reason = "CVE-2023-22081"
+type Policy struct {
+	// AllowedVendors lists the vendors that may be installed; empty allows all
+	AllowedVendors []string `toml:"allowed_vendors"`
+	// MinPatch is the oldest release allowed per feature release
+	MinPatch map[string]string `toml:"min_patch"`
+	Banned   []Ban             `toml:"banned"`
+}
+
+// Ban forbids a release, or every release of a feature release when Version
+// is just the feature release
+type Ban struct {
+	Version string `toml:"version"`
+	Reason  string `toml:"reason"`
+}
+
+// Parse decodes and checks a policy file. Unknown keys are errors, since a
+// misspelt rule would otherwise silently allow everything.
+func Parse(content []byte) (*Policy, error) {
+	var p Policy
+	meta, err := toml.Decode(string(content), &p)
+	if err != nil {
+		return nil, err
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, k := range undecoded {
+			keys[i] = k.String()
+		}
+		return nil, fmt.Errorf("unknown policy keys: %s", strings.Join(keys, ", "))
+	}
+
+	for major, min := range p.MinPatch {
+		if major == "" || java.Major(major) != major {
+			return nil, fmt.Errorf("min_patch: %q is not a feature release like \"17\"", major)
+		}
+		if java.Major(min) != major {
+			return nil, fmt.Errorf("min_patch.%s: %q is not a release of Java %s", major, min, major)
+		}
+	}
+	for i, ban := range p.Banned {
+		if ban.Version == "" {
+			return nil, fmt.Errorf("banned[%d]: version is required", i)
+		}
+	}
+	return &p, nil
+}
+
+// Check returns every rule a release breaks, or nothing when it is allowed
+func (p *Policy) Check(vendor string, version string) []string {
+	if p == nil {
+		return nil
+	}
+	var violations []string
+
+	if len(p.AllowedVendors) > 0 && !p.allowsVendor(vendor) {
+		violations = append(violations, fmt.Sprintf("vendor %s is not allowed (allowed: %s)", vendor, strings.Join(p.AllowedVendors, ", ")))
+	}
+	if min, ok := p.MinPatch[java.Major(version)]; ok && java.Compare(version, min) < 0 {
+		violations = append(violations, fmt.Sprintf("%s is older than the minimum %s for Java %s", version, min, java.Major(version)))
+	}
+	for _, ban := range p.Banned {
+		if !matchesBan(version, ban.Version) {
+			continue
+		}
+		reason := ban.Reason
+		if reason == "" {
+			reason = "no reason given"
+		}
+		violations = append(violations, fmt.Sprintf("%s is banned: %s", ban.Version, reason))
+	}
+	return violations
+}
+
+func (p *Policy) allowsVendor(vendor string) bool {
+	for _, v := range p.AllowedVendors {
+		if strings.EqualFold(v, vendor) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesBan reports whether version is the banned version, or starts with
+// it followed by a separator: "17.0.9" bans "17.0.9+9", "17" bans all of 17
+func matchesBan(version string, banned string) bool {
+	version = strings.TrimPrefix(version, "v")
+	banned = strings.TrimPrefix(banned, "v")
+	if version == banned {
+		return true
+	}
+	if !strings.HasPrefix(version, banned) {
+		return false
+	}
+	next := version[len(banned)]
+	return next < '0' || next > '9'
+}
@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchesBan(t *testing.T) {
+	tests := []struct {
+		version string
+		banned  string
+		want    bool
+	}{
+		{"17.0.9+9", "17.0.9+9", true},
+		{"17.0.9+9", "17.0.9", true},
+		{"17.0.9+9", "17", true},
+		{"v17.0.9+9", "17.0.9", true},
+		{"17.0.9+9", "v17.0.9", true},
+		{"17.0.10+7", "17.0.1", false},
+		{"17.0.9+9", "17.0.9+1", false},
+		{"170.0.1", "17", false},
+		{"21.0.3+9", "17", false},
+		{"8u412-b08", "8u412", true},
+		{"8u412-b08", "8", true},
+		{"8u41", "8u412", false},
+	}
+	for _, tt := range tests {
+		if got := matchesBan(tt.version, tt.banned); got != tt.want {
+			t.Errorf("matchesBan(%q, %q) = %v, want %v", tt.version, tt.banned, got, tt.want)
+		}
+	}
+}
+
+const testPolicy = `
+allowed_vendors = ["temurin", "Zulu"]
+
+[min_patch]
+17 = "17.0.10"
+21 = "21.0.2"
+
+[[banned]]
+version = "17.0.11+9"
+reason = "CVE-2024-0001"
+
+[[banned]]
+version = "11"
+`
+
+func TestCheck(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		vendor  string
+		version string
+		want    []string
+	}{
+		{"temurin", "17.0.10+7", nil},
+		{"zulu", "21.0.3+9", nil},
+		{"corretto", "17.0.10+7", []string{"vendor corretto is not allowed"}},
+		{"temurin", "17.0.9+9", []string{"older than the minimum 17.0.10"}},
+		{"temurin", "17.0.11+9", []string{"17.0.11+9 is banned: CVE-2024-0001"}},
+		{"temurin", "11.0.23+9", []string{"11 is banned: no reason given"}},
+		{"oracle", "21.0.1+12", []string{"vendor oracle", "older than the minimum 21.0.2"}},
+		{"temurin", "8u412-b08", nil},
+	}
+	for _, tt := range tests {
+		got := p.Check(tt.vendor, tt.version)
+		if len(got) != len(tt.want) {
+			t.Errorf("Check(%s, %s) = %q, want %d violations", tt.vendor, tt.version, got, len(tt.want))
+			continue
+		}
+		for i, want := range tt.want {
+			if !strings.Contains(got[i], want) {
+				t.Errorf("Check(%s, %s)[%d] = %q, want it to mention %q", tt.vendor, tt.version, i, got[i], want)
+			}
+		}
+	}
+
+	var none *Policy
+	if got := none.Check("anyone", "1.0"); got != nil {
+		t.Errorf("nil policy Check = %q, want nothing", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"valid", testPolicy, ""},
+		{"empty", "", ""},
+		{"misspelt key", `allowed_vendor = ["temurin"]`, "unknown policy keys: allowed_vendor"},
+		{"misspelt ban key", "[[banned]]\nversion = \"17\"\nreasn = \"x\"", "unknown policy keys"},
+		{"min patch key", "[min_patch]\n\"17.0\" = \"17.0.10\"", "is not a feature release"},
+		{"min patch value", "[min_patch]\n17 = \"21.0.2\"", "is not a release of Java 17"},
+		{"ban without version", "[[banned]]\nreason = \"x\"", "version is required"},
+		{"not toml", "allowed_vendors = [", "expected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.content))
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Parse error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"jdkvm/cli"
+	"jdkvm/utility"
+	"jdkvm/web"
+)
+
+// proxyCredentials are kept out of the configuration file: the password is encrypted
+// for the current user on Windows and the file is readable only by its owner
+type proxyCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func credentialsFile() string {
+	return filepath.Join(env.configDir, "proxy-credentials.json")
+}
+
+// proxy shows, changes and tests the proxy settings:
+//
+//	jdkvm proxy [<url>|none|direct]
+//	jdkvm proxy test <url>
+//	jdkvm proxy login <user>
+//	jdkvm proxy logout
+func proxy(args []string) error {
+	if len(args) == 0 {
+		showProxy()
+		return nil
+	}
+
+	switch args[0] {
+	case "test":
+		if len(args) < 2 {
+			return cli.Usage("proxy test needs a URL")
+		}
+		return testProxy(args[1])
+	case "login":
+		if len(args) < 2 {
+			return cli.Usage("proxy login needs a user name")
+		}
+		return proxyLogin(args[1])
+	case "logout":
+		if err := os.Remove(credentialsFile()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove proxy credentials: %w", err)
+		}
+		fmt.Println("Proxy credentials removed.")
+	default:
+		return setProxy(args[0])
+	}
+	return nil
+}
+
+func showProxy() {
+	fmt.Printf("Current proxy: %s\n", env.proxy)
+	if env.proxyPAC != "" {
+		fmt.Printf("PAC file: %s\n", env.proxyPAC)
+	}
+	if env.noProxy != "" {
+		fmt.Printf("No proxy for: %s\n", env.noProxy)
+	}
+	for _, name := range []string{"HTTPS_PROXY", "HTTP_PROXY", "ALL_PROXY", "NO_PROXY"} {
+		value := os.Getenv(name)
+		if value == "" {
+			value = os.Getenv(strings.ToLower(name))
+		}
+		if value != "" {
+			fmt.Printf("%s: %s\n", name, redactProxy(value))
+		}
+	}
+	if creds, err := loadProxyCredentials(); err == nil && creds.Username != "" {
+		fmt.Printf("Proxy user: %s\n", creds.Username)
+	}
+	if env.proxy != "none" {
+		fmt.Println("To remove proxy, use: jdkvm proxy none")
+	}
+}
+
+func setProxy(proxyUrl string) error {
+	// Credentials typed into the URL would end up in the plaintext configuration file
+	if strings.Contains(proxyUrl, "@") {
+		return cli.Usage("do not put credentials in the proxy URL; use: jdkvm proxy login <user>")
+	}
+
+	previous := env.proxy
+	env.proxy = proxyUrl
+	if err := web.SetProxy(proxySettings()); err != nil {
+		env.proxy = previous
+		return cli.Usage("%v", err)
+	}
+
+	// Save to configuration file
+	saveSetting("proxy", proxyUrl)
+
+	switch proxyUrl {
+	case "none":
+		fmt.Println("Proxy removed.")
+	case web.ProxyDirect:
+		fmt.Println("All proxies bypassed, including HTTP_PROXY and PAC files.")
+	default:
+		fmt.Printf("Proxy set to: %s\n", proxyUrl)
+	}
+	return nil
+}
+
+// testProxy shows which proxy a URL would use and tries to reach it
+func testProxy(target string) error {
+	request, err := http.NewRequest(http.MethodHead, target, nil)
+	if err != nil || (request.URL.Scheme != "http" && request.URL.Scheme != "https") || request.URL.Host == "" {
+		return cli.Usage("proxy test needs an http or https URL, got %q", target)
+	}
+	choice, err := web.ProxyFor(target)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Proxy for %s: %s\n", target, choice)
+
+	start := time.Now()
+	response, err := web.Do(request)
+	if err != nil {
+		return cli.Exit(cli.ExitNetwork, "request failed: %v", err)
+	}
+	response.Body.Close()
+	fmt.Printf("Response: %s in %v\n", response.Status, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// proxyLogin stores proxy credentials. The password is read from
+// JDKVM_PROXY_PASSWORD or, without it, prompted for without echo.
+func proxyLogin(username string) error {
+	password := os.Getenv("JDKVM_PROXY_PASSWORD")
+	if password == "" {
+		fmt.Print("Proxy password: ")
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			input, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Println()
+			if err != nil {
+				return fmt.Errorf("could not read password: %w", err)
+			}
+			password = string(input)
+		} else {
+			line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			password = strings.TrimRight(line, "\r\n")
+		}
+	}
+
+	if err := saveProxyCredentials(proxyCredentials{Username: username, Password: password}); err != nil {
+		return fmt.Errorf("could not save proxy credentials: %w", err)
+	}
+	fmt.Printf("Proxy credentials saved for %s.\n", username)
+	return nil
+}
+
+// proxySettings collects the proxy configuration from the config files and the credential store
+func proxySettings() web.ProxyConfig {
+	cfg := web.ProxyConfig{URL: env.proxy, NoProxy: env.noProxy, PAC: env.proxyPAC}
+	creds, err := loadProxyCredentials()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			warnf("Ignoring proxy credentials: %v", err)
+		}
+		return cfg
+	}
+	cfg.Username, cfg.Password = creds.Username, creds.Password
+	return cfg
+}
+
+func loadProxyCredentials() (proxyCredentials, error) {
+	creds := proxyCredentials{}
+	content, err := os.ReadFile(credentialsFile())
+	if err != nil {
+		return creds, err
+	}
+	if err := json.Unmarshal(content, &creds); err != nil {
+		return creds, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(creds.Password)
+	if err != nil {
+		return creds, err
+	}
+	password, err := utility.UnprotectSecret(sealed)
+	if err != nil {
+		return creds, err
+	}
+	creds.Password = string(password)
+	return creds, nil
+}
+
+func saveProxyCredentials(creds proxyCredentials) error {
+	sealed, err := utility.ProtectSecret([]byte(creds.Password))
+	if err != nil {
+		return err
+	}
+	creds.Password = base64.StdEncoding.EncodeToString(sealed)
+	content, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+	os.MkdirAll(env.configDir, os.ModePerm)
+	if err := os.WriteFile(credentialsFile(), content, 0600); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(credentialsFile(), 0600)
+}
+
+// redactProxy hides the password of a proxy URL from the environment
+func redactProxy(p string) string {
+	if u, err := web.ParseProxyURL(p); err == nil {
+		return u.Redacted()
+	}
+	return p
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package tui
+
+import "os"
+
+// enableVT does nothing: terminals outside Windows process escape sequences
+func enableVT(f *os.File) func() {
+	return func() {}
+}
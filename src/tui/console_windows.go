@@ -0,0 +1,21 @@
+//go:build windows
+
+package tui
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// enableVT turns on escape sequence processing for a console, which older
+// Windows consoles leave off, and returns a function that restores the mode
+func enableVT(f *os.File) func() {
+	h := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(h, &mode); err != nil {
+		return func() {}
+	}
+	windows.SetConsoleMode(h, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+	return func() { windows.SetConsoleMode(h, mode) }
+}
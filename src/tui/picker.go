@@ -0,0 +1,224 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// ErrCancelled is returned when the user leaves the picker without choosing
+var ErrCancelled = errors.New("cancelled")
+
+// maxRows is the most lines the list takes up, headings included
+const maxRows = 15
+
+// Item is one entry of a picker
+type Item struct {
+	Label string
+	// Group is the heading the item is listed under; items of a group must
+	// be next to each other
+	Group string
+}
+
+// Interactive reports whether the user can answer a picker: stdin and
+// stderr, where the picker is drawn, are both terminals
+func Interactive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// Pick shows items on stderr and returns the index of the one chosen. Typing
+// filters the list by fuzzy match, the arrow keys move the selection, Enter
+// chooses and Esc or Ctrl+C cancels.
+func Pick(title string, items []Item) (int, error) {
+	in := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return -1, err
+	}
+	defer term.Restore(in, state)
+	defer enableVT(os.Stderr)()
+
+	p := &picker{title: title, items: items, out: os.Stderr}
+	p.filter()
+	fmt.Fprint(p.out, "\x1b[?25l") // hide the cursor
+	defer fmt.Fprint(p.out, "\x1b[?25h")
+
+	buf := make([]byte, 64)
+	for {
+		p.render()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			p.clear()
+			return -1, err
+		}
+		switch key := buf[:n]; {
+		case string(key) == "\r" || string(key) == "\n":
+			if len(p.matches) == 0 {
+				continue
+			}
+			p.clear()
+			return p.matches[p.cursor], nil
+		case string(key) == "\x1b" || key[0] == 3 || key[0] == 4: // Esc, Ctrl+C, Ctrl+D
+			p.clear()
+			return -1, ErrCancelled
+		case string(key) == "\x1b[A" || string(key) == "\x1bOA" || key[0] == 16: // Up, Ctrl+P
+			p.move(-1)
+		case string(key) == "\x1b[B" || string(key) == "\x1bOB" || key[0] == 14: // Down, Ctrl+N
+			p.move(1)
+		case string(key) == "\x1b[5~": // Page Up
+			p.move(-maxRows)
+		case string(key) == "\x1b[6~": // Page Down
+			p.move(maxRows)
+		case key[0] == 127 || key[0] == 8: // Backspace
+			if p.query != "" {
+				_, size := utf8.DecodeLastRuneInString(p.query)
+				p.query = p.query[:len(p.query)-size]
+				p.filter()
+			}
+		case key[0] == 21: // Ctrl+U
+			p.query = ""
+			p.filter()
+		case key[0] != 0x1b:
+			for _, r := range string(key) {
+				if unicode.IsPrint(r) {
+					p.query += string(r)
+				}
+			}
+			p.filter()
+		}
+	}
+}
+
+type picker struct {
+	title   string
+	items   []Item
+	out     io.Writer
+	query   string
+	matches []int
+	cursor  int
+	// drawn is the number of lines the last render left below the first one
+	drawn int
+}
+
+// filter keeps the items matching the query, in their original order
+func (p *picker) filter() {
+	p.matches = p.matches[:0]
+	for i, item := range p.items {
+		if fuzzyMatch(item.Group+" "+item.Label, p.query) {
+			p.matches = append(p.matches, i)
+		}
+	}
+	p.cursor = 0
+}
+
+func (p *picker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.cursor = max(0, min(len(p.matches)-1, p.cursor+delta))
+}
+
+// render redraws the picker in place
+func (p *picker) render() {
+	width := 80
+	if w, _, err := term.GetSize(int(os.Stderr.Fd())); err == nil && w > 0 {
+		width = w
+	}
+
+	// Headings become rows of their own; the window follows the selection
+	type row struct {
+		text     string
+		selected bool
+	}
+	var rows []row
+	selectedRow, group := 0, ""
+	for i, index := range p.matches {
+		item := p.items[index]
+		if item.Group != "" && (i == 0 || item.Group != group) {
+			rows = append(rows, row{text: item.Group})
+		}
+		group = item.Group
+		if i == p.cursor {
+			selectedRow = len(rows)
+		}
+		rows = append(rows, row{text: "  " + item.Label, selected: i == p.cursor})
+	}
+	start := 0
+	if selectedRow >= maxRows {
+		start = selectedRow - maxRows + 1
+	}
+	end := min(len(rows), start+maxRows)
+
+	var b strings.Builder
+	p.moveToTop(&b)
+	fmt.Fprintf(&b, "\x1b[J%s\r\n", truncate(p.title+" (type to filter, Esc to cancel)", width))
+	fmt.Fprintf(&b, "> %s", p.query)
+	lines := 1
+	if len(rows) == 0 {
+		b.WriteString("\r\n  no matches")
+		lines++
+	}
+	for _, r := range rows[start:end] {
+		b.WriteString("\r\n")
+		text := truncate(r.text, width-1)
+		if r.selected {
+			fmt.Fprintf(&b, "\x1b[7m%s\x1b[0m", text)
+		} else {
+			b.WriteString(text)
+		}
+		lines++
+	}
+	if end-start < len(rows) {
+		fmt.Fprintf(&b, "\r\n  (%d of %d shown)", end-start, len(rows))
+		lines++
+	}
+	p.drawn = lines
+	fmt.Fprint(p.out, b.String())
+}
+
+// moveToTop moves the cursor to the picker's first line
+func (p *picker) moveToTop(b *strings.Builder) {
+	if p.drawn > 0 {
+		fmt.Fprintf(b, "\x1b[%dA", p.drawn)
+	}
+	b.WriteString("\r")
+}
+
+// clear erases the picker
+func (p *picker) clear() {
+	var b strings.Builder
+	p.moveToTop(&b)
+	b.WriteString("\x1b[J")
+	fmt.Fprint(p.out, b.String())
+	p.drawn = 0
+}
+
+// fuzzyMatch reports whether every word of query matches text, ignoring
+// case: its characters appear in text in order, not necessarily together
+func fuzzyMatch(text string, query string) bool {
+	text = strings.ToLower(text)
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		rest := text
+		for _, r := range word {
+			i := strings.IndexRune(rest, r)
+			if i < 0 {
+				return false
+			}
+			rest = rest[i+utf8.RuneLen(r):]
+		}
+	}
+	return true
+}
+
+func truncate(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width])
+}
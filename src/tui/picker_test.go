@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		text  string
+		query string
+		want  bool
+	}{
+		{"temurin 17.0.11+9 (x64, jdk)", "", true},
+		{"temurin 17.0.11+9 (x64, jdk)", "17", true},
+		{"temurin 17.0.11+9 (x64, jdk)", "1711", true},
+		{"temurin 17.0.11+9 (x64, jdk)", "TEM", true},
+		{"temurin 17.0.11+9 (x64, jdk)", "tmrn", true},
+		{"temurin 17.0.11+9 (x64, jdk)", "jdk 17", true},
+		{"temurin 17.0.11+9 (x64, jdk)", "  jdk   x64 ", true},
+		{"temurin 17.0.11+9 (x64, jdk)", "jre", false},
+		{"temurin 17.0.11+9 (x64, jdk)", "71", true},
+		{"temurin 17.0.11+9 (x64, jdk)", "99", false},
+		{"temurin 17.0.11+9 (x64, jdk)", "17 aarch64", false},
+		{"Zulu 21 — Ünïcode", "ün", true},
+		{"Zulu 21 — Ünïcode", "—", true},
+		{"short", "shorter", false},
+	}
+	for _, tt := range tests {
+		if got := fuzzyMatch(tt.text, tt.query); got != tt.want {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tt.text, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPickerFilter(t *testing.T) {
+	items := []Item{
+		{Group: "Java 21", Label: "21.0.3+9 (x64, jdk)"},
+		{Group: "Java 17", Label: "17.0.11+9 (x64, jdk)"},
+		{Group: "Java 17", Label: "17.0.11+9 (aarch64, jre)"},
+		{Group: "Java 11", Label: "11.0.23+9 (x64, jdk)"},
+	}
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"", []int{0, 1, 2, 3}},
+		{"java 17", []int{1, 2}},
+		{"x64", []int{0, 1, 3}},
+		{"17 jre", []int{2}},
+		{"zulu", nil},
+	}
+	for _, tt := range tests {
+		p := &picker{items: items, query: tt.query, cursor: 2}
+		p.filter()
+		if !reflect.DeepEqual(p.matches, tt.want) {
+			t.Errorf("filter(%q) = %v, want %v", tt.query, p.matches, tt.want)
+		}
+		if p.cursor != 0 {
+			t.Errorf("filter(%q) left the cursor at %d", tt.query, p.cursor)
+		}
+	}
+}
+
+func TestPickerMove(t *testing.T) {
+	p := &picker{items: make([]Item, 5)}
+	p.filter()
+	tests := []struct {
+		delta int
+		want  int
+	}{
+		{1, 1},
+		{2, 3},
+		{maxRows, 4},
+		{-1, 3},
+		{-maxRows, 0},
+		{-1, 0},
+	}
+	for _, tt := range tests {
+		p.move(tt.delta)
+		if p.cursor != tt.want {
+			t.Errorf("move(%d): cursor = %d, want %d", tt.delta, p.cursor, tt.want)
+		}
+	}
+
+	empty := &picker{}
+	empty.filter()
+	empty.move(1)
+	if empty.cursor != 0 {
+		t.Errorf("move on an empty list: cursor = %d", empty.cursor)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"jdkvm/cli"
+	"jdkvm/java"
+	"jdkvm/utility"
+	"jdkvm/web"
+)
+
+// upgrade installs the newest patch release of installed feature releases.
+// With a version only that feature release is upgraded; --all upgrades every
+// install. Whether JAVA_HOME follows the upgrade and whether the superseded
+// install is removed come from the configuration and can be overridden per run.
+func upgrade(version string, cpuarch string, image string, opts upgradeOptions) error {
+	if version == "" && !opts.all {
+		return cli.Usage("please specify a version to upgrade, or --all to upgrade every installed version")
+	}
+
+	removeOld := (env.upgradeRemoveOld || opts.removeOld) && !opts.keepOld
+	moveDefault := (env.upgradeMoveDefault || opts.moveDefault) && !opts.keepDefault
+
+	installed := java.GetInstallations(env.root)
+	if len(installed) == 0 {
+		fmt.Println("No installations recognized.")
+		return nil
+	}
+
+	superseded := outdatedInstalls(installed, version, cpuarch, image)
+	if removeOld && len(superseded) > 0 && !confirm(fmt.Sprintf("Remove the %d superseded install(s) after upgrading?", len(superseded))) {
+		removeOld = false
+	}
+
+	defaultHome, _ := utility.GetEnvironmentVariable("JAVA_HOME")
+	upgraded := 0
+	var lastErr error
+	failures := 0
+	for _, old := range superseded {
+		major := java.Major(old.Version)
+		info, _ := web.FindRelease(major, old.Vendor)
+		latest := info.Latest
+		denial, err := policyDenial(old.Vendor, latest)
+		if err != nil {
+			return err
+		}
+		if denial != "" {
+			fmt.Println(denial)
+			continue
+		}
+		fmt.Printf("Upgrading Java %s (%s, %s) to %s...\n", old.Version, old.ImageLabel(), old.Arch, latest)
+
+		osName := old.OS
+		if osName == "" {
+			osName = web.HostOS()
+		}
+		if err := web.GetJava(env.root, major, old.Vendor, old.Arch, osName, old.Image); err != nil {
+			fmt.Printf("Failed to upgrade Java %s (%s): %v\n", old.Version, old.Arch, err)
+			lastErr = err
+			failures++
+			continue
+		}
+		// Carry add-on images over to the new JDK
+		for _, extra := range old.Extras {
+			if err := web.GetJava(env.root, major, old.Vendor, old.Arch, osName, extra); err != nil {
+				fmt.Printf("Warning: Could not add the %s image to Java %s: %v\n", extra, latest, err)
+			}
+		}
+		upgraded++
+
+		if moveDefault && defaultHome != "" && filepath.Clean(defaultHome) == filepath.Clean(old.Dir) {
+			fmt.Printf("JAVA_HOME pointed at Java %s; switching it to %s.\n", old.Version, latest)
+			if err := use(latest, java.Query{Vendor: old.Vendor, Arch: old.Arch, Image: old.Image}); err != nil {
+				fmt.Printf("Warning: Could not switch to Java %s: %v\n", latest, err)
+			}
+		}
+
+		if removeOld {
+			removeSuperseded(old)
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d Java version(s) could not be upgraded; the last error: %w", failures, lastErr)
+	}
+	if upgraded == 0 {
+		fmt.Println("All selected Java versions are up to date.")
+	}
+	return nil
+}
+
+// removeSuperseded removes an install replaced by an upgrade, unless it is
+// still the active Java because JAVA_HOME was not moved to the new one
+func removeSuperseded(old java.Installation) {
+	if inUse(old) {
+		fmt.Printf("Keeping Java %s (%s): it is still the active Java. Run \"jdkvm use\" and uninstall it afterwards.\n", old.Version, old.Arch)
+		return
+	}
+	if err := os.RemoveAll(old.Dir); err != nil {
+		fmt.Printf("Warning: Could not remove Java %s: %v\n", old.Version, err)
+	} else {
+		fmt.Printf("Removed superseded Java %s (%s).\n", old.Version, old.Arch)
+	}
+}
+
+// inUse reports whether JAVA_HOME, for this process or new shells, or the
+// java on PATH points at an install
+func inUse(inst java.Installation) bool {
+	dir, err := os.Stat(inst.Dir)
+	if err != nil {
+		return false
+	}
+	for _, get := range []func(string) (string, error){utility.GetEnvironmentVariable, utility.GetPersistedEnvironmentVariable} {
+		home, err := get("JAVA_HOME")
+		if err != nil || home == "" {
+			continue
+		}
+		if info, err := os.Stat(home); err == nil && os.SameFile(info, dir) {
+			return true
+		}
+	}
+	current, ok := java.Current(env.root)
+	return ok && filepath.Clean(current.Dir) == filepath.Clean(inst.Dir)
+}
+
+// upgradeOptions are the upgrade flags that override the configuration
+type upgradeOptions struct {
+	all         bool
+	removeOld   bool
+	keepOld     bool
+	moveDefault bool
+	keepDefault bool
+}
+
+// outdatedInstalls returns the installs matching the filters whose feature
+// release has a newer patch in the catalog that is not installed yet
+func outdatedInstalls(installed []java.Installation, version string, cpuarch string, image string) []java.Installation {
+	list := make([]java.Installation, 0)
+	for _, inst := range installed {
+		if version != "" && !java.Matches(inst.Version, version) {
+			continue
+		}
+		if cpuarch != "" && inst.Arch != cpuarch {
+			continue
+		}
+		if image != "" && inst.Image != image {
+			continue
+		}
+
+		info, ok := web.FindRelease(java.Major(inst.Version), inst.Vendor)
+		if !ok || java.Compare(inst.Version, info.Latest) >= 0 {
+			continue
+		}
+		if _, ok := java.Find(env.root, java.Query{Version: info.Latest, Vendor: inst.Vendor, Arch: inst.Arch, Image: inst.Image}); ok {
+			continue
+		}
+		list = append(list, inst)
+	}
+	return list
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jdkvm/arch"
+	"jdkvm/config"
+	"jdkvm/java"
+	"jdkvm/web"
+)
+
+// useTestEnv points the environment at an empty install root, with no
+// configuration files
+func useTestEnv(t *testing.T) string {
+	t.Helper()
+	saved := *env
+	t.Cleanup(func() { *env = saved })
+	root := t.TempDir()
+	env.root, env.configDir, env.cacheDir = root, t.TempDir(), t.TempDir()
+	env.config = config.Load(config.Sources{})
+	env.offline = true
+	return root
+}
+
+// useTestCatalog replaces the catalog with the given feature releases
+func useTestCatalog(t *testing.T, versions map[string]web.FeatureRelease) {
+	t.Helper()
+	saved, savedMapping := web.CurrentCatalog, web.JavaVersionMapping
+	t.Cleanup(func() { web.CurrentCatalog, web.JavaVersionMapping = saved, savedMapping })
+	web.CurrentCatalog = web.Catalog{SchemaVersion: web.CatalogSchemaVersion, Versions: versions}
+	web.JavaVersionMapping = web.CurrentCatalog.Mapping()
+}
+
+// releases lists versions of a feature release, newest first
+func releases(versions ...string) []web.Release {
+	list := make([]web.Release, 0, len(versions))
+	for _, v := range versions {
+		list = append(list, web.Release{Version: v})
+	}
+	return list
+}
+
+// writeInstall creates an install with a launcher and metadata under root
+func writeInstall(t *testing.T, root string, inst java.Installation) java.Installation {
+	t.Helper()
+	if inst.Image == "" {
+		inst.Image = java.ImageJDK
+	}
+	inst.Dir = filepath.Join(root, java.DirName(inst.Version, inst.Vendor, inst.Arch, inst.Image))
+	if err := os.MkdirAll(filepath.Join(inst.Dir, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(java.Executable(inst.Dir), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := java.WriteInstallation(inst); err != nil {
+		t.Fatal(err)
+	}
+	return inst
+}
+
+func TestOutdatedInstalls(t *testing.T) {
+	root := useTestEnv(t)
+	useTestCatalog(t, map[string]web.FeatureRelease{
+		"17": {Vendor: "temurin", LTS: true, Releases: append(releases("17.0.12+7", "17.0.11+9"),
+			web.Release{Version: "17.0.11+10", Vendor: "zulu"})},
+		"21": {Vendor: "temurin", LTS: true, Releases: releases("21.0.3+9")},
+	})
+	host := arch.Host()
+	other := arch.X86
+	if host == other {
+		other = arch.X64
+	}
+	for _, inst := range []java.Installation{
+		{Version: "17.0.9+9", Vendor: "temurin", Arch: host},
+		{Version: "17.0.9+9", Vendor: "temurin", Arch: other},
+		{Version: "17.0.9+9", Vendor: "temurin", Arch: host, Image: java.ImageJRE},
+		{Version: "17.0.12+7", Vendor: "temurin", Arch: host, Image: java.ImageJRE},
+		{Version: "17.0.10+7", Vendor: "zulu", Arch: host},
+		{Version: "21.0.3+9", Vendor: "temurin", Arch: host},
+		{Version: "11.0.22+7", Vendor: "temurin", Arch: host},
+	} {
+		writeInstall(t, root, inst)
+	}
+	installed := java.GetInstallations(root)
+
+	type build struct{ version, vendor, arch, image string }
+	tests := []struct {
+		name    string
+		version string
+		cpuarch string
+		image   string
+		want    []build
+	}{
+		{"every install", "", "", "", []build{
+			{"17.0.10+7", "zulu", host, java.ImageJDK},
+			{"17.0.9+9", "temurin", host, java.ImageJDK},
+			{"17.0.9+9", "temurin", other, java.ImageJDK},
+		}},
+		{"feature release", "17", host, java.ImageJDK, []build{
+			{"17.0.10+7", "zulu", host, java.ImageJDK},
+			{"17.0.9+9", "temurin", host, java.ImageJDK},
+		}},
+		{"latest installed", "21", "", "", []build{}},
+		{"image with the latest installed", "17", "", java.ImageJRE, []build{}},
+		{"not in the catalog", "11", "", "", []build{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := outdatedInstalls(installed, tt.version, tt.cpuarch, tt.image)
+			if len(got) != len(tt.want) {
+				t.Fatalf("outdatedInstalls = %v, want %v", got, tt.want)
+			}
+			for i, inst := range got {
+				if b := (build{inst.Version, inst.Vendor, inst.Arch, inst.Image}); b != tt.want[i] {
+					t.Errorf("outdatedInstalls[%d] = %v, want %v", i, b, tt.want[i])
+				}
+			}
+		})
+	}
+
+	// The upgrade target stays in the install's feature line and vendor
+	for _, inst := range outdatedInstalls(installed, "", "", "") {
+		info, _ := web.FindRelease(java.Major(inst.Version), inst.Vendor)
+		want := map[string]string{"temurin": "17.0.12+7", "zulu": "17.0.11+10"}[inst.Vendor]
+		if info.Latest != want {
+			t.Errorf("upgrade target of %s (%s) = %s, want %s", inst.Version, inst.Vendor, info.Latest, want)
+		}
+	}
+}
+
+func TestRemoveSuperseded(t *testing.T) {
+	tests := []struct {
+		name       string
+		javaHome   string
+		pathToOld  bool
+		wantRemove bool
+	}{
+		{"not in use", "", false, true},
+		{"JAVA_HOME moved to the new install", "new", false, true},
+		{"JAVA_HOME still points at it", "old", false, false},
+		{"java on PATH is still the old one", "new", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := useTestEnv(t)
+			old := writeInstall(t, root, java.Installation{Version: "17.0.9+9", Vendor: "temurin", Arch: arch.Host()})
+			updated := writeInstall(t, root, java.Installation{Version: "17.0.12+7", Vendor: "temurin", Arch: arch.Host()})
+
+			homes := map[string]string{"": "", "old": old.Dir, "new": updated.Dir}
+			t.Setenv("JAVA_HOME", homes[tt.javaHome])
+			path := t.TempDir()
+			if tt.pathToOld {
+				path = filepath.Join(old.Dir, "bin")
+			}
+			t.Setenv("PATH", path)
+
+			removeSuperseded(old)
+			_, err := os.Stat(old.Dir)
+			if removed := os.IsNotExist(err); removed != tt.wantRemove {
+				t.Errorf("removed = %v, want %v", removed, tt.wantRemove)
+			}
+			if _, err := os.Stat(updated.Dir); err != nil {
+				t.Errorf("the new install was touched: %v", err)
+			}
+		})
+	}
+}
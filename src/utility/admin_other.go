@@ -0,0 +1,35 @@
+//go:build !windows
+
+package utility
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Check if the current process has admin privileges
+func IsAdmin() bool {
+	return os.Geteuid() == 0
+}
+
+// Check if the current process is elevated
+func IsElevated() bool {
+	return os.Geteuid() == 0
+}
+
+// Run a command with elevated privileges
+func RunElevated(name string, arg ...string) bool {
+	// First try to run normally
+	cmd := exec.Command(name, arg...)
+	err := cmd.Run()
+	if err == nil {
+		return true
+	}
+
+	// If that fails, retry through sudo
+	cmd = exec.Command("sudo", append([]string{name}, arg...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run() == nil
+}
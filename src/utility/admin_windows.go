@@ -38,12 +38,6 @@ func IsElevated() bool {
 	return token.IsElevated()
 }
 
-// Exists checks if a file or directory exists
-func Exists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
-}
-
 // Run a command with elevated privileges
 func RunElevated(name string, arg ...string) bool {
 	// First try to run normally
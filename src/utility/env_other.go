@@ -0,0 +1,73 @@
+//go:build !windows
+
+package utility
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrPersistUnsupported is returned when a variable can only be changed for the
+// current process; on Unix the user's shell profile owns the persistent value
+var ErrPersistUnsupported = errors.New("persistent environment variables are not supported on this platform; update your shell profile instead")
+
+// GetCurrentPath retrieves the current PATH environment variable
+func GetCurrentPath() string {
+	return os.Getenv("PATH")
+}
+
+// AddToPath adds a directory to the PATH of the current process
+func AddToPath(dir string) error {
+	currentPath := GetCurrentPath()
+	for _, path := range filepath.SplitList(currentPath) {
+		if strings.TrimSpace(path) == dir {
+			return nil // Already in PATH
+		}
+	}
+
+	if err := os.Setenv("PATH", currentPath+string(os.PathListSeparator)+dir); err != nil {
+		return err
+	}
+	return ErrPersistUnsupported
+}
+
+// SetEnvironmentVariable sets an environment variable for the current process
+func SetEnvironmentVariable(name, value string) error {
+	if err := os.Setenv(name, value); err != nil {
+		return err
+	}
+	return ErrPersistUnsupported
+}
+
+// GetEnvironmentVariable gets an environment variable
+func GetEnvironmentVariable(name string) (string, error) {
+	return os.Getenv(name), nil
+}
+
+// GetPersistedEnvironmentVariable is unsupported outside Windows, where the
+// persistent value lives in the user's shell profile
+func GetPersistedEnvironmentVariable(name string) (string, error) {
+	return "", ErrPersistUnsupported
+}
+
+// NotifyWindowsOfEnvironmentChange is a no-op outside Windows
+func NotifyWindowsOfEnvironmentChange() error {
+	return nil
+}
+
+// RemoveFromPath removes a directory from the PATH of the current process
+func RemoveFromPath(dir string) error {
+	newPaths := make([]string, 0)
+	for _, path := range filepath.SplitList(GetCurrentPath()) {
+		if strings.TrimSpace(path) != dir {
+			newPaths = append(newPaths, path)
+		}
+	}
+
+	if err := os.Setenv("PATH", strings.Join(newPaths, string(os.PathListSeparator))); err != nil {
+		return err
+	}
+	return ErrPersistUnsupported
+}
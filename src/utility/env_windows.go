@@ -112,6 +112,29 @@ func GetEnvironmentVariable(name string) (string, error) {
 	return regValue, regErr
 }
 
+// GetPersistedEnvironmentVariable reads the value new processes will get from
+// the registry, ignoring the current process, the user's value first
+func GetPersistedEnvironmentVariable(name string) (string, error) {
+	for _, location := range []struct {
+		root registry.Key
+		path string
+	}{
+		{registry.CURRENT_USER, "Environment"},
+		{registry.LOCAL_MACHINE, "SYSTEM\\CurrentControlSet\\Control\\Session Manager\\Environment"},
+	} {
+		k, err := registry.OpenKey(location.root, location.path, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+		value, _, err := k.GetStringValue(name)
+		k.Close()
+		if err == nil {
+			return value, nil
+		}
+	}
+	return "", registry.ErrNotExist
+}
+
 // NotifyWindowsOfEnvironmentChange notifies Windows that environment variables have changed
 func NotifyWindowsOfEnvironmentChange() error {
 	// Convert "Environment" string to UTF-16 pointer
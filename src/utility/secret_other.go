@@ -0,0 +1,14 @@
+//go:build !windows
+
+package utility
+
+// ProtectSecret returns data unchanged; on Unix secrets are protected by
+// writing them to a file only the owner can read
+func ProtectSecret(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+// UnprotectSecret returns data written by ProtectSecret
+func UnprotectSecret(data []byte) ([]byte, error) {
+	return data, nil
+}
@@ -64,6 +64,12 @@ func writeToLog(level string, message string) {
 	logFile.WriteString(logEntry)
 }
 
+// Exists checks if a file or directory exists
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 func CleanVersion(version string) string {
 	// Remove any leading 'v' or other non-numeric characters
 	version = strings.TrimPrefix(version, "v")
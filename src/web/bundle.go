@@ -0,0 +1,335 @@
+package web
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"jdkvm/java"
+)
+
+// bundleManifestName is the first entry of every bundle
+const bundleManifestName = "manifest.json"
+
+// bundleFormat is bumped whenever the bundle layout changes incompatibly
+const bundleFormat = 1
+
+// bundleFilePattern matches the archive names CreateBundle writes
+var bundleFilePattern = regexp.MustCompile(`^archives/[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+
+// BundleManifest describes the contents of an offline bundle: the catalog
+// entries of the packaged feature releases and one record per archive
+type BundleManifest struct {
+	Format    int                        `json:"format"`
+	Created   string                     `json:"created"`
+	Catalog   map[string]JavaVersionInfo `json:"catalog"`
+	Artifacts []BundleArtifact           `json:"artifacts"`
+}
+
+// BundleArtifact is one archive inside a bundle
+type BundleArtifact struct {
+	Version string `json:"version"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	Image   string `json:"image"`
+	File    string `json:"file"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256"`
+}
+
+// CreateBundle downloads the archives of the given releases, verifies
+// them against the published checksums and packages them, together with their
+// catalog entries and checksums, into a tar file for air-gapped machines
+func CreateBundle(target string, versions []string, osName string, a string, image string) error {
+	if JavaVersionMapping == nil {
+		if err := LoadVersionMapping(); err != nil {
+			return err
+		}
+	}
+
+	manifest := BundleManifest{
+		Format:  bundleFormat,
+		Created: time.Now().UTC().Format(time.RFC3339),
+		Catalog: map[string]JavaVersionInfo{},
+	}
+	archives := make([]string, 0, len(versions))
+	defer func() {
+		for _, p := range archives {
+			os.Remove(p)
+		}
+	}()
+
+	for _, v := range versions {
+		versionInfo, ok := CurrentCatalog.FindRelease(v, "")
+		if !ok {
+			return failure(ErrNotFound, "unsupported Java version '%s'", v)
+		}
+		artifact, err := versionInfo.FindArtifact(osName, a, image)
+		if err != nil {
+			return failure(ErrNotFound, "%v", err)
+		}
+		downloadURL := artifact.URL
+
+		archivePath, published, err := downloadFromMirrors(artifact, versionInfo.Artifact(osName, a, image), fmt.Sprintf("java-%s-%s-%s-%s", versionInfo.Latest, osName, a, image))
+		if err != nil {
+			return err
+		}
+		archives = append(archives, archivePath)
+
+		checksum, err := FileChecksum(archivePath)
+		if err != nil {
+			return err
+		}
+		// Never package an archive that does not match what the vendor published
+		if published != "" && published != checksum {
+			return failure(ErrVerification, "checksum mismatch for %s: expected %s, got %s", filepath.Base(downloadURL), published, checksum)
+		}
+
+		manifest.Catalog[v] = versionInfo
+		manifest.Artifacts = append(manifest.Artifacts, BundleArtifact{
+			Version: v,
+			OS:      osName,
+			Arch:    a,
+			Image:   image,
+			File:    path.Join("archives", path.Base(downloadURL)),
+			URL:     downloadURL,
+			SHA256:  checksum,
+		})
+	}
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	tw := tar.NewWriter(out)
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeTarEntry(tw, bundleManifestName, int64(len(content)), bytes.NewReader(content)); err != nil {
+		return err
+	}
+	for i, artifact := range manifest.Artifacts {
+		f, err := os.Open(archives[i])
+		if err != nil {
+			return err
+		}
+		info, _ := f.Stat()
+		err = writeTarEntry(tw, artifact.File, info.Size(), f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// ReadBundleManifest returns the manifest of a bundle
+func ReadBundleManifest(bundlePath string) (BundleManifest, error) {
+	manifest := BundleManifest{}
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return manifest, err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	header, err := tr.Next()
+	if err != nil || header.Name != bundleManifestName {
+		return manifest, fmt.Errorf("%s is not a jdkvm bundle", bundlePath)
+	}
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("invalid bundle manifest: %v", err)
+	}
+	if manifest.Format > bundleFormat {
+		return manifest, fmt.Errorf("bundle format %d is newer than this jdkvm supports (%d)", manifest.Format, bundleFormat)
+	}
+	if err := checkBundleManifest(manifest); err != nil {
+		return manifest, failure(ErrVerification, "invalid bundle manifest: %v", err)
+	}
+	return manifest, nil
+}
+
+// checkBundleManifest makes sure nothing in a manifest can lead a path
+// outside the install or temp directory
+func checkBundleManifest(manifest BundleManifest) error {
+	for major, info := range manifest.Catalog {
+		if !java.Matches(info.Latest, major) {
+			return fmt.Errorf("catalog entry %q does not match its version %q", major, info.Latest)
+		}
+		if !java.ValidVersion(info.Latest) {
+			return fmt.Errorf("invalid version %q", info.Latest)
+		}
+		if !java.ValidVendor(info.VendorName()) {
+			return fmt.Errorf("invalid vendor %q", info.Vendor)
+		}
+	}
+	for _, artifact := range manifest.Artifacts {
+		info, ok := manifest.Catalog[artifact.Version]
+		if !ok {
+			return fmt.Errorf("%s has no catalog entry", artifact.File)
+		}
+		if !containsString(catalogOSes, artifact.OS) {
+			return fmt.Errorf("%s: unknown operating system %q", artifact.File, artifact.OS)
+		}
+		if err := java.CheckBuild(info.Latest, info.VendorName(), artifact.Arch, artifact.Image); err != nil {
+			return fmt.Errorf("%s: %v", artifact.File, err)
+		}
+		if !bundleFilePattern.MatchString(artifact.File) {
+			return fmt.Errorf("invalid archive name %q", artifact.File)
+		}
+	}
+	return nil
+}
+
+// InstallBundle installs the archives of a bundle without network access.
+// The manifest travels with the archives, so its checksums prove nothing on
+// their own: each archive is verified against the local catalog's checksum
+// when it has one, and against the manifest's only when the local catalog
+// lists the same download or trust is set. Versions are matched like
+// install's, so 17 and 17.0.11 select the same archive. An empty filter
+// value matches every artifact.
+func InstallBundle(root string, bundlePath string, versions []string, a string, trust bool) error {
+	manifest, err := ReadBundleManifest(bundlePath)
+	if err != nil {
+		return err
+	}
+	if JavaVersionMapping == nil {
+		if err := LoadVersionMapping(); err != nil {
+			return err
+		}
+	}
+
+	selected := map[string]BundleArtifact{}
+	for _, artifact := range manifest.Artifacts {
+		if len(versions) > 0 && !matchesAny(manifest.Catalog[artifact.Version].Latest, versions) {
+			continue
+		}
+		if a != "" && artifact.Arch != a {
+			continue
+		}
+		selected[artifact.File] = artifact
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("the bundle contains no matching Java versions")
+	}
+
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	failed := 0
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		artifact, ok := selected[header.Name]
+		if !ok {
+			continue
+		}
+
+		if err := CheckOSCompatible(artifact.OS); err != nil {
+			fmt.Printf("Skipping Java %s (%s, %s): %v.\n", artifact.Version, artifact.OS, artifact.Arch, err)
+			failed++
+			continue
+		}
+
+		archivePath := filepath.Join(os.TempDir(), path.Base(artifact.File))
+		out, err := os.Create(archivePath)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, tr)
+		out.Close()
+		if err != nil {
+			os.Remove(archivePath)
+			return err
+		}
+
+		versionInfo := manifest.Catalog[artifact.Version]
+		fmt.Printf("Installing Java %s (%s, %s) from bundle...\n", versionInfo.Latest, artifact.Image, artifact.Arch)
+		checksum, err := bundleChecksum(artifact, versionInfo.Latest, versionInfo.VendorName(), trust)
+		if err == nil {
+			err = InstallArchive(root, versionInfo, artifact.Arch, artifact.OS, artifact.Image, archivePath, checksum)
+		}
+		if err != nil {
+			fmt.Println(err)
+			failed++
+		}
+		os.Remove(archivePath)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d Java versions could not be installed", failed, len(selected))
+	}
+	return nil
+}
+
+// bundleChecksum returns the checksum to verify a bundled archive against.
+// A catalog that lists the same download without a checksum, as the embedded
+// one does, vouches for the release; the bundle's checksum was checked
+// against the vendor's when the bundle was created.
+func bundleChecksum(artifact BundleArtifact, version string, vendor string, trust bool) (string, error) {
+	listed, ok := CurrentCatalog.Artifact(version, vendor, artifact.OS, artifact.Arch, artifact.Image, packageOf(artifact.File))
+	switch {
+	case listed.SHA256 != "" && listed.SHA256 != artifact.SHA256:
+		return "", failure(ErrVerification, "the bundle's checksum of Java %s (%s, %s) differs from the catalog's; the bundle may have been tampered with", version, artifact.Image, artifact.Arch)
+	case listed.SHA256 != "":
+		return listed.SHA256, nil
+	case ok && listed.URL == artifact.URL:
+		return artifact.SHA256, nil
+	case trust:
+		fmt.Printf("Warning: The catalog does not list Java %s (%s, %s) from %s; trusting the bundle's checksum.\n", version, artifact.Image, artifact.Arch, artifact.URL)
+		return artifact.SHA256, nil
+	}
+	return "", failure(ErrVerification, "the catalog does not list Java %s (%s, %s) from %s to verify the bundle against; update the catalog, or pass --trust-bundle to rely on the bundle's own checksum", version, artifact.Image, artifact.Arch, artifact.URL)
+}
+
+func writeTarEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+// matchesAny reports whether a release matches one of the requested versions
+func matchesAny(version string, requested []string) bool {
+	for _, r := range requested {
+		if java.Matches(version, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,319 @@
+package web
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"jdkvm/arch"
+	"jdkvm/java"
+)
+
+// testBundle is a bundle of one Java 17 archive for this host, along with
+// the catalog installing it is checked against
+type testBundle struct {
+	archive  []byte
+	checksum string
+	manifest BundleManifest
+}
+
+func newTestBundle(t *testing.T) testBundle {
+	t.Helper()
+	archivePath := filepath.Join(t.TempDir(), "jdk.tar.gz")
+	launcher := "#!/bin/sh\necho 17\n"
+	writeTarGz(t, archivePath, []tarEntry{
+		{name: "jdk-17.0.11+9/", typeflag: tar.TypeDir},
+		{name: "jdk-17.0.11+9/bin/java", typeflag: tar.TypeReg, body: launcher},
+		{name: "jdk-17.0.11+9/bin/java.exe", typeflag: tar.TypeReg, body: launcher},
+		{name: "jdk-17.0.11+9/release", typeflag: tar.TypeReg, body: "JAVA_VERSION=\"17.0.11\"\n"},
+	})
+	content, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum, err := FileChecksum(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := JavaVersionInfo{Latest: "17.0.11+9", Short: "17", Vendor: "temurin"}
+	return testBundle{
+		archive:  content,
+		checksum: checksum,
+		manifest: BundleManifest{
+			Format:  bundleFormat,
+			Created: "2024-05-01T00:00:00Z",
+			Catalog: map[string]JavaVersionInfo{"17": info},
+			Artifacts: []BundleArtifact{{
+				Version: "17",
+				OS:      HostOS(),
+				Arch:    arch.Host(),
+				Image:   java.ImageJDK,
+				File:    "archives/OpenJDK17U-jdk_hotspot_17.0.11_9.tar.gz",
+				URL:     testBundleURL,
+				SHA256:  checksum,
+			}},
+		},
+	}
+}
+
+// write saves the bundle the way CreateBundle lays it out
+func (b testBundle) write(t *testing.T) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	content, _ := json.Marshal(b.manifest)
+	if err := writeTarEntry(tw, bundleManifestName, int64(len(content)), bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range b.manifest.Artifacts {
+		if err := writeTarEntry(tw, a.File, int64(len(b.archive)), bytes.NewReader(b.archive)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	path := filepath.Join(t.TempDir(), "bundle.tar")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const testBundleURL = "https://example.com/OpenJDK17U-jdk_hotspot_17.0.11_9.tar.gz"
+
+// useTestCatalog makes the catalog list an archive of Java 17 with a checksum
+func useTestCatalog(t *testing.T, checksum string, url string) {
+	saved, savedMapping := CurrentCatalog, JavaVersionMapping
+	t.Cleanup(func() { CurrentCatalog, JavaVersionMapping = saved, savedMapping })
+
+	artifact := CatalogArtifact{
+		OS:      HostOS(),
+		Arch:    arch.Host(),
+		Image:   java.ImageJDK,
+		Package: PackageTarGz,
+		URL:     url,
+		SHA256:  checksum,
+	}
+	CurrentCatalog = Catalog{SchemaVersion: CatalogSchemaVersion, Versions: map[string]FeatureRelease{
+		"17": {Vendor: "temurin", Releases: []Release{{Version: "17.0.11+9", Artifacts: []CatalogArtifact{artifact}}}},
+	}}
+	JavaVersionMapping = CurrentCatalog.Mapping()
+}
+
+func TestCheckBundleManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(m *BundleManifest)
+		wantErr bool
+	}{
+		{"valid", func(m *BundleManifest) {}, false},
+		{"exact version key", func(m *BundleManifest) {
+			m.Catalog["17.0.11"] = m.Catalog["17"]
+			m.Artifacts[0].Version = "17.0.11"
+		}, false},
+		{"version with path", func(m *BundleManifest) {
+			setBundleInfo(m, func(info *JavaVersionInfo) { info.Latest = "17/../../../x" })
+		}, true},
+		{"version of another feature release", func(m *BundleManifest) { setBundleInfo(m, func(info *JavaVersionInfo) { info.Latest = "21.0.3+9" }) }, true},
+		{"vendor with path", func(m *BundleManifest) { setBundleInfo(m, func(info *JavaVersionInfo) { info.Vendor = "../../x" }) }, true},
+		{"artifact without catalog entry", func(m *BundleManifest) { m.Artifacts[0].Version = "21" }, true},
+		{"unknown os", func(m *BundleManifest) { m.Artifacts[0].OS = "../linux" }, true},
+		{"unknown arch", func(m *BundleManifest) { m.Artifacts[0].Arch = "../x64" }, true},
+		{"unknown image", func(m *BundleManifest) { m.Artifacts[0].Image = "sdk/.." }, true},
+		{"file outside archives", func(m *BundleManifest) { m.Artifacts[0].File = "manifest.json" }, true},
+		{"file with parent directory", func(m *BundleManifest) { m.Artifacts[0].File = "archives/../../etc/passwd" }, true},
+		{"file in a subdirectory", func(m *BundleManifest) { m.Artifacts[0].File = "archives/x/jdk.tar.gz" }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBundle(t)
+			tt.change(&b.manifest)
+			err := checkBundleManifest(b.manifest)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkBundleManifest error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func setBundleInfo(m *BundleManifest, change func(info *JavaVersionInfo)) {
+	info := m.Catalog["17"]
+	change(&info)
+	m.Catalog["17"] = info
+}
+
+func TestReadBundleManifestRejectsBadManifest(t *testing.T) {
+	b := newTestBundle(t)
+	setBundleInfo(&b.manifest, func(info *JavaVersionInfo) { info.Latest = "../../../tmp/x" })
+	if _, err := ReadBundleManifest(b.write(t)); !errors.Is(err, ErrVerification) {
+		t.Errorf("ReadBundleManifest error = %v, want a verification failure", err)
+	}
+
+	b = newTestBundle(t)
+	b.manifest.Format = bundleFormat + 1
+	if _, err := ReadBundleManifest(b.write(t)); err == nil {
+		t.Error("ReadBundleManifest accepted a newer format")
+	}
+}
+
+func TestBundleChecksum(t *testing.T) {
+	const other = "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+	b := newTestBundle(t)
+	artifact := b.manifest.Artifacts[0]
+
+	tests := []struct {
+		name    string
+		catalog string
+		url     string
+		bundle  string
+		trust   bool
+		want    string
+		wantErr bool
+	}{
+		{"catalog agrees", b.checksum, testBundleURL, b.checksum, false, b.checksum, false},
+		{"catalog disagrees", other, testBundleURL, b.checksum, false, "", true},
+		{"catalog disagrees, trusted", other, testBundleURL, b.checksum, true, "", true},
+		{"catalog lists the download without a checksum", "", testBundleURL, b.checksum, false, b.checksum, false},
+		{"catalog lists another download", "", "https://example.com/other.tar.gz", b.checksum, false, "", true},
+		{"catalog lists another download, trusted", "", "https://example.com/other.tar.gz", b.checksum, true, b.checksum, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestCatalog(t, tt.catalog, tt.url)
+			artifact.SHA256 = tt.bundle
+			got, err := bundleChecksum(artifact, "17.0.11+9", "temurin", tt.trust)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("bundleChecksum error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrVerification) {
+				t.Errorf("bundleChecksum error = %v, want a verification failure", err)
+			}
+			if got != tt.want {
+				t.Errorf("bundleChecksum = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstallBundle(t *testing.T) {
+	tests := []struct {
+		name          string
+		withChecksum  bool
+		url           string
+		tamper        bool
+		trust         bool
+		wantInstalled bool
+	}{
+		{"verified against the catalog", true, testBundleURL, false, false, true},
+		{"tampered archive and manifest", true, testBundleURL, true, false, false},
+		{"tampered archive, trusted", true, testBundleURL, true, true, false},
+		{"no catalog checksum", false, testBundleURL, false, false, true},
+		{"download the catalog does not list", false, "https://example.com/other.tar.gz", false, false, false},
+		{"download the catalog does not list, trusted", false, "https://example.com/other.tar.gz", false, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBundle(t)
+			catalogChecksum := ""
+			if tt.withChecksum {
+				catalogChecksum = b.checksum
+			}
+			useTestCatalog(t, catalogChecksum, tt.url)
+			if tt.tamper {
+				// Whoever swaps the archive rewrites the manifest to match
+				b.archive = append(b.archive, 0)
+				b.manifest.Artifacts[0].SHA256 = checksumOf(t, b.archive)
+			}
+
+			root := t.TempDir()
+			err := InstallBundle(root, b.write(t), nil, "", tt.trust)
+			_, installed := java.Find(root, java.Query{Version: "17"})
+			if installed != tt.wantInstalled {
+				t.Errorf("installed = %v, want %v (error %v)", installed, tt.wantInstalled, err)
+			}
+			if (err == nil) != tt.wantInstalled {
+				t.Errorf("InstallBundle error = %v", err)
+			}
+		})
+	}
+}
+
+func TestInstallBundleVersions(t *testing.T) {
+	tests := []struct {
+		versions      []string
+		wantInstalled bool
+	}{
+		{nil, true},
+		{[]string{"17"}, true},
+		{[]string{"17.0.11"}, true},
+		{[]string{"17.0.11+9"}, true},
+		{[]string{"21", "17.0.11"}, true},
+		{[]string{"17.0.9"}, false},
+		{[]string{"21"}, false},
+	}
+	for _, tt := range tests {
+		b := newTestBundle(t)
+		useTestCatalog(t, b.checksum, testBundleURL)
+		root := t.TempDir()
+		err := InstallBundle(root, b.write(t), tt.versions, "", false)
+		if (err == nil) != tt.wantInstalled {
+			t.Errorf("InstallBundle(%q) error = %v, want installed %v", tt.versions, err, tt.wantInstalled)
+		}
+		if _, installed := java.Find(root, java.Query{Version: "17"}); installed != tt.wantInstalled {
+			t.Errorf("InstallBundle(%q) installed = %v, want %v", tt.versions, installed, tt.wantInstalled)
+		}
+	}
+}
+
+// TestInstallBundleEmbeddedCatalog installs a bundle the way a default setup
+// does: against the embedded catalog, which carries no checksums, and
+// without --trust-bundle
+func TestInstallBundleEmbeddedCatalog(t *testing.T) {
+	savedSources, saved, savedMapping := catalogSources, CurrentCatalog, JavaVersionMapping
+	t.Cleanup(func() { catalogSources, CurrentCatalog, JavaVersionMapping = savedSources, saved, savedMapping })
+	ConfigureCatalog(CatalogSources{})
+	if err := LoadVersionMapping(); err != nil {
+		t.Fatal(err)
+	}
+	info, ok := CurrentCatalog.FindRelease("17", "")
+	if !ok {
+		t.Fatal("the embedded catalog has no Java 17")
+	}
+	listed, err := info.FindArtifact(HostOS(), arch.Host(), java.ImageJDK)
+	if err != nil {
+		t.Skipf("the embedded catalog has no Java 17 for this host: %v", err)
+	}
+	if listed.Package != PackageTarGz {
+		t.Skipf("this host's Java 17 is packaged as %s", listed.Package)
+	}
+
+	b := newTestBundle(t)
+	b.manifest.Catalog = map[string]JavaVersionInfo{"17": info}
+	b.manifest.Artifacts[0].URL = listed.URL
+	b.manifest.Artifacts[0].File = "archives/" + path.Base(listed.URL)
+	root := t.TempDir()
+	if err := InstallBundle(root, b.write(t), []string{"17"}, "", false); err != nil {
+		t.Fatalf("InstallBundle: %v", err)
+	}
+	if _, ok := java.Find(root, java.Query{Version: info.Latest}); !ok {
+		t.Errorf("Java %s was not installed", info.Latest)
+	}
+}
+
+func checksumOf(t *testing.T, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "content")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	checksum, err := FileChecksum(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return checksum
+}
@@ -0,0 +1,194 @@
+package web
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// embeddedCatalog is the catalog this release was built with, used when no
+// newer one is available
+//
+//go:embed version_mapping.json
+var embeddedCatalog []byte
+
+// Catalog sources, lowest precedence first
+const (
+	CatalogEmbedded = "embedded"
+	CatalogUser     = "user"
+	CatalogRemote   = "remote"
+	CatalogSigned   = "signed"
+)
+
+// catalogMaxAge is how long a downloaded remote catalog is used before it is fetched again
+const catalogMaxAge = 24 * time.Hour
+
+// CatalogSources says where catalogs are looked for. Empty fields are skipped.
+type CatalogSources struct {
+	// SignedFile keeps the catalog fetched by jdkvm catalog update, which is
+	// only used while its signature matches PublicKey
+	SignedFile string
+	PublicKey  string
+	// UserFile is a catalog maintained by the user in the config directory
+	UserFile string
+	// RemoteURL serves a live catalog, e.g. another machine's jdkvm serve
+	RemoteURL string
+	// CacheFile keeps the last catalog downloaded from RemoteURL
+	CacheFile string
+	// Offline uses the cached remote catalog however old it is, and never
+	// downloads one
+	Offline bool
+}
+
+// CatalogCandidate is one catalog source and whether it could be used
+type CatalogCandidate struct {
+	Source   string
+	Location string
+	Status   string
+	Used     bool
+}
+
+var (
+	catalogSources CatalogSources
+	catalogReport  []CatalogCandidate
+)
+
+// ConfigureCatalog sets where LoadVersionMapping looks for catalogs
+func ConfigureCatalog(src CatalogSources) {
+	catalogSources = src
+	JavaVersionMapping = nil
+}
+
+// CatalogReport returns every source the last LoadVersionMapping considered,
+// highest precedence first
+func CatalogReport() []CatalogCandidate {
+	return catalogReport
+}
+
+// LoadVersionMapping loads the catalog from the highest-precedence source
+// that works: the signed catalog, the remote catalog, the user catalog, then
+// the embedded one
+func LoadVersionMapping() error {
+	catalogReport = nil
+	src := catalogSources
+
+	if src.SignedFile != "" {
+		catalog, status := loadSignedCatalog(src)
+		if useCatalog(CatalogSigned, src.SignedFile, catalog, status) {
+			return nil
+		}
+	}
+
+	if src.RemoteURL != "" {
+		catalog, status := loadRemoteCatalog(src)
+		if useCatalog(CatalogRemote, src.RemoteURL, catalog, status) {
+			return nil
+		}
+	} else {
+		catalogReport = append(catalogReport, CatalogCandidate{Source: CatalogRemote, Status: "not configured (catalog_url)"})
+	}
+
+	if src.UserFile != "" {
+		catalog, err := readCatalogFile(src.UserFile)
+		status := "ok"
+		if os.IsNotExist(err) {
+			status = "not present"
+		} else if err != nil {
+			status = err.Error()
+			warnf("Ignoring catalog %s: %v", src.UserFile, err)
+		}
+		if useCatalog(CatalogUser, src.UserFile, catalog, status) {
+			return nil
+		}
+	}
+
+	catalog, err := parseCatalog(embeddedCatalog)
+	if err != nil {
+		return fmt.Errorf("embedded catalog is invalid: %v", err)
+	}
+	useCatalog(CatalogEmbedded, "built into jdkvm", catalog, "ok")
+	return nil
+}
+
+// useCatalog records a candidate and makes it the catalog when it loaded
+func useCatalog(source string, location string, catalog *Catalog, status string) bool {
+	used := catalog != nil
+	catalogReport = append(catalogReport, CatalogCandidate{Source: source, Location: location, Status: status, Used: used})
+	debugf("Catalog %s (%s): %s", source, location, status)
+	if used {
+		CurrentCatalog = *catalog
+		JavaVersionMapping = catalog.Mapping()
+	}
+	return used
+}
+
+// loadSignedCatalog returns the catalog stored by jdkvm catalog update. A
+// stored catalog whose signature no longer verifies is skipped with a
+// warning rather than trusted.
+func loadSignedCatalog(src CatalogSources) (*Catalog, string) {
+	if _, err := os.Stat(src.SignedFile); err != nil {
+		return nil, "not present (jdkvm catalog update)"
+	}
+	if src.PublicKey == "" {
+		return nil, "ignored: catalog_public_key is not set"
+	}
+	catalog, signed, err := readSignedCatalog(src.SignedFile, src.PublicKey)
+	if err != nil {
+		warnf("Ignoring the signed catalog %s: %v", src.SignedFile, err)
+		return nil, err.Error()
+	}
+	return catalog, "ok (published " + signed.Published + ", fetched " + signed.Fetched + ")"
+}
+
+// loadRemoteCatalog returns the remote catalog, downloading it when the
+// cached copy is missing or older than catalogMaxAge. A stale copy is still
+// used when the download fails.
+func loadRemoteCatalog(src CatalogSources) (*Catalog, string) {
+	cached, cacheErr := readCatalogFile(src.CacheFile)
+	if info, err := os.Stat(src.CacheFile); cacheErr == nil && err == nil && (src.Offline || time.Since(info.ModTime()) < catalogMaxAge) {
+		return cached, "ok (cached " + info.ModTime().Format("2006-01-02 15:04") + ")"
+	}
+	if src.Offline {
+		return nil, "not downloaded (offline)"
+	}
+
+	content, err := GetRemoteTextFile(src.RemoteURL)
+	if err == nil {
+		var catalog *Catalog
+		if catalog, err = parseCatalog([]byte(content)); err == nil {
+			if src.CacheFile != "" {
+				os.MkdirAll(filepath.Dir(src.CacheFile), os.ModePerm)
+				os.WriteFile(src.CacheFile, []byte(content), 0644)
+			}
+			return catalog, "ok (downloaded)"
+		}
+	}
+
+	if cacheErr == nil {
+		warnf("Could not refresh the catalog from %s, using the cached copy: %v", src.RemoteURL, err)
+		return cached, "stale cached copy (" + err.Error() + ")"
+	}
+	warnf("Could not download the catalog from %s: %v", src.RemoteURL, err)
+	return nil, err.Error()
+}
+
+func readCatalogFile(path string) (*Catalog, error) {
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseCatalog(content)
+}
+
+func parseCatalog(content []byte) (*Catalog, error) {
+	catalog, _, err := ParseCatalog(content)
+	if err != nil {
+		return nil, err
+	}
+	return &catalog, nil
+}
@@ -0,0 +1,208 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Publish times that tell the test catalogs apart
+const (
+	signedPublished = "2024-06-01T00:00:00Z"
+	remotePublished = "2024-05-01T00:00:00Z"
+	userPublished   = "2024-04-01T00:00:00Z"
+	cachePublished  = "2024-03-01T00:00:00Z"
+)
+
+// useCatalogSources restores the catalog settings and state after a test
+func useCatalogSources(t *testing.T, src CatalogSources) {
+	t.Helper()
+	savedSources, saved, savedMapping, savedReport := catalogSources, CurrentCatalog, JavaVersionMapping, catalogReport
+	t.Cleanup(func() {
+		catalogSources, CurrentCatalog, JavaVersionMapping, catalogReport = savedSources, saved, savedMapping, savedReport
+	})
+	ConfigureCatalog(src)
+}
+
+// catalogJSON returns the test catalog published at a time
+func catalogJSON(t *testing.T, published string) string {
+	t.Helper()
+	c := testCatalog()
+	c.Published = published
+	content, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// countingServer serves a catalog at /catalog.json and counts every request
+type countingServer struct {
+	content string
+	hits    atomic.Int32
+}
+
+func (s *countingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.hits.Add(1)
+	if r.URL.Path != "/catalog.json" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write([]byte(s.content))
+}
+
+func TestLoadVersionMappingPrecedence(t *testing.T) {
+	public, private, _ := GenerateCatalogKey()
+	otherPublic, _, _ := GenerateCatalogKey()
+	server := httptest.NewServer(&countingServer{content: catalogJSON(t, remotePublished)})
+	defer server.Close()
+
+	dir := t.TempDir()
+	signedFile := filepath.Join(dir, "signed.json")
+	content, signature := signedContent(t, signedPublished, "2027-10-31", private)
+	if err := writeSignedFile(signedFile, SignedCatalog{Published: signedPublished, Signature: signature, Catalog: content}); err != nil {
+		t.Fatal(err)
+	}
+	userFile := filepath.Join(dir, "catalog.json")
+	if err := os.WriteFile(userFile, []byte(catalogJSON(t, userPublished)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	invalidFile := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalidFile, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	staleCache := filepath.Join(dir, "cache.json")
+	if err := os.WriteFile(staleCache, []byte(catalogJSON(t, cachePublished)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Now().Add(-2 * catalogMaxAge)
+	if err := os.Chtimes(staleCache, day, day); err != nil {
+		t.Fatal(err)
+	}
+	embedded, err := parseCatalog(embeddedCatalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote := server.URL + "/catalog.json"
+	missing := server.URL + "/missing.json"
+	tests := []struct {
+		name          string
+		src           CatalogSources
+		wantPublished string
+		wantSource    string
+	}{
+		{"signed first", CatalogSources{SignedFile: signedFile, PublicKey: public, RemoteURL: remote, UserFile: userFile}, signedPublished, CatalogSigned},
+		{"signed without a key", CatalogSources{SignedFile: signedFile, RemoteURL: remote, UserFile: userFile}, remotePublished, CatalogRemote},
+		{"signed with another key", CatalogSources{SignedFile: signedFile, PublicKey: otherPublic, RemoteURL: remote, UserFile: userFile}, remotePublished, CatalogRemote},
+		{"remote before user", CatalogSources{RemoteURL: remote, UserFile: userFile}, remotePublished, CatalogRemote},
+		{"remote failing", CatalogSources{RemoteURL: missing, UserFile: userFile}, userPublished, CatalogUser},
+		{"remote failing with a cached copy", CatalogSources{RemoteURL: missing, CacheFile: staleCache, UserFile: userFile}, cachePublished, CatalogRemote},
+		{"user without remote", CatalogSources{UserFile: userFile}, userPublished, CatalogUser},
+		{"invalid user catalog", CatalogSources{UserFile: invalidFile}, embedded.Published, CatalogEmbedded},
+		{"missing user catalog", CatalogSources{UserFile: filepath.Join(dir, "none.json")}, embedded.Published, CatalogEmbedded},
+		{"nothing configured", CatalogSources{}, embedded.Published, CatalogEmbedded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCatalogSources(t, tt.src)
+			if err := LoadVersionMapping(); err != nil {
+				t.Fatal(err)
+			}
+			if CurrentCatalog.Published != tt.wantPublished {
+				t.Errorf("Published = %q, want %q", CurrentCatalog.Published, tt.wantPublished)
+			}
+			if JavaVersionMapping == nil {
+				t.Error("the version mapping was not set")
+			}
+			used := ""
+			for _, c := range CatalogReport() {
+				if c.Used {
+					if used != "" {
+						t.Errorf("both %s and %s are reported as used", used, c.Source)
+					}
+					used = c.Source
+				}
+			}
+			if used != tt.wantSource {
+				t.Errorf("used source = %q, want %q", used, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestLoadRemoteCatalogCache(t *testing.T) {
+	tests := []struct {
+		name          string
+		cacheAge      time.Duration
+		noCache       bool
+		serverDown    bool
+		offline       bool
+		wantPublished string
+		wantHits      int32
+		wantStatus    string
+		wantCached    string
+	}{
+		{"fresh cache", time.Hour, false, false, false, cachePublished, 0, "ok (cached", cachePublished},
+		{"stale cache is refreshed", 25 * time.Hour, false, false, false, remotePublished, 1, "ok (downloaded)", remotePublished},
+		{"stale cache when the fetch fails", 25 * time.Hour, false, true, false, cachePublished, 1, "stale cached copy", cachePublished},
+		{"offline uses a stale cache", 25 * time.Hour, false, false, true, cachePublished, 0, "ok (cached", cachePublished},
+		{"no cache", 0, true, false, false, remotePublished, 1, "ok (downloaded)", remotePublished},
+		{"no cache when the fetch fails", 0, true, true, false, "", 1, "HTTP Status 404", ""},
+		{"offline without a cache", 0, true, false, true, "", 0, "not downloaded (offline)", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &countingServer{content: catalogJSON(t, remotePublished)}
+			server := httptest.NewServer(backend)
+			defer server.Close()
+			remote := server.URL + "/catalog.json"
+			if tt.serverDown {
+				remote = server.URL + "/missing.json"
+			}
+
+			cacheFile := filepath.Join(t.TempDir(), "cache", "catalog.json")
+			if !tt.noCache {
+				if err := os.MkdirAll(filepath.Dir(cacheFile), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(cacheFile, []byte(catalogJSON(t, cachePublished)), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				modified := time.Now().Add(-tt.cacheAge)
+				if err := os.Chtimes(cacheFile, modified, modified); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			catalog, status := loadRemoteCatalog(CatalogSources{RemoteURL: remote, CacheFile: cacheFile, Offline: tt.offline})
+			published := ""
+			if catalog != nil {
+				published = catalog.Published
+			}
+			if published != tt.wantPublished {
+				t.Errorf("Published = %q, want %q", published, tt.wantPublished)
+			}
+			if !strings.Contains(status, tt.wantStatus) {
+				t.Errorf("status = %q, want it to contain %q", status, tt.wantStatus)
+			}
+			if hits := backend.hits.Load(); hits != tt.wantHits {
+				t.Errorf("%d downloads, want %d", hits, tt.wantHits)
+			}
+
+			cached, err := readCatalogFile(cacheFile)
+			switch {
+			case tt.wantCached == "" && err == nil:
+				t.Error("a cache file was written")
+			case tt.wantCached != "" && (err != nil || cached.Published != tt.wantCached):
+				t.Errorf("cache holds %v (%v), want the catalog published %s", cached, err, tt.wantCached)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Verbose makes jdkvm explain what it is doing on stderr: which catalog it
+// picked and which mirrors it tried
+var Verbose bool
+
+func debugf(format string, args ...interface{}) {
+	if Verbose {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// warnf reports a problem on stderr, where it cannot end up in output that
+// scripts capture
+func warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
+// Kinds of failure, so callers can tell them apart with errors.Is
+var (
+	// ErrNotFound means the catalog has no such release or build
+	ErrNotFound = errors.New("not found")
+	// ErrNetwork means a download failed
+	ErrNetwork = errors.New("network failure")
+	// ErrVerification means a checksum, architecture or C library check failed
+	ErrVerification = errors.New("verification failed")
+)
+
+// kindError is an error of one of the kinds above, printed without the kind
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// failure returns an error of a kind
+func failure(kind error, format string, args ...interface{}) error {
+	return &kindError{kind: kind, err: fmt.Errorf(format, args...)}
+}
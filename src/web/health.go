@@ -0,0 +1,190 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A mirror that failed this many times in a row is skipped until the cooldown passes
+const (
+	mirrorMaxFailures = 3
+	mirrorCooldown    = 30 * time.Minute
+	mirrorProbeTime   = 5 * time.Second
+)
+
+// MirrorHealth is what jdkvm remembers about one mirror host between runs
+type MirrorHealth struct {
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure,omitempty"`
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	LatencyMs   int64     `json:"latency_ms,omitempty"`
+}
+
+var (
+	mirrorStateFile string
+	mirrorProbe     bool
+	mirrorHealth    = map[string]*MirrorHealth{}
+	mirrorHealthMu  sync.Mutex
+)
+
+// ConfigureMirrorHealth sets the file mirror health is kept in and whether
+// mirrors are probed with HEAD requests to try the fastest one first
+func ConfigureMirrorHealth(stateFile string, probe bool) {
+	mirrorStateFile = stateFile
+	mirrorProbe = probe
+
+	mirrorHealthMu.Lock()
+	defer mirrorHealthMu.Unlock()
+	mirrorHealth = map[string]*MirrorHealth{}
+	if content, err := os.ReadFile(stateFile); err == nil {
+		json.Unmarshal(content, &mirrorHealth)
+	}
+}
+
+// MirrorHealthReport returns a copy of the recorded health of every mirror host
+func MirrorHealthReport() map[string]MirrorHealth {
+	mirrorHealthMu.Lock()
+	defer mirrorHealthMu.Unlock()
+	report := make(map[string]MirrorHealth, len(mirrorHealth))
+	for host, h := range mirrorHealth {
+		report[host] = *h
+	}
+	return report
+}
+
+// mirrorKey identifies the mirror a URL belongs to
+func mirrorKey(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
+
+// isDead reports whether a mirror failed repeatedly and recently
+func isDead(u string) bool {
+	mirrorHealthMu.Lock()
+	defer mirrorHealthMu.Unlock()
+	h, ok := mirrorHealth[mirrorKey(u)]
+	return ok && h.Failures >= mirrorMaxFailures && time.Since(h.LastFailure) < mirrorCooldown
+}
+
+// recordMirror updates and saves the health of the mirror serving u
+func recordMirror(u string, ok bool) {
+	mirrorHealthMu.Lock()
+	defer mirrorHealthMu.Unlock()
+	key := mirrorKey(u)
+	h, found := mirrorHealth[key]
+	if !found {
+		h = &MirrorHealth{}
+		mirrorHealth[key] = h
+	}
+	if ok {
+		h.Failures = 0
+		h.LastSuccess = time.Now()
+	} else {
+		h.Failures++
+		h.LastFailure = time.Now()
+	}
+	saveMirrorHealth()
+}
+
+// hostFailure reports whether a failed download counts against the health of
+// its mirror: the host could not be reached, the connection or TLS handshake
+// failed, or it answered with a server error. A missing file does not, as
+// mirrors such as TUNA only keep the newest builds.
+func hostFailure(err error) bool {
+	var de *downloadError
+	return errors.As(err, &de) && (de.status == 0 || de.status >= 500)
+}
+
+// saveMirrorHealth writes the state file; callers hold mirrorHealthMu
+func saveMirrorHealth() {
+	if mirrorStateFile == "" {
+		return
+	}
+	content, err := json.MarshalIndent(mirrorHealth, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := mirrorStateFile + ".tmp"
+	if os.WriteFile(tmp, content, 0644) == nil {
+		os.Rename(tmp, mirrorStateFile)
+	}
+}
+
+// orderMirrors puts the candidates in the order they should be tried: mirrors
+// known to be dead go last, and with probing enabled the rest are sorted by
+// how fast they answer a HEAD request. Otherwise the configured order is kept.
+func orderMirrors(candidates []string) []string {
+	alive := make([]string, 0, len(candidates))
+	dead := make([]string, 0)
+	for _, c := range candidates {
+		if isDead(c) {
+			dead = append(dead, c)
+		} else {
+			alive = append(alive, c)
+		}
+	}
+
+	if mirrorProbe && len(alive) > 1 {
+		latency := probeMirrors(alive)
+		sort.SliceStable(alive, func(i, j int) bool {
+			return latency[alive[i]] < latency[alive[j]]
+		})
+	}
+	return append(alive, dead...)
+}
+
+// probeMirrors sends a HEAD request for the archive to every candidate at once.
+// Candidates that fail or do not have the archive get the maximum latency.
+func probeMirrors(candidates []string) map[string]time.Duration {
+	latency := make(map[string]time.Duration, len(candidates))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	probe := &http.Client{Transport: client.Transport, Timeout: mirrorProbeTime}
+
+	for _, c := range candidates {
+		wg.Add(1)
+		go func(c string) {
+			defer wg.Done()
+			start := time.Now()
+			d := time.Duration(1<<63 - 1)
+			req, err := http.NewRequest(http.MethodHead, c, nil)
+			if err == nil {
+				req.Header.Set("User-Agent", "JDKVM")
+				if resp, err := probe.Do(req); err == nil {
+					resp.Body.Close()
+					if resp.StatusCode < 400 {
+						d = time.Since(start)
+					}
+				}
+			}
+			mu.Lock()
+			latency[c] = d
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	mirrorHealthMu.Lock()
+	for c, d := range latency {
+		if d >= mirrorProbeTime {
+			continue
+		}
+		key := mirrorKey(c)
+		if mirrorHealth[key] == nil {
+			mirrorHealth[key] = &MirrorHealth{}
+		}
+		mirrorHealth[key].LatencyMs = d.Milliseconds()
+	}
+	saveMirrorHealth()
+	mirrorHealthMu.Unlock()
+	return latency
+}
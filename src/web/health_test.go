@@ -0,0 +1,103 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHostFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", &downloadError{url: "https://mirror", err: errors.New("connection refused")}, true},
+		{"server error", &downloadError{url: "https://mirror", status: http.StatusBadGateway}, true},
+		{"not found", &downloadError{url: "https://mirror", status: http.StatusNotFound}, false},
+		{"forbidden", &downloadError{url: "https://mirror", status: http.StatusForbidden}, false},
+		{"wrapped", fmt.Errorf("mirror: %w", &downloadError{status: http.StatusServiceUnavailable}), true},
+		{"local error", errors.New("no space left on device"), false},
+	}
+	for _, tt := range tests {
+		if got := hostFailure(tt.err); got != tt.want {
+			t.Errorf("%s: hostFailure = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			fmt.Fprint(w, "archive")
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		wantErr  bool
+		wantHost bool
+	}{
+		{"ok", server.URL + "/ok", false, false},
+		{"missing file", server.URL + "/jdk-8u1.tar.gz", true, false},
+		{"server error", server.URL + "/broken", true, true},
+		{"unreachable", closed.URL + "/ok", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "archive")
+			err := download(tt.url, target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("download error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := hostFailure(err); got != tt.wantHost {
+				t.Errorf("hostFailure(%v) = %v, want %v", err, got, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestMirrorHealth(t *testing.T) {
+	ConfigureMirrorHealth(filepath.Join(t.TempDir(), "mirrors.json"), false)
+	t.Cleanup(func() { ConfigureMirrorHealth("", false) })
+
+	dead := "https://dead.example/jdk.tar.gz"
+	alive := "https://alive.example/jdk.tar.gz"
+	for i := 0; i < mirrorMaxFailures; i++ {
+		recordMirror(dead, false)
+	}
+	recordMirror(alive, false)
+
+	if !isDead(dead) || isDead(alive) {
+		t.Fatalf("isDead = %v, %v, want true, false", isDead(dead), isDead(alive))
+	}
+	if got := orderMirrors([]string{dead, alive}); got[0] != alive || got[1] != dead {
+		t.Errorf("orderMirrors = %v, want the dead mirror last", got)
+	}
+
+	// The state survives a restart, and a success revives the mirror
+	ConfigureMirrorHealth(mirrorStateFile, false)
+	if !isDead(dead) {
+		t.Error("mirror health was not saved")
+	}
+	recordMirror(dead, true)
+	if isDead(dead) {
+		t.Error("a successful download did not revive the mirror")
+	}
+	if _, err := os.Stat(mirrorStateFile); err != nil {
+		t.Error(err)
+	}
+}
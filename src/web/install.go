@@ -0,0 +1,346 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jdkvm/arch"
+	"jdkvm/file"
+	"jdkvm/java"
+)
+
+// GetJava downloads and installs the specified Java version, a feature release
+// or an exact release of one, from a vendor or, without one, the vendor the
+// catalog lists first. Standalone images
+// (jdk, jre) get their own install directory; overlay images (debugimage,
+// testimage, staticlibs) are unpacked into the matching JDK install.
+func GetJava(root string, v string, vendor string, a string, osName string, image string) error {
+	// Load version mapping if not already loaded
+	if JavaVersionMapping == nil {
+		if err := LoadVersionMapping(); err != nil {
+			return fmt.Errorf("error loading version mapping: %v", err)
+		}
+	}
+
+	// Resolve a feature release to its newest release, or find the exact release
+	versionInfo, exists := CurrentCatalog.FindRelease(v, vendor)
+	if !exists && vendor != "" {
+		if _, ok := CurrentCatalog.FindRelease(v, ""); ok {
+			return failure(ErrNotFound, "the catalog has no %s build of Java %s; it has builds from: %s", vendor, v, strings.Join(CurrentCatalog.Vendors(), ", "))
+		}
+	}
+	if !exists {
+		return failure(ErrNotFound, "unsupported Java version '%s'; use one of the supported versions: %s", v, strings.Join(GetAvailableVersions(), ", "))
+	}
+
+	// Use the full version from the catalog
+	fullVersion := versionInfo.Latest
+	fmt.Printf("Using Java %s version: %s\n", v, fullVersion)
+
+	// Refuse builds that would install fine but never start on this host
+	if err := CheckOSCompatible(osName); err != nil {
+		return err
+	}
+
+	published, err := versionInfo.FindArtifact(osName, a, image)
+	if err != nil {
+		return failure(ErrNotFound, "%v", err)
+	}
+
+	if isInstalled(root, versionInfo, a, image) {
+		fmt.Printf("Java version %s (%s, %s) is already installed.\n", fullVersion, image, a)
+		return nil
+	}
+
+	artifact := versionInfo.Artifact(osName, a, image)
+	archivePath, checksum, err := downloadFromMirrors(published, artifact, fmt.Sprintf("java-%s-%s-%s", fullVersion, a, image))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archivePath) // Clean up
+
+	return InstallArchive(root, versionInfo, a, osName, image, archivePath, checksum)
+}
+
+// InstallArchive verifies an archive that is already on disk against its
+// SHA-256 checksum and installs it. Network installs and offline bundles both
+// go through here so they are verified the same way.
+func InstallArchive(root string, versionInfo JavaVersionInfo, a string, osName string, image string, archivePath string, checksum string) error {
+	fullVersion := versionInfo.Latest
+	if err := java.CheckBuild(fullVersion, versionInfo.VendorName(), a, image); err != nil {
+		return failure(ErrVerification, "refusing to install: %v", err)
+	}
+	if isInstalled(root, versionInfo, a, image) {
+		fmt.Printf("Java version %s (%s, %s) is already installed.\n", fullVersion, image, a)
+		return nil
+	}
+
+	if checksum == "" {
+		fmt.Println("Warning: No checksum available; the archive cannot be verified.")
+	} else if err := VerifyChecksum(archivePath, checksum); err != nil {
+		return failure(ErrVerification, "Java installation verification failed: %v", err)
+	}
+
+	tempExtractDir, jdkDir, ok := extractArchive(archivePath)
+	if !ok {
+		return fmt.Errorf("could not extract %s", filepath.Base(archivePath))
+	}
+	defer os.RemoveAll(tempExtractDir)
+
+	if java.IsOverlay(image) {
+		return addOverlay(root, versionInfo, a, image, jdkDir)
+	}
+
+	// Create version directory
+	versionDir := filepath.Join(root, java.DirName(fullVersion, versionInfo.VendorName(), a, image))
+	if file.Exists(versionDir) {
+		// Clean up incomplete installation
+		fmt.Printf("Found incomplete installation of Java %s. Cleaning up...\n", fullVersion)
+		os.RemoveAll(versionDir)
+	}
+	if err := os.MkdirAll(versionDir, 0o755); err != nil {
+		return fmt.Errorf("could not create %s: %v", versionDir, err)
+	}
+
+	// Move the JDK contents to our version directory
+	jdkContents, _ := os.ReadDir(jdkDir)
+	for _, item := range jdkContents {
+		srcPath := filepath.Join(jdkDir, item.Name())
+		destPath := filepath.Join(versionDir, item.Name())
+
+		if err := os.Rename(srcPath, destPath); err != nil {
+			os.RemoveAll(versionDir) // Clean up incomplete directory
+			return fmt.Errorf("failed to move %s to %s: %v", item.Name(), versionDir, err)
+		}
+	}
+
+	// Verify the installation
+	javaExe := java.Executable(versionDir)
+	if !file.Exists(javaExe) {
+		os.RemoveAll(versionDir)
+		return failure(ErrVerification, "Java installation verification failed: %s not found", filepath.Base(javaExe))
+	}
+
+	// Make sure the archive actually contained a build for the requested
+	// architecture. A launcher whose header cannot be read proves nothing either way.
+	if got := arch.Detect(versionDir); got == arch.Unknown {
+		fmt.Printf("Warning: Could not tell the architecture of the Java %s launcher; assuming %s.\n", fullVersion, a)
+	} else if got != a {
+		os.RemoveAll(versionDir)
+		return failure(ErrVerification, "Java installation verification failed: expected a %s build but the archive contains %s", a, got)
+	}
+
+	// A glibc launcher on a musl host (or the reverse) fails with a bare "not found"
+	if need, host := arch.Libc(versionDir), arch.HostLibc(); need != "" && host != "" && need != host {
+		os.RemoveAll(versionDir)
+		return failure(ErrVerification, "Java installation verification failed: this build needs %s (%s) but this host uses %s", need, arch.Interpreter(versionDir), host)
+	}
+
+	// Record what was installed so list/use can tell builds apart. An install
+	// without its metadata would be listed and matched wrongly, so it is undone.
+	inst := java.Installation{Version: fullVersion, Vendor: versionInfo.VendorName(), Arch: a, OS: osName, Image: image, Dir: versionDir}
+	if err := java.WriteInstallation(inst); err != nil {
+		os.RemoveAll(versionDir)
+		return fmt.Errorf("could not write install metadata: %v", err)
+	}
+
+	fmt.Printf("Successfully installed Java %s (%s, %s)\n", fullVersion, image, a)
+	return nil
+}
+
+// isInstalled reports whether an image of the catalog release is already installed
+func isInstalled(root string, versionInfo JavaVersionInfo, a string, image string) bool {
+	query := java.Query{Version: versionInfo.Latest, Vendor: versionInfo.VendorName(), Arch: a, Image: image}
+	if java.IsOverlay(image) {
+		query.Image = java.ImageJDK
+	}
+	inst, ok := java.Find(root, query)
+	return ok && (!java.IsOverlay(image) || inst.HasExtra(image))
+}
+
+// addOverlay copies an extracted debug, test or static-libs image into the JDK install it belongs to
+func addOverlay(root string, versionInfo JavaVersionInfo, a string, image string, imageDir string) error {
+	fullVersion := versionInfo.Latest
+	base, ok := java.Find(root, java.Query{Version: fullVersion, Vendor: versionInfo.VendorName(), Arch: a, Image: java.ImageJDK})
+	if !ok {
+		return fmt.Errorf("the %s image is added to an existing JDK; install the JDK first: jdkvm install %s %s", image, versionInfo.Short, a)
+	}
+
+	// The test image is a separate tree; the others mirror the JDK layout
+	target := base.Dir
+	if image == java.ImageTest {
+		target = filepath.Join(base.Dir, "test-image")
+	}
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return fmt.Errorf("could not create %s: %v", target, err)
+	}
+	if err := file.CopyDir(imageDir, target); err != nil {
+		return fmt.Errorf("failed to copy the %s image into %s: %v", image, target, err)
+	}
+
+	base.Extras = append(base.Extras, image)
+	if err := java.WriteInstallation(base); err != nil {
+		return fmt.Errorf("the %s image was copied into %s but could not be recorded: %v", image, target, err)
+	}
+
+	fmt.Printf("Successfully added the %s image to Java %s (%s)\n", image, fullVersion, a)
+	return nil
+}
+
+// downloadFromMirrors downloads an archive from the configured mirrors and the
+// catalog URL, failing over to the next one on connection errors, missing
+// files or a checksum mismatch. It returns the archive path with its checksum.
+func downloadFromMirrors(published CatalogArtifact, artifact Artifact, name string) (string, string, error) {
+	downloadURL := published.URL
+
+	// A checksum in the catalog is used as is. Otherwise the vendor's is
+	// authoritative, and mirrors are only trusted for it when the vendor is unreachable.
+	expected, upstreamErr := published.SHA256, error(nil)
+	if expected == "" {
+		expected, upstreamErr = FetchChecksum(downloadURL)
+	}
+
+	mismatches := 0
+	candidates := orderMirrors(MirrorURLs(downloadURL, artifact))
+	for _, candidate := range candidates {
+		debugf("Downloading %s", candidate)
+		archivePath, err := downloadArchive(candidate, name)
+		if err != nil {
+			if hostFailure(err) {
+				recordMirror(candidate, false)
+			}
+			continue
+		}
+
+		checksum := expected
+		if upstreamErr != nil && candidate != downloadURL {
+			checksum, _ = FetchChecksum(candidate)
+		}
+		if checksum == "" {
+			fmt.Printf("Warning: Could not fetch the checksum of %s: %v\n", filepath.Base(downloadURL), upstreamErr)
+		} else if err := VerifyChecksum(archivePath, checksum); err != nil {
+			fmt.Printf("Discarding download from %s: %v\n", mirrorKey(candidate), err)
+			os.Remove(archivePath)
+			mismatches++
+			continue
+		}
+
+		recordMirror(candidate, true)
+		return archivePath, checksum, nil
+	}
+	if mismatches == len(candidates) {
+		return "", "", failure(ErrVerification, "every download of %s failed checksum verification", filepath.Base(downloadURL))
+	}
+	return "", "", failure(ErrNetwork, "could not download %s", filepath.Base(downloadURL))
+}
+
+// archiveExt returns the extension of an archive URL or path
+func archiveExt(name string) string {
+	if strings.HasSuffix(name, ".tar.gz") {
+		return ".tar.gz"
+	}
+	return ".zip"
+}
+
+// downloadArchive downloads an archive into the temp directory and returns its path
+func downloadArchive(downloadURL string, name string) (string, error) {
+	archivePath := filepath.Join(os.TempDir(), name+archiveExt(downloadURL))
+
+	fmt.Printf("Downloading Java from: %s\n", downloadURL)
+	fmt.Printf("Saving to: %s\n", archivePath)
+
+	if err := download(downloadURL, archivePath); err != nil {
+		fmt.Println(err)
+		fmt.Println("Failed to download Java archive.")
+		os.Remove(archivePath) // Clean up
+		return "", err
+	}
+	return archivePath, nil
+}
+
+// extractArchive extracts an archive into a temporary directory and returns
+// that directory along with the top-level image directory inside it
+func extractArchive(archivePath string) (string, string, bool) {
+	fmt.Printf("Extracting %s...\n", filepath.Base(archivePath))
+	ext := archiveExt(archivePath)
+	tempExtractDir := strings.TrimSuffix(archivePath, ext) + "-extract"
+	os.RemoveAll(tempExtractDir)
+	if err := os.MkdirAll(tempExtractDir, 0o755); err != nil {
+		fmt.Printf("Failed to extract Java archive: %v\n", err)
+		return "", "", false
+	}
+
+	extract := Unzip
+	if ext == ".tar.gz" {
+		extract = Untar
+	}
+	if err := extract(archivePath, tempExtractDir); err != nil {
+		fmt.Printf("Failed to extract Java archive: %v\n", err)
+		os.RemoveAll(tempExtractDir) // Clean up
+		return "", "", false
+	}
+
+	// Find the extracted directory (it usually has a name like jdk-17.0.11+9,
+	// jdk8u412-b08 or jdk-17.0.11+9-jre)
+	extractedItems, _ := os.ReadDir(tempExtractDir)
+	var jdkDir string
+	for _, item := range extractedItems {
+		if item.IsDir() && strings.HasPrefix(item.Name(), "jdk") {
+			jdkDir = filepath.Join(tempExtractDir, item.Name())
+			break
+		}
+	}
+
+	if jdkDir == "" {
+		fmt.Println("Failed to find JDK directory in extracted files.")
+		os.RemoveAll(tempExtractDir) // Clean up
+		return "", "", false
+	}
+
+	return tempExtractDir, jdkDir, true
+}
+
+// FetchChecksum retrieves the published SHA-256 checksum of an archive
+func FetchChecksum(archiveURL string) (string, error) {
+	content, err := GetRemoteTextFile(archiveURL + ".sha256.txt")
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file")
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// FileChecksum returns the hex-encoded SHA-256 checksum of a file
+func FileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyChecksum returns an error unless the file has the expected SHA-256 checksum
+func VerifyChecksum(path string, expected string) error {
+	actual, err := FileChecksum(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", filepath.Base(path), expected, actual)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"jdkvm/arch"
+)
+
+// Mirror rewrites catalog URLs so archives are fetched from somewhere else.
+// URLs starting with Prefix are rewritten with Template, whose placeholders
+// are filled in from the archive being downloaded:
+//
+//	{path}     the rest of the URL after Prefix
+//	{file}     the archive file name
+//	{major}    the feature release, e.g. 17
+//	{version}  the full version, e.g. 17.0.11+9
+//	{image}    jdk, jre, ...
+//	{os}       windows, linux, alpine-linux, mac
+//	{arch}     x64, x32, aarch64, ...
+type Mirror struct {
+	Prefix   string
+	Template string
+}
+
+// Artifact describes the archive behind a catalog URL, for mirror templates
+type Artifact struct {
+	Major   string
+	Version string
+	OS      string
+	Arch    string
+	Image   string
+}
+
+// mirrorPresets are well-known mirrors that cannot be expressed as a plain base URL
+var mirrorPresets = map[string]Mirror{
+	"tuna": {Prefix: "https://github.com/adoptium/", Template: "https://mirrors.tuna.tsinghua.edu.cn/Adoptium/{major}/{image}/{arch}/{os}/{file}"},
+}
+
+// javaMirrors are tried in order before the catalog URL itself
+var javaMirrors []Mirror
+
+// ParseMirror parses one mirror entry. An entry is a preset name ("tuna"), a
+// rule "<prefix> => <template>", or a base URL that replaces the scheme and
+// host of catalog URLs and keeps their path, which is the layout jdkvm serve uses.
+func ParseMirror(entry string) (Mirror, error) {
+	entry = strings.TrimSpace(entry)
+	if preset, ok := mirrorPresets[strings.ToLower(entry)]; ok {
+		return preset, nil
+	}
+
+	if prefix, template, ok := strings.Cut(entry, "=>"); ok {
+		prefix, template = strings.TrimSpace(prefix), strings.TrimSpace(template)
+		if prefix == "" || template == "" {
+			return Mirror{}, fmt.Errorf("invalid mirror rule %q: expected <prefix> => <template>", entry)
+		}
+		return Mirror{Prefix: prefix, Template: template}, nil
+	}
+
+	if !strings.HasPrefix(strings.ToLower(entry), "http") {
+		entry = "http://" + entry
+	}
+	base, err := url.Parse(entry)
+	if err != nil || base.Host == "" {
+		return Mirror{}, fmt.Errorf("invalid mirror URL %q", entry)
+	}
+	return Mirror{Template: strings.TrimSuffix(entry, "/") + "{path}"}, nil
+}
+
+// SetJavaMirror configures the mirrors from the java_mirror setting, a comma
+// separated list of entries tried in order. "none" or an empty value removes them.
+func SetJavaMirror(mirror string) error {
+	javaMirrors = nil
+	if mirror == "" || mirror == "none" {
+		return nil
+	}
+
+	for _, entry := range strings.Split(mirror, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		m, err := ParseMirror(entry)
+		if err != nil {
+			return err
+		}
+		javaMirrors = append(javaMirrors, m)
+	}
+	return nil
+}
+
+// Rewrite returns the mirror URL of a catalog URL, or false when the mirror
+// does not cover it
+func (m Mirror) Rewrite(u string, a Artifact) (string, bool) {
+	rest := ""
+	if m.Prefix == "" {
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Host == "" {
+			return "", false
+		}
+		rest = parsed.EscapedPath()
+	} else if strings.HasPrefix(u, m.Prefix) {
+		rest = strings.TrimPrefix(u, m.Prefix)
+	} else {
+		return "", false
+	}
+
+	apiArch := a.Arch
+	if apiArch == arch.X86 {
+		apiArch = "x32"
+	}
+	replacer := strings.NewReplacer(
+		"{path}", rest,
+		"{file}", path.Base(u),
+		"{major}", a.Major,
+		"{version}", a.Version,
+		"{image}", a.Image,
+		"{os}", a.OS,
+		"{arch}", apiArch,
+	)
+	return replacer.Replace(m.Template), true
+}
+
+// MirrorURLs returns the URLs to try for a catalog URL: every configured
+// mirror that covers it, in order, followed by the catalog URL itself. Rules
+// covering the same URL are tried longest prefix first, so a specific rule
+// refines a preset wherever it is listed; base URLs keep their place.
+func MirrorURLs(u string, a Artifact) []string {
+	var covering []Mirror
+	var rules []int
+	for _, m := range javaMirrors {
+		if _, ok := m.Rewrite(u, a); ok {
+			if m.Prefix != "" {
+				rules = append(rules, len(covering))
+			}
+			covering = append(covering, m)
+		}
+	}
+
+	ordered := make([]Mirror, len(rules))
+	for i, at := range rules {
+		ordered[i] = covering[at]
+	}
+	sort.SliceStable(ordered, func(i, j int) bool { return len(ordered[i].Prefix) > len(ordered[j].Prefix) })
+	for i, at := range rules {
+		covering[at] = ordered[i]
+	}
+
+	urls := make([]string, 0, len(covering)+1)
+	for _, m := range covering {
+		rewritten, _ := m.Rewrite(u, a)
+		urls = append(urls, rewritten)
+	}
+	return append(urls, u)
+}
@@ -0,0 +1,121 @@
+package web
+
+import (
+	"reflect"
+	"testing"
+
+	"jdkvm/arch"
+)
+
+const (
+	temurinURL = "https://github.com/adoptium/temurin17-binaries/releases/download/jdk-17.0.11%2B9/OpenJDK17U-jdk_x64_linux_hotspot_17.0.11_9.tar.gz"
+	zuluURL    = "https://cdn.azul.com/zulu/bin/zulu17.50.19-ca-jdk17.0.11-linux_x64.tar.gz"
+)
+
+var temurin17 = Artifact{Major: "17", Version: "17.0.11+9", OS: OSLinux, Arch: arch.X64, Image: "jdk"}
+
+func TestParseMirror(t *testing.T) {
+	tests := []struct {
+		entry   string
+		want    Mirror
+		wantErr bool
+	}{
+		{"tuna", mirrorPresets["tuna"], false},
+		{" TUNA ", mirrorPresets["tuna"], false},
+		{"http://lan-host:8080/", Mirror{Template: "http://lan-host:8080{path}"}, false},
+		{"lan-host:8080", Mirror{Template: "http://lan-host:8080{path}"}, false},
+		{"https://github.com/ => https://mirror.example.com/gh/{path}", Mirror{Prefix: "https://github.com/", Template: "https://mirror.example.com/gh/{path}"}, false},
+		{"https://github.com/ =>", Mirror{}, true},
+		{"=> https://mirror.example.com/{file}", Mirror{}, true},
+		{"http://", Mirror{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMirror(tt.entry)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMirror(%q) error = %v, wantErr %v", tt.entry, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMirror(%q) = %+v, want %+v", tt.entry, got, tt.want)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	x86 := temurin17
+	x86.Arch = arch.X86
+	tests := []struct {
+		name   string
+		mirror string
+		url    string
+		a      Artifact
+		want   string
+		wantOK bool
+	}{
+		{"tuna layout", "tuna", temurinURL, temurin17,
+			"https://mirrors.tuna.tsinghua.edu.cn/Adoptium/17/jdk/x64/linux/OpenJDK17U-jdk_x64_linux_hotspot_17.0.11_9.tar.gz", true},
+		{"tuna names x86 x32", "tuna", temurinURL, x86,
+			"https://mirrors.tuna.tsinghua.edu.cn/Adoptium/17/jdk/x32/linux/OpenJDK17U-jdk_x64_linux_hotspot_17.0.11_9.tar.gz", true},
+		{"tuna does not cover other vendors", "tuna", zuluURL, temurin17, "", false},
+		{"base URL keeps the path", "http://lan-host:8080", temurinURL, temurin17,
+			"http://lan-host:8080/adoptium/temurin17-binaries/releases/download/jdk-17.0.11%2B9/OpenJDK17U-jdk_x64_linux_hotspot_17.0.11_9.tar.gz", true},
+		{"base URL covers every vendor", "http://lan-host:8080/", zuluURL, temurin17,
+			"http://lan-host:8080/zulu/bin/zulu17.50.19-ca-jdk17.0.11-linux_x64.tar.gz", true},
+		{"rule keeps the rest of the URL", "https://github.com/adoptium/ => https://mirror.example.com/adoptium/{path}", temurinURL, temurin17,
+			"https://mirror.example.com/adoptium/temurin17-binaries/releases/download/jdk-17.0.11%2B9/OpenJDK17U-jdk_x64_linux_hotspot_17.0.11_9.tar.gz", true},
+		{"every placeholder", "https://github.com/ => https://m/{major}/{version}/{image}/{os}/{arch}/{file}", temurinURL, temurin17,
+			"https://m/17/17.0.11+9/jdk/linux/x64/OpenJDK17U-jdk_x64_linux_hotspot_17.0.11_9.tar.gz", true},
+		{"rule prefix must match", "https://github.com/openjdk/ => https://m/{file}", temurinURL, temurin17, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMirror(tt.mirror)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := m.Rewrite(tt.url, tt.a)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Rewrite = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMirrorURLs(t *testing.T) {
+	t.Cleanup(func() { javaMirrors = nil })
+	tuna := "https://mirrors.tuna.tsinghua.edu.cn/Adoptium/17/jdk/x64/linux/OpenJDK17U-jdk_x64_linux_hotspot_17.0.11_9.tar.gz"
+	lan := "http://lan-host:8080/adoptium/temurin17-binaries/releases/download/jdk-17.0.11%2B9/OpenJDK17U-jdk_x64_linux_hotspot_17.0.11_9.tar.gz"
+	specific := "https://github.com/adoptium/temurin17-binaries/ => https://m17/{file}"
+
+	tests := []struct {
+		name    string
+		setting string
+		url     string
+		want    []string
+	}{
+		{"no mirror", "", temurinURL, []string{temurinURL}},
+		{"none", "none", temurinURL, []string{temurinURL}},
+		{"mirrors in order before upstream", "http://lan-host:8080/,tuna", temurinURL, []string{lan, tuna, temurinURL}},
+		{"order is kept", "tuna,http://lan-host:8080/", temurinURL, []string{tuna, lan, temurinURL}},
+		{"mirrors that do not cover the URL are skipped", "tuna, ,http://lan-host:8080/", zuluURL,
+			[]string{"http://lan-host:8080/zulu/bin/zulu17.50.19-ca-jdk17.0.11-linux_x64.tar.gz", zuluURL}},
+		{"longest prefix first", "tuna," + specific, temurinURL,
+			[]string{"https://m17/OpenJDK17U-jdk_x64_linux_hotspot_17.0.11_9.tar.gz", tuna, temurinURL}},
+		{"base URLs keep their place", "http://lan-host:8080/,tuna," + specific, temurinURL,
+			[]string{lan, "https://m17/OpenJDK17U-jdk_x64_linux_hotspot_17.0.11_9.tar.gz", tuna, temurinURL}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetJavaMirror(tt.setting); err != nil {
+				t.Fatal(err)
+			}
+			if got := MirrorURLs(tt.url, temurin17); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MirrorURLs =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+
+	if err := SetJavaMirror("tuna,https://github.com/ =>"); err == nil {
+		t.Error("SetJavaMirror accepted an invalid rule")
+	}
+}
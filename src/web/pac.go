@@ -0,0 +1,237 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// pacRunner evaluates a proxy auto-config file. The JavaScript runtime is not
+// safe for concurrent use, so calls are serialized through pacMu.
+type pacRunner struct {
+	vm      *goja.Runtime
+	find    goja.Callable
+	results map[string]string
+}
+
+// pacTimeout bounds each run of PAC code, so a script that loops forever
+// fails the request instead of hanging jdkvm
+var pacTimeout = 5 * time.Second
+
+// pacHelpers implements the PAC functions that need no network access
+const pacHelpers = `
+function isPlainHostName(host) { return host.indexOf('.') < 0; }
+function dnsDomainIs(host, domain) {
+	return host.length >= domain.length && host.substring(host.length - domain.length) === domain;
+}
+function localHostOrDomainIs(host, hostdom) {
+	return host === hostdom || (host.indexOf('.') < 0 && hostdom.lastIndexOf(host + '.', 0) === 0);
+}
+function dnsDomainLevels(host) { return host.split('.').length - 1; }
+function isResolvable(host) { return dnsResolve(host) !== null; }
+function isInNet(host, pattern, mask) {
+	var ip = /^\d+\.\d+\.\d+\.\d+$/.test(host) ? host : dnsResolve(host);
+	if (ip === null) { return false; }
+	function num(a) { var p = a.split('.'); return ((+p[0] << 24) | (+p[1] << 16) | (+p[2] << 8) | +p[3]) >>> 0; }
+	return ((num(ip) & num(mask)) >>> 0) === ((num(pattern) & num(mask)) >>> 0);
+}
+function shExpMatch(str, pattern) {
+	var re = pattern.replace(/[.+^${}()|[\]\\]/g, '\\$&').replace(/\*/g, '.*').replace(/\?/g, '.');
+	return new RegExp('^' + re + '$').test(str);
+}
+function weekdayRange(start, end, gmt) {
+	var days = ['SUN', 'MON', 'TUE', 'WED', 'THU', 'FRI', 'SAT'];
+	if (end === 'GMT') { gmt = end; end = undefined; }
+	var now = new Date();
+	var today = gmt === 'GMT' ? now.getUTCDay() : now.getDay();
+	var s = days.indexOf(start), e = end === undefined ? s : days.indexOf(end);
+	return s <= e ? today >= s && today <= e : today >= s || today <= e;
+}
+function timeRange() {
+	var args = Array.prototype.slice.call(arguments);
+	var gmt = args[args.length - 1] === 'GMT';
+	if (gmt) { args.pop(); }
+	var now = new Date();
+	var h = gmt ? now.getUTCHours() : now.getHours();
+	var m = gmt ? now.getUTCMinutes() : now.getMinutes();
+	var s = gmt ? now.getUTCSeconds() : now.getSeconds();
+	var cur = h * 3600 + m * 60 + s, from, to;
+	if (args.length === 1) { return h === args[0]; }
+	if (args.length === 2) { from = args[0] * 3600; to = args[1] * 3600 + 3599; }
+	else if (args.length === 4) { from = args[0] * 3600 + args[1] * 60; to = args[2] * 3600 + args[3] * 60 + 59; }
+	else { from = args[0] * 3600 + args[1] * 60 + args[2]; to = args[3] * 3600 + args[4] * 60 + args[5]; }
+	return from <= to ? cur >= from && cur <= to : cur >= from || cur <= to;
+}
+function dateRange() { return true; }
+`
+
+// pacProxy asks the PAC file which proxy to use for a URL
+func pacProxy(target *url.URL, cfg ProxyConfig) (ProxyChoice, error) {
+	pacMu.Lock()
+	defer pacMu.Unlock()
+
+	// The file is loaded once; a failure is remembered rather than retried per request
+	if pacErr != nil {
+		return ProxyChoice{}, pacErr
+	}
+	if pacScript == nil {
+		runner, err := loadPAC(cfg.PAC)
+		if err != nil {
+			pacErr = err
+			return ProxyChoice{}, err
+		}
+		pacScript = runner
+	}
+
+	result, err := pacScript.evaluate(target)
+	if err != nil {
+		// A script that ran out of time once would hang every request
+		var interrupted *goja.InterruptedError
+		if errors.As(err, &interrupted) {
+			pacErr = err
+		}
+		return ProxyChoice{}, err
+	}
+	p, err := parsePACResult(result)
+	if err != nil {
+		return ProxyChoice{}, err
+	}
+	source := fmt.Sprintf("PAC file returned %q", result)
+	if p == nil {
+		return ProxyChoice{Source: source}, nil
+	}
+	return ProxyChoice{Proxy: withCredentials(p, cfg), Source: source}, nil
+}
+
+// loadPAC reads a PAC file from disk or over HTTP and compiles it
+func loadPAC(location string) (*pacRunner, error) {
+	script, err := readPAC(location)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := goja.New()
+	vm.Set("dnsResolve", func(host string) goja.Value {
+		addrs, err := net.LookupIP(host)
+		if err != nil {
+			return goja.Null()
+		}
+		for _, a := range addrs {
+			if ip4 := a.To4(); ip4 != nil {
+				return vm.ToValue(ip4.String())
+			}
+		}
+		return goja.Null()
+	})
+	vm.Set("myIpAddress", func() string {
+		// The source address of an outgoing UDP socket; nothing is sent
+		conn, err := net.Dial("udp", "198.51.100.1:80")
+		if err != nil {
+			return "127.0.0.1"
+		}
+		defer conn.Close()
+		return conn.LocalAddr().(*net.UDPAddr).IP.String()
+	})
+	if _, err := vm.RunString(pacHelpers); err != nil {
+		return nil, err
+	}
+	if err := runPAC(vm, func() (err error) {
+		_, err = vm.RunString(script)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("invalid PAC file: %v", err)
+	}
+	find, ok := goja.AssertFunction(vm.Get("FindProxyForURL"))
+	if !ok {
+		return nil, fmt.Errorf("PAC file does not define FindProxyForURL")
+	}
+	return &pacRunner{vm: vm, find: find, results: map[string]string{}}, nil
+}
+
+// runPAC runs PAC code, interrupting it once pacTimeout has passed
+func runPAC(vm *goja.Runtime, run func() error) error {
+	timer := time.AfterFunc(pacTimeout, func() {
+		vm.Interrupt(fmt.Sprintf("timed out after %v", pacTimeout))
+	})
+	err := run()
+	timer.Stop()
+	vm.ClearInterrupt()
+	return err
+}
+
+// readPAC fetches a PAC file. Remote files are fetched directly, never
+// through a proxy, since the PAC file is what decides the proxy.
+func readPAC(location string) (string, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		content, err := os.ReadFile(strings.TrimPrefix(location, "file://"))
+		return string(content), err
+	}
+
+	config, err := tlsConfig(tlsOptions)
+	if err != nil {
+		return "", err
+	}
+	direct := &http.Client{Transport: &http.Transport{TLSClientConfig: config}, Timeout: 30 * time.Second}
+	response, err := direct.Get(location)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != 200 {
+		return "", fmt.Errorf("HTTP status %d", response.StatusCode)
+	}
+	content, err := io.ReadAll(response.Body)
+	return string(content), err
+}
+
+// evaluate calls FindProxyForURL, remembering the answer for each host
+func (r *pacRunner) evaluate(target *url.URL) (string, error) {
+	key := target.Scheme + "://" + target.Host
+	if result, ok := r.results[key]; ok {
+		return result, nil
+	}
+	var value goja.Value
+	err := runPAC(r.vm, func() (err error) {
+		value, err = r.find(goja.Undefined(), r.vm.ToValue(target.String()), r.vm.ToValue(target.Hostname()))
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("FindProxyForURL failed: %w", err)
+	}
+	result := strings.TrimSpace(value.String())
+	r.results[key] = result
+	return result, nil
+}
+
+// parsePACResult turns the first entry of a PAC answer such as
+// "PROXY proxy:8080; DIRECT" into a proxy URL; nil means DIRECT
+func parsePACResult(result string) (*url.URL, error) {
+	first := strings.TrimSpace(strings.Split(result, ";")[0])
+	fields := strings.Fields(first)
+	if len(fields) == 0 || strings.EqualFold(fields[0], "DIRECT") {
+		return nil, nil
+	}
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("unrecognised PAC result %q", first)
+	}
+
+	scheme := map[string]string{
+		"PROXY":  "http",
+		"HTTP":   "http",
+		"HTTPS":  "https",
+		"SOCKS":  "socks5",
+		"SOCKS5": "socks5",
+	}[strings.ToUpper(fields[0])]
+	if scheme == "" {
+		return nil, fmt.Errorf("unsupported PAC proxy type %q", fields[0])
+	}
+	return ParseProxyURL(scheme + "://" + fields[1])
+}
@@ -0,0 +1,150 @@
+package web
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePACResult(t *testing.T) {
+	tests := []struct {
+		result  string
+		want    string
+		wantErr bool
+	}{
+		{"DIRECT", "", false},
+		{"", "", false},
+		{"direct; PROXY proxy:8080", "", false},
+		{"PROXY proxy.corp:8080", "http://proxy.corp:8080", false},
+		{"PROXY proxy.corp:8080; DIRECT", "http://proxy.corp:8080", false},
+		{"  HTTPS secure.corp:443 ;PROXY other:80", "https://secure.corp:443", false},
+		{"SOCKS socks.corp:1080", "socks5://socks.corp:1080", false},
+		{"SOCKS5 socks.corp:1080", "socks5://socks.corp:1080", false},
+		{"PROXY", "", true},
+		{"PROXY a:1 b:2", "", true},
+		{"QUIC proxy:443", "", true},
+	}
+	for _, tt := range tests {
+		p, err := parsePACResult(tt.result)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePACResult(%q) error = %v, wantErr %v", tt.result, err, tt.wantErr)
+			continue
+		}
+		got := ""
+		if p != nil {
+			got = p.String()
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("parsePACResult(%q) = %q, want %q", tt.result, got, tt.want)
+		}
+	}
+}
+
+func writePAC(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "proxy.pac")
+	if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// resetPAC forgets the loaded PAC file, as SetProxy does
+func resetPAC(t *testing.T) {
+	pacScript, pacErr, pacWarned = nil, nil, false
+	t.Cleanup(func() { pacScript, pacErr, pacWarned = nil, nil, false })
+}
+
+func TestPACProxy(t *testing.T) {
+	resetPAC(t)
+	cfg := ProxyConfig{PAC: writePAC(t, `
+function FindProxyForURL(url, host) {
+	if (isPlainHostName(host) || dnsDomainIs(host, ".corp.example")) { return "DIRECT"; }
+	if (isInNet(host, "10.0.0.0", "255.0.0.0")) { return "PROXY inner:3128"; }
+	if (shExpMatch(url, "https://*.adoptium.net/*")) { return "PROXY adoptium-proxy:8080; DIRECT"; }
+	return "PROXY default:3128";
+}`)}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://intranet/x", ""},
+		{"https://repo.corp.example/jdk.tar.gz", ""},
+		{"http://10.1.2.3/jdk.zip", "http://inner:3128"},
+		{"https://api.adoptium.net/v3/info", "http://adoptium-proxy:8080"},
+		{"https://github.com/adoptium", "http://default:3128"},
+	}
+	for _, tt := range tests {
+		target, _ := url.Parse(tt.url)
+		choice, err := pacProxy(target, cfg)
+		if err != nil {
+			t.Fatalf("pacProxy(%s): %v", tt.url, err)
+		}
+		got := ""
+		if choice.Proxy != nil {
+			got = choice.Proxy.String()
+		}
+		if got != tt.want {
+			t.Errorf("pacProxy(%s) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestPACErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"syntax error", "function FindProxyForURL(url, host) {", "invalid PAC file"},
+		{"no function", "var x = 1;", "does not define FindProxyForURL"},
+		{"throws", "function FindProxyForURL(url, host) { throw new Error('boom'); }", "boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetPAC(t)
+			target, _ := url.Parse("https://github.com/")
+			_, err := pacProxy(target, ProxyConfig{PAC: writePAC(t, tt.script)})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("pacProxy error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPACTimeout(t *testing.T) {
+	resetPAC(t)
+	saved := pacTimeout
+	pacTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { pacTimeout = saved })
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"loop in FindProxyForURL", "function FindProxyForURL(url, host) { while (true) {} }"},
+		{"loop at load", "while (true) {}\nfunction FindProxyForURL(url, host) { return 'DIRECT'; }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetPAC(t)
+			cfg := ProxyConfig{PAC: writePAC(t, tt.script)}
+			target, _ := url.Parse("https://github.com/")
+			start := time.Now()
+			if _, err := pacProxy(target, cfg); err == nil || !strings.Contains(err.Error(), "timed out") {
+				t.Fatalf("pacProxy error = %v, want a timeout", err)
+			}
+			// The script is not run again once it has timed out
+			if _, err := pacProxy(target, cfg); err == nil {
+				t.Fatal("pacProxy succeeded after a timeout")
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("pacProxy took %v", elapsed)
+			}
+		})
+	}
+}
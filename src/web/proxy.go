@@ -0,0 +1,222 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ProxyDirect in the proxy setting bypasses every proxy, including the ones
+// from the environment and PAC files
+const ProxyDirect = "direct"
+
+// ProxyConfig describes where downloads find their proxy. A URL from the
+// settings wins over a PAC file, which wins over the standard environment
+// variables (HTTPS_PROXY, HTTP_PROXY, ALL_PROXY).
+type ProxyConfig struct {
+	// URL is an http://, https://, socks5:// or socks5h:// proxy, "direct",
+	// or "" / "none" when no proxy is configured
+	URL string
+	// NoProxy is a comma-separated list of hosts reached directly, added to NO_PROXY
+	NoProxy string
+	// PAC is the URL or path of a proxy auto-config file
+	PAC string
+	// Username and Password are sent to proxies whose URL carries no credentials
+	Username string
+	Password string
+}
+
+// ProxyChoice is the proxy picked for a URL and why
+type ProxyChoice struct {
+	Proxy  *url.URL
+	Source string
+}
+
+// String describes the choice without revealing passwords
+func (c ProxyChoice) String() string {
+	if c.Proxy == nil {
+		return "DIRECT (" + c.Source + ")"
+	}
+	return c.Proxy.Redacted() + " (" + c.Source + ")"
+}
+
+var (
+	proxyConfig ProxyConfig
+	pacScript   *pacRunner
+	pacErr      error
+	pacWarned   bool
+	pacMu       sync.Mutex
+)
+
+// SetProxy applies a proxy configuration to every download
+func SetProxy(cfg ProxyConfig) error {
+	if cfg.URL != "" && cfg.URL != "none" && cfg.URL != ProxyDirect {
+		if _, err := ParseProxyURL(cfg.URL); err != nil {
+			return err
+		}
+	}
+
+	pacMu.Lock()
+	proxyConfig = cfg
+	pacScript, pacErr, pacWarned = nil, nil, false
+	pacMu.Unlock()
+	return buildClient()
+}
+
+// ProxyFor returns the proxy a request to rawURL goes through
+func ProxyFor(rawURL string) (ProxyChoice, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Host == "" {
+		return ProxyChoice{}, fmt.Errorf("invalid URL %q", rawURL)
+	}
+	return resolveProxy(target)
+}
+
+// proxyFunc is the Proxy hook of the shared transport
+func proxyFunc(req *http.Request) (*url.URL, error) {
+	choice, err := resolveProxy(req.URL)
+	return choice.Proxy, err
+}
+
+func resolveProxy(target *url.URL) (ProxyChoice, error) {
+	cfg := proxyConfig
+
+	if cfg.URL == ProxyDirect {
+		return ProxyChoice{Source: "proxy=direct"}, nil
+	}
+	if entry, ok := bypassEntry(target, cfg.NoProxy); ok {
+		return ProxyChoice{Source: "matches no_proxy entry " + entry}, nil
+	}
+
+	if cfg.URL != "" && cfg.URL != "none" {
+		p, err := ParseProxyURL(cfg.URL)
+		if err != nil {
+			return ProxyChoice{}, err
+		}
+		return ProxyChoice{Proxy: withCredentials(p, cfg), Source: "proxy setting"}, nil
+	}
+
+	if cfg.PAC != "" {
+		choice, err := pacProxy(target, cfg)
+		if err == nil {
+			return choice, nil
+		}
+		// A broken PAC file should not stop downloads that work without it
+		pacMu.Lock()
+		if !pacWarned {
+			warnf("Ignoring PAC file %s: %v", cfg.PAC, err)
+			pacWarned = true
+		}
+		pacMu.Unlock()
+	}
+
+	return environmentProxy(target, cfg)
+}
+
+// environmentProxy reads the proxy variables curl and most tools understand
+func environmentProxy(target *url.URL, cfg ProxyConfig) (ProxyChoice, error) {
+	names := []string{"HTTP_PROXY", "ALL_PROXY"}
+	if target.Scheme == "https" {
+		names = []string{"HTTPS_PROXY", "ALL_PROXY"}
+	}
+	for _, name := range names {
+		value := getenvAnyCase(name)
+		if value == "" {
+			continue
+		}
+		p, err := ParseProxyURL(value)
+		if err != nil {
+			return ProxyChoice{}, fmt.Errorf("%s: %v", name, err)
+		}
+		return ProxyChoice{Proxy: withCredentials(p, cfg), Source: name + " environment variable"}, nil
+	}
+	return ProxyChoice{Source: "no proxy configured"}, nil
+}
+
+// ParseProxyURL accepts proxy URLs with or without a scheme; a bare
+// "host:port" means an HTTP proxy
+func ParseProxyURL(p string) (*url.URL, error) {
+	if !strings.Contains(p, "://") {
+		p = "http://" + p
+	}
+	u, err := url.Parse(p)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", p)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return u, nil
+	}
+	return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https, socks5 or socks5h)", u.Scheme)
+}
+
+// withCredentials adds the stored credentials to a proxy URL that has none
+func withCredentials(p *url.URL, cfg ProxyConfig) *url.URL {
+	if p.User != nil || cfg.Username == "" {
+		return p
+	}
+	withUser := *p
+	withUser.User = url.UserPassword(cfg.Username, cfg.Password)
+	return &withUser
+}
+
+// bypassEntry reports the NO_PROXY or no_proxy entry that matches the target, if any
+func bypassEntry(target *url.URL, extra string) (string, bool) {
+	entries := strings.Split(getenvAnyCase("NO_PROXY")+","+extra, ",")
+	host := target.Hostname()
+	port := target.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[target.Scheme]
+	}
+
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if matchesBypass(strings.ToLower(host), port, entry) {
+			return entry, true
+		}
+	}
+	return "", false
+}
+
+// matchesBypass matches a host against one NO_PROXY entry: "*", a domain
+// (".example.com" or "example.com", both covering subdomains), an IP address
+// or a CIDR range, each optionally followed by ":port"
+func matchesBypass(host string, port string, entry string) bool {
+	if entry == "*" {
+		return true
+	}
+
+	if _, network, err := net.ParseCIDR(entry); err == nil {
+		ip := net.ParseIP(host)
+		return ip != nil && network.Contains(ip)
+	}
+
+	if h, p, err := net.SplitHostPort(entry); err == nil {
+		if p != port {
+			return false
+		}
+		entry = h
+	}
+	entry = strings.Trim(entry, "[]")
+
+	if ip := net.ParseIP(entry); ip != nil {
+		return ip.Equal(net.ParseIP(host))
+	}
+	domain := strings.TrimPrefix(entry, "*")
+	domain = strings.TrimPrefix(domain, ".")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+func getenvAnyCase(name string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return os.Getenv(strings.ToLower(name))
+}
@@ -0,0 +1,448 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"jdkvm/arch"
+	"jdkvm/java"
+)
+
+// CatalogSchemaVersion is the catalog format this release writes. Older
+// formats are upgraded when read; newer ones are refused.
+const CatalogSchemaVersion = 2
+
+// Package types an artifact can have. Only archives can be installed.
+const (
+	PackageZip   = "zip"
+	PackageTarGz = "tar.gz"
+	PackageMSI   = "msi"
+	PackagePKG   = "pkg"
+)
+
+// Catalog is the v2 catalog: every feature release with its releases,
+// newest first, and the artifacts published for each
+type Catalog struct {
+	SchemaVersion int `json:"schema_version"`
+	// Published is when the catalog was issued (RFC 3339). Signed catalogs
+	// must have it, so an older one can never replace a newer one.
+	Published string                    `json:"published,omitempty"`
+	Versions  map[string]FeatureRelease `json:"versions"`
+}
+
+// FeatureRelease describes a feature release such as 17
+type FeatureRelease struct {
+	Vendor string `json:"vendor,omitempty"`
+	// LTS marks long-term support feature releases
+	LTS bool `json:"lts,omitempty"`
+	// EOL is the date (YYYY-MM-DD) the vendor stops supporting the feature release
+	EOL      string    `json:"eol,omitempty"`
+	Releases []Release `json:"releases"`
+}
+
+// Release is one update of a feature release, e.g. 17.0.11+9
+type Release struct {
+	Version string `json:"version"`
+	// Vendor overrides the feature release's vendor, so several vendors'
+	// builds of a feature release can be listed side by side
+	Vendor    string            `json:"vendor,omitempty"`
+	Artifacts []CatalogArtifact `json:"artifacts"`
+}
+
+// VendorOf returns the vendor of a release of the feature release
+func (fr FeatureRelease) VendorOf(r Release) string {
+	if r.Vendor != "" {
+		return r.Vendor
+	}
+	if fr.Vendor != "" {
+		return fr.Vendor
+	}
+	return java.DefaultVendor
+}
+
+// CatalogArtifact is one downloadable build of a release
+type CatalogArtifact struct {
+	OS           string `json:"os"`
+	Arch         string `json:"arch"`
+	Image        string `json:"image"`
+	Package      string `json:"package"`
+	URL          string `json:"url"`
+	Size         int64  `json:"size,omitempty"`
+	SHA256       string `json:"sha256,omitempty"`
+	SignatureURL string `json:"signature_url,omitempty"`
+}
+
+// Key identifies the artifact within its release
+func (a CatalogArtifact) Key() string {
+	return a.OS + "/" + a.Arch + "/" + a.Image + "/" + a.Package
+}
+
+// catalogV1Entry is a feature release in the schema 1 catalog, which had one
+// release per feature release and builds keyed "<os>/<arch>[/<image>]"
+type catalogV1Entry struct {
+	Latest string            `json:"latest"`
+	URL    string            `json:"url"`
+	Short  string            `json:"short"`
+	Vendor string            `json:"vendor,omitempty"`
+	LTS    bool              `json:"lts,omitempty"`
+	EOL    string            `json:"eol,omitempty"`
+	URLs   map[string]string `json:"urls,omitempty"`
+}
+
+// ParseCatalog decodes a catalog of any supported schema version, upgrading
+// older ones, and validates it
+func ParseCatalog(content []byte) (Catalog, int, error) {
+	catalog, version, err := DecodeCatalog(content)
+	if err != nil {
+		return catalog, version, err
+	}
+	if errs := ValidateCatalog(catalog); len(errs) > 0 {
+		if len(errs) > 1 {
+			return catalog, version, fmt.Errorf("invalid catalog: %v (and %d more problems)", errs[0], len(errs)-1)
+		}
+		return catalog, version, fmt.Errorf("invalid catalog: %v", errs[0])
+	}
+	return catalog, version, nil
+}
+
+// DecodeCatalog decodes a catalog of any supported schema version without
+// validating it. It returns the schema version the content was written in.
+func DecodeCatalog(content []byte) (Catalog, int, error) {
+	header := struct {
+		SchemaVersion int `json:"schema_version"`
+	}{}
+	if err := json.Unmarshal(content, &header); err != nil {
+		return Catalog{}, 0, err
+	}
+
+	var catalog Catalog
+	version := header.SchemaVersion
+	switch {
+	case version == 0:
+		// Schema 1 had no version field
+		version = 1
+		v1 := map[string]catalogV1Entry{}
+		if err := json.Unmarshal(content, &v1); err != nil {
+			return Catalog{}, version, err
+		}
+		catalog = UpgradeCatalogV1(v1)
+	case version == CatalogSchemaVersion:
+		if err := json.Unmarshal(content, &catalog); err != nil {
+			return Catalog{}, version, err
+		}
+	default:
+		return Catalog{}, version, fmt.Errorf("catalog schema version %d is not supported by this jdkvm (up to %d)", version, CatalogSchemaVersion)
+	}
+	return catalog, version, nil
+}
+
+// UpgradeCatalogV1 converts a schema 1 catalog to the current schema
+func UpgradeCatalogV1(v1 map[string]catalogV1Entry) Catalog {
+	catalog := Catalog{SchemaVersion: CatalogSchemaVersion, Versions: map[string]FeatureRelease{}}
+	for major, entry := range v1 {
+		// Without per-build URLs, an entry only listed the Windows x64 build
+		var artifacts []CatalogArtifact
+		if len(entry.URLs) == 0 && entry.URL != "" {
+			artifacts = append(artifacts, CatalogArtifact{OS: OSWindows, Arch: arch.X64, Image: java.ImageJDK, Package: packageOf(entry.URL), URL: entry.URL})
+		}
+		for key, u := range entry.URLs {
+			parts := strings.Split(key, "/")
+			image := java.ImageJDK
+			if len(parts) == 3 {
+				image = parts[2]
+			}
+			if len(parts) < 2 {
+				continue
+			}
+			artifacts = append(artifacts, CatalogArtifact{OS: parts[0], Arch: parts[1], Image: image, Package: packageOf(u), URL: u})
+		}
+		sortArtifacts(artifacts)
+
+		catalog.Versions[major] = FeatureRelease{
+			Vendor:   entry.Vendor,
+			LTS:      entry.LTS,
+			EOL:      entry.EOL,
+			Releases: []Release{{Version: entry.Latest, Artifacts: artifacts}},
+		}
+	}
+	return catalog
+}
+
+// Mapping returns the newest release of every feature release from its
+// vendor
+func (c Catalog) Mapping() map[string]JavaVersionInfo {
+	mapping := make(map[string]JavaVersionInfo, len(c.Versions))
+	for major := range c.Versions {
+		if info, ok := c.FindRelease(major, ""); ok {
+			mapping[major] = info
+		}
+	}
+	return mapping
+}
+
+// Releases returns the releases of a feature release, newest first. An empty
+// vendor lists every vendor's.
+func (c Catalog) Releases(major string, vendor string) []JavaVersionInfo {
+	fr := c.Versions[major]
+	var releases []JavaVersionInfo
+	for _, r := range fr.Releases {
+		if vendor != "" && fr.VendorOf(r) != vendor {
+			continue
+		}
+		releases = append(releases, JavaVersionInfo{
+			Latest:    r.Version,
+			Short:     major,
+			Vendor:    fr.VendorOf(r),
+			LTS:       fr.LTS,
+			EOL:       fr.EOL,
+			Artifacts: r.Artifacts,
+		})
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		return java.Compare(releases[i].Latest, releases[j].Latest) > 0
+	})
+	return releases
+}
+
+// FindRelease returns the newest release matching a requested version: a
+// feature release ("17"), an exact release ("17.0.9+9") or one without its
+// build ("17.0.9"). Without a vendor, the feature release's own vendor is
+// preferred.
+func (c Catalog) FindRelease(version string, vendor string) (JavaVersionInfo, bool) {
+	major := java.Major(version)
+	if vendor == "" {
+		if fr, ok := c.Versions[major]; ok && len(fr.Releases) > 0 {
+			if info, ok := c.FindRelease(version, fr.VendorOf(Release{})); ok {
+				return info, true
+			}
+		}
+	}
+	for _, info := range c.Releases(major, vendor) {
+		if java.Matches(info.Latest, version) {
+			return info, true
+		}
+	}
+	return JavaVersionInfo{}, false
+}
+
+// Vendors returns the vendors the catalog has builds from
+func (c Catalog) Vendors() []string {
+	var vendors []string
+	for _, fr := range c.Versions {
+		for _, r := range fr.Releases {
+			if !containsString(vendors, fr.VendorOf(r)) {
+				vendors = append(vendors, fr.VendorOf(r))
+			}
+		}
+	}
+	sort.Strings(vendors)
+	return vendors
+}
+
+// Checksum returns the SHA-256 the catalog lists for a package of a vendor's
+// release, or "" when the catalog does not have it
+func (c Catalog) Checksum(version string, vendor string, osName string, a string, image string, pkg string) string {
+	artifact, _ := c.Artifact(version, vendor, osName, a, image, pkg)
+	return artifact.SHA256
+}
+
+// Artifact returns the catalog entry of a package of a vendor's release
+func (c Catalog) Artifact(version string, vendor string, osName string, a string, image string, pkg string) (CatalogArtifact, bool) {
+	for _, r := range c.Releases(java.Major(version), vendor) {
+		if r.Latest != version {
+			continue
+		}
+		for _, artifact := range r.Artifacts {
+			if artifact.OS == osName && artifact.Arch == a && artifact.Image == image && artifact.Package == pkg {
+				return artifact, true
+			}
+		}
+	}
+	return CatalogArtifact{}, false
+}
+
+var (
+	sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	majorPattern  = regexp.MustCompile(`^[0-9]+$`)
+	catalogOSes   = []string{OSWindows, OSMac, OSLinux, OSAlpine}
+	packages      = []string{PackageZip, PackageTarGz, PackageMSI, PackagePKG}
+)
+
+// ValidateCatalog checks a catalog against the schema and returns every
+// problem, each prefixed with the path of the offending field
+func ValidateCatalog(c Catalog) []error {
+	var errs []error
+	fail := func(path string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if c.SchemaVersion != CatalogSchemaVersion {
+		fail("schema_version", "must be %d, got %d", CatalogSchemaVersion, c.SchemaVersion)
+	}
+	if c.Published != "" {
+		if _, err := time.Parse(time.RFC3339, c.Published); err != nil {
+			fail("published", "must be an RFC 3339 time such as 2024-05-01T00:00:00Z, got %q", c.Published)
+		}
+	}
+	if len(c.Versions) == 0 {
+		fail("versions", "the catalog has no feature releases")
+	}
+
+	majors := make([]string, 0, len(c.Versions))
+	for major := range c.Versions {
+		majors = append(majors, major)
+	}
+	sort.Strings(majors)
+
+	for _, major := range majors {
+		fr := c.Versions[major]
+		path := "versions." + major
+		if !majorPattern.MatchString(major) {
+			fail(path, "feature release keys must be numbers like \"17\"")
+		}
+		if fr.Vendor != "" && !java.ValidVendor(fr.Vendor) {
+			fail(path+".vendor", "must be a lower-case name like \"temurin\", got %q", fr.Vendor)
+		}
+		if fr.EOL != "" {
+			if _, err := time.Parse("2006-01-02", fr.EOL); err != nil {
+				fail(path+".eol", "must be a YYYY-MM-DD date, got %q", fr.EOL)
+			}
+		}
+		if len(fr.Releases) == 0 {
+			fail(path+".releases", "must list at least one release")
+		}
+
+		// Releases are listed newest first, per vendor
+		previous := map[string]string{}
+		for i, r := range fr.Releases {
+			rpath := fmt.Sprintf("%s.releases[%d]", path, i)
+			if r.Vendor != "" && !java.ValidVendor(r.Vendor) {
+				fail(rpath+".vendor", "must be a lower-case name like \"temurin\", got %q", r.Vendor)
+			}
+			if r.Version == "" {
+				fail(rpath+".version", "is required")
+			} else if !java.ValidVersion(r.Version) {
+				fail(rpath+".version", "%q is not a version like 17.0.11+9", r.Version)
+			} else if java.Major(r.Version) != major {
+				fail(rpath+".version", "%q does not belong to feature release %s", r.Version, major)
+			}
+			if prev, ok := previous[fr.VendorOf(r)]; ok && java.Compare(prev, r.Version) <= 0 {
+				fail(rpath+".version", "releases must be listed newest first")
+			}
+			previous[fr.VendorOf(r)] = r.Version
+			if len(r.Artifacts) == 0 {
+				fail(rpath+".artifacts", "must list at least one artifact")
+			}
+
+			seen := map[string]bool{}
+			for j, a := range r.Artifacts {
+				apath := fmt.Sprintf("%s.artifacts[%d]", rpath, j)
+				if !containsString(catalogOSes, a.OS) {
+					fail(apath+".os", "must be one of %s, got %q", strings.Join(catalogOSes, ", "), a.OS)
+				}
+				if a.Arch == arch.Unknown || arch.Normalize(a.Arch) != a.Arch {
+					fail(apath+".arch", "unknown architecture %q", a.Arch)
+				}
+				if !java.IsImage(a.Image) {
+					fail(apath+".image", "must be one of %s, got %q", strings.Join(java.Images, ", "), a.Image)
+				}
+				if !containsString(packages, a.Package) {
+					fail(apath+".package", "must be one of %s, got %q", strings.Join(packages, ", "), a.Package)
+				}
+				if !isHTTPURL(a.URL) {
+					fail(apath+".url", "must be an http(s) URL, got %q", a.URL)
+				}
+				if a.Size < 0 {
+					fail(apath+".size", "must not be negative")
+				}
+				if a.SHA256 != "" && !sha256Pattern.MatchString(a.SHA256) {
+					fail(apath+".sha256", "must be 64 lower-case hex digits")
+				}
+				if a.SignatureURL != "" && !isHTTPURL(a.SignatureURL) {
+					fail(apath+".signature_url", "must be an http(s) URL, got %q", a.SignatureURL)
+				}
+				if seen[a.Key()] {
+					fail(apath, "duplicate artifact %s", a.Key())
+				}
+				seen[a.Key()] = true
+			}
+		}
+	}
+	return errs
+}
+
+// FindArtifact returns the installable archive of an image for an operating
+// system and architecture. Images other than the JDK that the catalog does
+// not list are derived from the JDK archive's URL, without a checksum.
+func (info JavaVersionInfo) FindArtifact(osName string, a string, image string) (CatalogArtifact, error) {
+	if image == "" {
+		image = java.ImageJDK
+	}
+	if found, ok := info.archive(osName, a, image); ok {
+		return found, nil
+	}
+	if image != java.ImageJDK {
+		if jdk, ok := info.archive(osName, a, java.ImageJDK); ok {
+			return CatalogArtifact{OS: osName, Arch: a, Image: image, Package: jdk.Package, URL: rewriteImage(jdk.URL, image, osName)}, nil
+		}
+	}
+
+	builds := []string{}
+	for _, artifact := range info.Artifacts {
+		build := artifact.OS + "/" + artifact.Arch
+		if !containsString(builds, build) {
+			builds = append(builds, build)
+		}
+	}
+	sort.Strings(builds)
+	return CatalogArtifact{}, fmt.Errorf("no %s/%s build of Java %s in the catalog (available: %s)", osName, a, info.Latest, strings.Join(builds, ", "))
+}
+
+// archive finds an artifact jdkvm can unpack, preferring zip on Windows and
+// tar.gz elsewhere
+func (info JavaVersionInfo) archive(osName string, a string, image string) (CatalogArtifact, bool) {
+	preferred := PackageTarGz
+	if osName == OSWindows {
+		preferred = PackageZip
+	}
+	var found CatalogArtifact
+	ok := false
+	for _, artifact := range info.Artifacts {
+		if artifact.OS != osName || artifact.Arch != a || artifact.Image != image {
+			continue
+		}
+		if artifact.Package != PackageZip && artifact.Package != PackageTarGz {
+			continue
+		}
+		if !ok || artifact.Package == preferred {
+			found, ok = artifact, true
+		}
+	}
+	return found, ok
+}
+
+// packageOf infers the package type of an artifact from its URL
+func packageOf(u string) string {
+	for _, p := range []string{PackageTarGz, PackageZip, PackageMSI, PackagePKG} {
+		if strings.HasSuffix(u, "."+p) {
+			return p
+		}
+	}
+	return PackageZip
+}
+
+func sortArtifacts(artifacts []CatalogArtifact) {
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].Key() < artifacts[j].Key()
+	})
+}
+
+func isHTTPURL(u string) bool {
+	parsed, err := url.Parse(u)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
@@ -0,0 +1,315 @@
+package web
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"jdkvm/arch"
+	"jdkvm/java"
+)
+
+const testSHA256 = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func testArtifact(osName string, a string, version string) CatalogArtifact {
+	ext := PackageTarGz
+	if osName == OSWindows {
+		ext = PackageZip
+	}
+	return CatalogArtifact{
+		OS:      osName,
+		Arch:    a,
+		Image:   java.ImageJDK,
+		Package: ext,
+		URL:     "https://example.com/OpenJDK-jdk_" + a + "_" + osName + "_hotspot_" + version + "." + ext,
+		SHA256:  testSHA256,
+	}
+}
+
+// testCatalog has two temurin releases of 17, a corretto one listed beside
+// them and a single release of 21
+func testCatalog() Catalog {
+	return Catalog{
+		SchemaVersion: CatalogSchemaVersion,
+		Published:     "2024-05-01T00:00:00Z",
+		Versions: map[string]FeatureRelease{
+			"17": {Vendor: "temurin", LTS: true, EOL: "2027-10-31", Releases: []Release{
+				{Version: "17.0.11+9", Artifacts: []CatalogArtifact{testArtifact(OSLinux, arch.X64, "17.0.11"), testArtifact(OSWindows, arch.X64, "17.0.11")}},
+				{Version: "17.0.11+10", Vendor: "corretto", Artifacts: []CatalogArtifact{testArtifact(OSLinux, arch.X64, "17.0.11")}},
+				{Version: "17.0.10+7", Artifacts: []CatalogArtifact{testArtifact(OSLinux, arch.X64, "17.0.10")}},
+			}},
+			"21": {Vendor: "temurin", LTS: true, Releases: []Release{
+				{Version: "21.0.3+9", Artifacts: []CatalogArtifact{testArtifact(OSLinux, arch.AArch64, "21.0.3")}},
+			}},
+		},
+	}
+}
+
+func TestValidateCatalog(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Catalog)
+		want   string
+	}{
+		{"valid", func(c *Catalog) {}, ""},
+		{"schema version", func(c *Catalog) { c.SchemaVersion = 1 }, "schema_version"},
+		{"published", func(c *Catalog) { c.Published = "yesterday" }, "published"},
+		{"no versions", func(c *Catalog) { c.Versions = nil }, "no feature releases"},
+		{"feature key", func(c *Catalog) { c.Versions["x17"] = c.Versions["17"] }, "versions.x17"},
+		{"vendor", func(c *Catalog) { setFeature(c, "17", func(fr *FeatureRelease) { fr.Vendor = "../x" }) }, "versions.17.vendor"},
+		{"eol", func(c *Catalog) { setFeature(c, "17", func(fr *FeatureRelease) { fr.EOL = "2027" }) }, "versions.17.eol"},
+		{"no releases", func(c *Catalog) { setFeature(c, "17", func(fr *FeatureRelease) { fr.Releases = nil }) }, "at least one release"},
+		{"release vendor", func(c *Catalog) { setRelease(c, "17", 1, func(r *Release) { r.Vendor = "Corretto" }) }, "releases[1].vendor"},
+		{"version path", func(c *Catalog) { setRelease(c, "17", 0, func(r *Release) { r.Version = "17/../../x" }) }, "releases[0].version"},
+		{"version feature", func(c *Catalog) { setRelease(c, "21", 0, func(r *Release) { r.Version = "17.0.1+1" }) }, "does not belong"},
+		{"release order", func(c *Catalog) { setRelease(c, "17", 2, func(r *Release) { r.Version = "17.0.12+7" }) }, "newest first"},
+		{"os", func(c *Catalog) { setArtifact(c, func(a *CatalogArtifact) { a.OS = "solaris" }) }, "artifacts[0].os"},
+		{"arch", func(c *Catalog) { setArtifact(c, func(a *CatalogArtifact) { a.Arch = "amd64" }) }, "artifacts[0].arch"},
+		{"unknown arch", func(c *Catalog) { setArtifact(c, func(a *CatalogArtifact) { a.Arch = arch.Unknown }) }, "artifacts[0].arch"},
+		{"image", func(c *Catalog) { setArtifact(c, func(a *CatalogArtifact) { a.Image = "sdk" }) }, "artifacts[0].image"},
+		{"package", func(c *Catalog) { setArtifact(c, func(a *CatalogArtifact) { a.Package = "rpm" }) }, "artifacts[0].package"},
+		{"url", func(c *Catalog) { setArtifact(c, func(a *CatalogArtifact) { a.URL = "file:///etc/passwd" }) }, "artifacts[0].url"},
+		{"size", func(c *Catalog) { setArtifact(c, func(a *CatalogArtifact) { a.Size = -1 }) }, "artifacts[0].size"},
+		{"sha256", func(c *Catalog) { setArtifact(c, func(a *CatalogArtifact) { a.SHA256 = "ABC" }) }, "artifacts[0].sha256"},
+		{"duplicate", func(c *Catalog) {
+			setRelease(c, "17", 0, func(r *Release) { r.Artifacts = append(r.Artifacts, r.Artifacts[0]) })
+		}, "duplicate artifact"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testCatalog()
+			tt.change(&c)
+			errs := ValidateCatalog(c)
+			if tt.want == "" {
+				if len(errs) > 0 {
+					t.Fatalf("ValidateCatalog = %v, want no errors", errs)
+				}
+				return
+			}
+			for _, err := range errs {
+				if strings.Contains(err.Error(), tt.want) {
+					return
+				}
+			}
+			t.Errorf("ValidateCatalog = %v, want an error mentioning %q", errs, tt.want)
+		})
+	}
+}
+
+func setFeature(c *Catalog, major string, change func(fr *FeatureRelease)) {
+	fr := c.Versions[major]
+	change(&fr)
+	c.Versions[major] = fr
+}
+
+func setRelease(c *Catalog, major string, i int, change func(r *Release)) {
+	setFeature(c, major, func(fr *FeatureRelease) { change(&fr.Releases[i]) })
+}
+
+func setArtifact(c *Catalog, change func(a *CatalogArtifact)) {
+	setRelease(c, "17", 0, func(r *Release) { change(&r.Artifacts[0]) })
+}
+
+func TestUpgradeCatalogV1(t *testing.T) {
+	content := `{
+		"17": {"latest": "17.0.11+9", "short": "17", "lts": true, "eol": "2027-10-31",
+			"url": "https://example.com/OpenJDK17U-jdk_x64_windows_hotspot_17.0.11_9.zip",
+			"urls": {
+				"windows/x64": "https://example.com/OpenJDK17U-jdk_x64_windows_hotspot_17.0.11_9.zip",
+				"linux/aarch64/jre": "https://example.com/OpenJDK17U-jre_aarch64_linux_hotspot_17.0.11_9.tar.gz"
+			}},
+		"8": {"latest": "8u412-b08", "short": "8", "vendor": "temurin",
+			"url": "https://example.com/OpenJDK8U-jdk_x64_windows_hotspot_8u412b08.zip"}
+	}`
+	catalog, version, err := ParseCatalog([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 || catalog.SchemaVersion != CatalogSchemaVersion {
+		t.Errorf("versions = %d, %d, want 1, %d", version, catalog.SchemaVersion, CatalogSchemaVersion)
+	}
+
+	tests := []struct {
+		major string
+		want  []string
+	}{
+		// Only the builds the v1 entry listed; none are invented
+		{"17", []string{"linux/aarch64/jre/tar.gz", "windows/x64/jdk/zip"}},
+		{"8", []string{"windows/x64/jdk/zip"}},
+	}
+	for _, tt := range tests {
+		fr := catalog.Versions[tt.major]
+		if len(fr.Releases) != 1 {
+			t.Fatalf("%s: %d releases, want 1", tt.major, len(fr.Releases))
+		}
+		var keys []string
+		for _, a := range fr.Releases[0].Artifacts {
+			keys = append(keys, a.Key())
+		}
+		if strings.Join(keys, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s artifacts = %v, want %v", tt.major, keys, tt.want)
+		}
+	}
+	if fr := catalog.Versions["17"]; !fr.LTS || fr.EOL != "2027-10-31" {
+		t.Errorf("17 lost its support data: %+v", fr)
+	}
+}
+
+func TestDecodeCatalogVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		wantErr bool
+	}{
+		{"v1", `{"17": {"latest": "17.0.11+9", "url": "https://example.com/a.zip"}}`, 1, false},
+		{"v2", `{"schema_version": 2, "versions": {}}`, 2, false},
+		{"newer", `{"schema_version": 3, "versions": {}}`, 3, true},
+		{"not json", `catalog`, 0, true},
+	}
+	for _, tt := range tests {
+		_, version, err := DecodeCatalog([]byte(tt.content))
+		if (err != nil) != tt.wantErr || version != tt.want {
+			t.Errorf("%s: DecodeCatalog = %d, %v, want %d, wantErr %v", tt.name, version, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestEmbeddedCatalog(t *testing.T) {
+	if _, _, err := ParseCatalog(embeddedCatalog); err != nil {
+		t.Fatalf("embedded catalog: %v", err)
+	}
+}
+
+func TestFindRelease(t *testing.T) {
+	c := testCatalog()
+	tests := []struct {
+		version string
+		vendor  string
+		want    string
+		wantVnd string
+		ok      bool
+	}{
+		{"17", "", "17.0.11+9", "temurin", true},
+		{"17", "temurin", "17.0.11+9", "temurin", true},
+		{"17", "corretto", "17.0.11+10", "corretto", true},
+		{"17.0.10", "", "17.0.10+7", "temurin", true},
+		{"17.0.10+7", "temurin", "17.0.10+7", "temurin", true},
+		{"17.0.10+8", "", "", "", false},
+		// The feature release's vendor has no such release, so another vendor's is used
+		{"17.0.11+10", "", "17.0.11+10", "corretto", true},
+		{"17.0.10", "corretto", "", "", false},
+		{"21", "zulu", "", "", false},
+		{"11", "", "", "", false},
+	}
+	for _, tt := range tests {
+		info, ok := c.FindRelease(tt.version, tt.vendor)
+		if ok != tt.ok || info.Latest != tt.want || (ok && info.Vendor != tt.wantVnd) {
+			t.Errorf("FindRelease(%q, %q) = %s (%s), %v, want %s (%s), %v", tt.version, tt.vendor, info.Latest, info.Vendor, ok, tt.want, tt.wantVnd, tt.ok)
+		}
+	}
+}
+
+func TestReleases(t *testing.T) {
+	c := testCatalog()
+	tests := []struct {
+		vendor string
+		want   []string
+	}{
+		{"", []string{"17.0.11+10", "17.0.11+9", "17.0.10+7"}},
+		{"temurin", []string{"17.0.11+9", "17.0.10+7"}},
+		{"corretto", []string{"17.0.11+10"}},
+		{"zulu", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range c.Releases("17", tt.vendor) {
+			got = append(got, r.Latest)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("Releases(17, %q) = %v, want %v", tt.vendor, got, tt.want)
+		}
+	}
+	if got := strings.Join(c.Vendors(), " "); got != "corretto temurin" {
+		t.Errorf("Vendors = %s", got)
+	}
+	if got := c.Mapping()["17"].Latest; got != "17.0.11+9" {
+		t.Errorf("Mapping()[17] = %s, want the feature release vendor's newest", got)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	c := testCatalog()
+	tests := []struct {
+		version, vendor, osName, a, pkg string
+		want                            string
+	}{
+		{"17.0.11+9", "temurin", OSLinux, arch.X64, PackageTarGz, testSHA256},
+		{"17.0.11+9", "temurin", OSWindows, arch.X64, PackageZip, testSHA256},
+		{"17.0.11+9", "temurin", OSWindows, arch.X64, PackageMSI, ""},
+		{"17.0.11+9", "corretto", OSLinux, arch.X64, PackageTarGz, ""},
+		{"17.0.11", "temurin", OSLinux, arch.X64, PackageTarGz, ""},
+		{"17.0.11+9", "temurin", OSLinux, arch.AArch64, PackageTarGz, ""},
+	}
+	for _, tt := range tests {
+		if got := c.Checksum(tt.version, tt.vendor, tt.osName, tt.a, java.ImageJDK, tt.pkg); got != tt.want {
+			t.Errorf("Checksum(%s, %s, %s, %s, %s) = %q, want %q", tt.version, tt.vendor, tt.osName, tt.a, tt.pkg, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogRoundTrip(t *testing.T) {
+	content, err := json.Marshal(testCatalog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, version, err := ParseCatalog(content)
+	if err != nil || version != CatalogSchemaVersion {
+		t.Fatalf("ParseCatalog = %d, %v", version, err)
+	}
+	if got := c.Versions["17"].Releases[1].Vendor; got != "corretto" {
+		t.Errorf("release vendor = %q after a round trip", got)
+	}
+}
+
+func TestFindArtifact(t *testing.T) {
+	const base = "https://example.com/jdk-17.0.11%2B9/OpenJDK17U-"
+	info := JavaVersionInfo{Latest: "17.0.11+9", Artifacts: []CatalogArtifact{
+		{OS: OSLinux, Arch: arch.X64, Image: java.ImageJDK, Package: PackageTarGz, URL: base + "jdk_x64_linux_hotspot_17.0.11_9.tar.gz"},
+		{OS: OSAlpine, Arch: arch.X64, Image: java.ImageJDK, Package: PackageTarGz, URL: base + "jdk_x64_alpine-linux_hotspot_17.0.11_9.tar.gz"},
+		{OS: OSMac, Arch: arch.AArch64, Image: java.ImageJDK, Package: PackageTarGz, URL: base + "jdk_aarch64_mac_hotspot_17.0.11_9.tar.gz"},
+		{OS: OSWindows, Arch: arch.X64, Image: java.ImageJDK, Package: PackageMSI, URL: base + "jdk_x64_windows_hotspot_17.0.11_9.msi"},
+		{OS: OSWindows, Arch: arch.X64, Image: java.ImageJDK, Package: PackageZip, URL: base + "jdk_x64_windows_hotspot_17.0.11_9.zip"},
+		{OS: OSWindows, Arch: arch.X64, Image: java.ImageJRE, Package: PackageZip, URL: base + "jre_x64_windows_hotspot_17.0.11_9.zip", SHA256: testSHA256},
+	}}
+
+	tests := []struct {
+		name    string
+		osName  string
+		a       string
+		image   string
+		want    string
+		wantErr bool
+	}{
+		{"jdk", OSLinux, arch.X64, "", "jdk_x64_linux_hotspot_17.0.11_9.tar.gz", false},
+		{"zip over msi", OSWindows, arch.X64, java.ImageJDK, "jdk_x64_windows_hotspot_17.0.11_9.zip", false},
+		{"listed jre", OSWindows, arch.X64, java.ImageJRE, "jre_x64_windows_hotspot_17.0.11_9.zip", false},
+		{"derived jre", OSLinux, arch.X64, java.ImageJRE, "jre_x64_linux_hotspot_17.0.11_9.tar.gz", false},
+		{"derived debug image", OSMac, arch.AArch64, java.ImageDebug, "debugimage_aarch64_mac_hotspot_17.0.11_9.tar.gz", false},
+		{"glibc static libs", OSLinux, arch.X64, java.ImageStaticLibs, "static-libs-glibc_x64_linux_hotspot_17.0.11_9.tar.gz", false},
+		{"musl static libs", OSAlpine, arch.X64, java.ImageStaticLibs, "static-libs_x64_alpine-linux_hotspot_17.0.11_9.tar.gz", false},
+		{"mac static libs", OSMac, arch.AArch64, java.ImageStaticLibs, "static-libs_aarch64_mac_hotspot_17.0.11_9.tar.gz", false},
+		{"missing build", OSLinux, arch.AArch64, java.ImageJDK, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifact, err := info.FindArtifact(tt.osName, tt.a, tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FindArtifact error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && artifact.URL != base+tt.want {
+				t.Errorf("FindArtifact URL = %s, want %s", artifact.URL, base+tt.want)
+			}
+		})
+	}
+}
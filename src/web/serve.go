@@ -0,0 +1,265 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"jdkvm/java"
+)
+
+// catalogPath is where jdkvm serve publishes the catalog
+const catalogPath = "/version_mapping.json"
+
+// Timeouts of jdkvm serve. Writing covers fetching a missing archive from
+// upstream as well as sending it, so it allows for large downloads.
+const (
+	serveReadTimeout  = 30 * time.Second
+	serveWriteTimeout = 30 * time.Minute
+	serveIdleTimeout  = 2 * time.Minute
+)
+
+// mirrorServer serves the local catalog and caches archives for other jdkvm
+// instances on the network. Archives keep the path they have upstream, so a
+// client only has to swap the scheme and host of a catalog URL to use it.
+type mirrorServer struct {
+	cacheDir string
+	// base is the URL clients reach this server at, which the published
+	// catalog points at
+	base string
+	mu   sync.Mutex
+	// fetches holds the downloads in progress by cache file, so concurrent
+	// misses for one file share a transfer and other files are not held up
+	fetches map[string]*fetchCall
+}
+
+// fetchCall is a download in progress; done is closed when err is set
+type fetchCall struct {
+	done chan struct{}
+	err  error
+}
+
+// Serve runs a catalog mirror on addr. Archives are fetched from upstream on
+// the first request and kept in cacheDir for later ones. The published
+// catalog points at advertise, or at this host and the port of addr.
+func Serve(addr string, advertise string, cacheDir string) error {
+	base, err := advertisedBase(addr, advertise)
+	if err != nil {
+		return err
+	}
+	if JavaVersionMapping == nil {
+		if err := LoadVersionMapping(); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(cacheDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           newMirrorServer(cacheDir, base),
+		ReadHeaderTimeout: serveReadTimeout,
+		ReadTimeout:       serveReadTimeout,
+		WriteTimeout:      serveWriteTimeout,
+		IdleTimeout:       serveIdleTimeout,
+	}
+	fmt.Printf("Serving the Java catalog on %s as %s (cache: %s)\n", addr, base, cacheDir)
+	fmt.Printf("Point other machines at it with: jdkvm config set java_mirror %s/\n", base)
+	return server.ListenAndServe()
+}
+
+func newMirrorServer(cacheDir string, base string) *mirrorServer {
+	return &mirrorServer{cacheDir: cacheDir, base: base, fetches: map[string]*fetchCall{}}
+}
+
+// advertisedBase returns the base URL of the published catalog. The Host
+// header of a request is never used, since any client could set it and
+// poison the catalog other clients get.
+func advertisedBase(addr string, advertise string) (string, error) {
+	if advertise != "" {
+		u, err := url.Parse(advertise)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return "", fmt.Errorf("invalid advertised URL %q, expected e.g. http://lan-host:8080", advertise)
+		}
+		return strings.TrimSuffix(advertise, "/"), nil
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %q: %v", addr, err)
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		if host, err = os.Hostname(); err != nil {
+			return "", fmt.Errorf("could not tell this host's name, pass --advertise: %v", err)
+		}
+	}
+	return "http://" + net.JoinHostPort(host, port), nil
+}
+
+func (s *mirrorServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	fmt.Printf("%s %s %s\n", r.RemoteAddr, r.Method, r.URL.Path)
+
+	if r.URL.Path == catalogPath {
+		s.serveCatalog(w, r)
+		return
+	}
+	s.serveArchive(w, r)
+}
+
+// serveCatalog publishes the local catalog with every URL pointing at this
+// server. Published is kept, so clients still refuse an older catalog.
+func (s *mirrorServer) serveCatalog(w http.ResponseWriter, r *http.Request) {
+	base := s.base
+	catalog := Catalog{SchemaVersion: CatalogSchemaVersion, Published: CurrentCatalog.Published, Versions: make(map[string]FeatureRelease, len(CurrentCatalog.Versions))}
+	for major, fr := range CurrentCatalog.Versions {
+		releases := make([]Release, len(fr.Releases))
+		for i, release := range fr.Releases {
+			artifacts := make([]CatalogArtifact, len(release.Artifacts))
+			for j, artifact := range release.Artifacts {
+				artifact.URL = rebase(artifact.URL, base)
+				if artifact.SignatureURL != "" {
+					artifact.SignatureURL = rebase(artifact.SignatureURL, base)
+				}
+				artifacts[j] = artifact
+			}
+			release.Artifacts = artifacts
+			releases[i] = release
+		}
+		fr.Releases = releases
+		catalog.Versions[major] = fr
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(catalog)
+}
+
+// serveArchive answers from the cache, fetching from upstream on a miss. Only
+// archives the catalog knows about are served, so this is not an open proxy.
+func (s *mirrorServer) serveArchive(w http.ResponseWriter, r *http.Request) {
+	upstream, checksum := s.upstreamURL(r.URL.EscapedPath())
+	if upstream == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	cached := filepath.Join(s.cacheDir, filepath.FromSlash(strings.TrimPrefix(r.URL.Path, "/")))
+	if !Exists(cached) {
+		if err := s.fetch(upstream, cached, checksum); err != nil {
+			fmt.Printf("Failed to fetch %s: %v\n", upstream, err)
+			http.Error(w, "upstream fetch failed", http.StatusBadGateway)
+			return
+		}
+	}
+	http.ServeFile(w, r, cached)
+}
+
+// upstreamURL maps a request path back to the catalog URL it mirrors, including
+// the other image types, signatures and the .sha256.txt checksum files
+// published next to every archive. For an archive the catalog has a checksum
+// for, that checksum is returned too.
+func (s *mirrorServer) upstreamURL(path string) (string, string) {
+	for _, fr := range CurrentCatalog.Versions {
+		for _, release := range fr.Releases {
+			for _, artifact := range release.Artifacts {
+				candidates := []string{}
+				for _, image := range java.Images {
+					u := rewriteImage(artifact.URL, image, artifact.OS)
+					candidates = append(candidates, u, u+".sha256.txt")
+				}
+				if artifact.SignatureURL != "" {
+					candidates = append(candidates, artifact.SignatureURL)
+				}
+				for _, u := range candidates {
+					if parsed, err := url.Parse(u); err == nil && parsed.EscapedPath() == path {
+						if u == artifact.URL {
+							return u, artifact.SHA256
+						}
+						return u, ""
+					}
+				}
+			}
+		}
+	}
+	return "", ""
+}
+
+// fetch downloads an upstream file into the cache. Concurrent requests for
+// the same file wait for one download instead of starting their own.
+func (s *mirrorServer) fetch(upstream string, cached string, checksum string) error {
+	s.mu.Lock()
+	if call, ok := s.fetches[cached]; ok {
+		s.mu.Unlock()
+		<-call.done
+		return call.err
+	}
+	call := &fetchCall{done: make(chan struct{})}
+	s.fetches[cached] = call
+	s.mu.Unlock()
+
+	call.err = s.download(upstream, cached, checksum)
+
+	s.mu.Lock()
+	delete(s.fetches, cached)
+	s.mu.Unlock()
+	close(call.done)
+	return call.err
+}
+
+// download fetches an upstream file to a temporary name and verifies it
+// before moving it into the cache, so clients are never served a truncated
+// or tampered archive. Archives the catalog has no checksum for are checked
+// against the vendor's .sha256.txt when there is one.
+func (s *mirrorServer) download(upstream string, cached string, checksum string) error {
+	if Exists(cached) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(cached), 0o755); err != nil {
+		return err
+	}
+	partial := cached + ".partial"
+	if !Download(upstream, partial, "") {
+		os.Remove(partial)
+		return fmt.Errorf("download failed")
+	}
+
+	if checksum == "" && isArchive(upstream) {
+		var err error
+		if checksum, err = FetchChecksum(upstream); err != nil {
+			fmt.Printf("Warning: Caching %s without verifying it: %v\n", upstream, err)
+		}
+	}
+	if checksum != "" {
+		if err := VerifyChecksum(partial, checksum); err != nil {
+			os.Remove(partial)
+			return err
+		}
+	}
+	return os.Rename(partial, cached)
+}
+
+// isArchive reports whether a URL names a Java archive rather than a
+// checksum or signature file
+func isArchive(u string) bool {
+	return strings.HasSuffix(u, "."+PackageZip) || strings.HasSuffix(u, "."+PackageTarGz)
+}
+
+// rebase replaces the scheme and host of a URL, keeping its path
+func rebase(u string, base string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return u
+	}
+	return base + parsed.EscapedPath()
+}
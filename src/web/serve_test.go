@@ -0,0 +1,290 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testArchivePath = "/adoptium/temurin17-binaries/releases/download/jdk-17.0.11%2B9/OpenJDK17U-jdk_x64_linux_hotspot_17.0.11_9.tar.gz"
+	testArchiveBody = "archive"
+)
+
+// upstreamServer serves one archive and, when set, its .sha256.txt
+type upstreamServer struct {
+	vendorChecksum string
+	// gate, when set, holds archive downloads until it is closed
+	gate chan struct{}
+	hits atomic.Int32
+}
+
+func (u *upstreamServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.EscapedPath() {
+	case testArchivePath:
+		u.hits.Add(1)
+		if u.gate != nil {
+			<-u.gate
+		}
+		io.WriteString(w, testArchiveBody)
+	case testArchivePath + ".sha256.txt":
+		if u.vendorChecksum == "" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, u.vendorChecksum+"  OpenJDK17U-jdk_x64_linux_hotspot_17.0.11_9.tar.gz\n")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newServeTest starts an upstream and a mirror of it, with a catalog
+// listing the upstream archive
+func newServeTest(t *testing.T, upstream *upstreamServer, catalogChecksum string) (*httptest.Server, *httptest.Server, string) {
+	t.Helper()
+	up := httptest.NewServer(upstream)
+	t.Cleanup(up.Close)
+	useTestCatalog(t, catalogChecksum, up.URL+testArchivePath)
+	cacheDir := t.TempDir()
+	mirror := httptest.NewServer(newMirrorServer(cacheDir, "http://lan-host:8080"))
+	t.Cleanup(mirror.Close)
+	return up, mirror, cacheDir
+}
+
+func get(t *testing.T, u string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestUpstreamURL(t *testing.T) {
+	const upstream = "https://github.com" + testArchivePath
+	sum := strings.Repeat("ab", 32)
+	useTestCatalog(t, sum, upstream)
+	s := newMirrorServer(t.TempDir(), "http://lan-host:8080")
+
+	jre := strings.Replace(testArchivePath, "-jdk_", "-jre_", 1)
+	tests := []struct {
+		name         string
+		path         string
+		want         string
+		wantChecksum string
+	}{
+		{"archive", testArchivePath, upstream, sum},
+		{"checksum file", testArchivePath + ".sha256.txt", upstream + ".sha256.txt", ""},
+		{"other image", jre, "https://github.com" + jre, ""},
+		{"other image checksum file", jre + ".sha256.txt", "https://github.com" + jre + ".sha256.txt", ""},
+		{"unknown file", "/adoptium/temurin17-binaries/releases/download/other.tar.gz", "", ""},
+		{"outside the catalog", "/etc/passwd", "", ""},
+		{"catalog", catalogPath, "", ""},
+	}
+	for _, tt := range tests {
+		got, checksum := s.upstreamURL(tt.path)
+		if got != tt.want || checksum != tt.wantChecksum {
+			t.Errorf("%s: upstreamURL = %q, %q, want %q, %q", tt.name, got, checksum, tt.want, tt.wantChecksum)
+		}
+	}
+}
+
+func TestServeArchive(t *testing.T) {
+	sum := checksumOf(t, []byte(testArchiveBody))
+	const other = "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+	tests := []struct {
+		name            string
+		catalogChecksum string
+		vendorChecksum  string
+		wantStatus      int
+	}{
+		{"catalog checksum", sum, "", http.StatusOK},
+		{"catalog checksum mismatch", other, sum, http.StatusBadGateway},
+		{"vendor checksum", "", sum, http.StatusOK},
+		{"vendor checksum mismatch", "", other, http.StatusBadGateway},
+		{"no checksum", "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := &upstreamServer{vendorChecksum: tt.vendorChecksum}
+			_, mirror, cacheDir := newServeTest(t, upstream, tt.catalogChecksum)
+			// The cache keeps the decoded path
+			cached := filepath.Join(cacheDir, "adoptium", "temurin17-binaries", "releases", "download", "jdk-17.0.11+9", "OpenJDK17U-jdk_x64_linux_hotspot_17.0.11_9.tar.gz")
+
+			status, body := get(t, mirror.URL+testArchivePath)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if _, err := os.Stat(cached + ".partial"); !os.IsNotExist(err) {
+				t.Error("a partial download was left behind")
+			}
+			if tt.wantStatus != http.StatusOK {
+				if _, err := os.Stat(cached); !os.IsNotExist(err) {
+					t.Error("an unverified archive was cached")
+				}
+				return
+			}
+			if body != testArchiveBody {
+				t.Errorf("body = %q", body)
+			}
+			if _, err := os.Stat(cached); err != nil {
+				t.Errorf("archive was not cached: %v", err)
+			}
+
+			// The second request is answered from the cache
+			if status, _ := get(t, mirror.URL+testArchivePath); status != http.StatusOK {
+				t.Errorf("cached status = %d", status)
+			}
+			if hits := upstream.hits.Load(); hits != 1 {
+				t.Errorf("upstream was asked %d times, want 1", hits)
+			}
+		})
+	}
+}
+
+func TestServeArchiveNotFound(t *testing.T) {
+	_, mirror, _ := newServeTest(t, &upstreamServer{}, "")
+	for _, p := range []string{"/etc/passwd", "/adoptium/other.tar.gz", "/"} {
+		if status, _ := get(t, mirror.URL+p); status != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want %d", p, status, http.StatusNotFound)
+		}
+	}
+	resp, err := http.Post(mirror.URL+testArchivePath, "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+// TestServeArchiveOneFetchPerFile makes concurrent misses for one archive
+// share a download, while a different file is not held up by it
+func TestServeArchiveOneFetchPerFile(t *testing.T) {
+	sum := checksumOf(t, []byte(testArchiveBody))
+	upstream := &upstreamServer{vendorChecksum: sum, gate: make(chan struct{})}
+	_, mirror, _ := newServeTest(t, upstream, sum)
+
+	const clients = 5
+	statuses := make(chan int, clients)
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(mirror.URL + testArchivePath)
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for upstream.hits.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// The checksum file is fetched while the archive is still downloading
+	if status, _ := get(t, mirror.URL+testArchivePath+".sha256.txt"); status != http.StatusOK {
+		t.Errorf("checksum file status = %d while the archive was downloading", status)
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(upstream.gate)
+	wg.Wait()
+	close(statuses)
+
+	for status := range statuses {
+		if status != http.StatusOK {
+			t.Errorf("status = %d, want %d", status, http.StatusOK)
+		}
+	}
+	if hits := upstream.hits.Load(); hits != 1 {
+		t.Errorf("upstream was asked %d times, want 1", hits)
+	}
+}
+
+func TestServeCatalog(t *testing.T) {
+	useTestCatalog(t, "", "https://github.com"+testArchivePath)
+	CurrentCatalog.Published = "2024-05-01T00:00:00Z"
+	setFeature(&CurrentCatalog, "17", func(fr *FeatureRelease) {
+		fr.Releases[0].Artifacts[0].SignatureURL = "https://github.com" + testArchivePath + ".sig"
+	})
+	s := newMirrorServer(t.TempDir(), "http://lan-host:8080")
+
+	req := httptest.NewRequest(http.MethodGet, catalogPath, nil)
+	req.Host = "attacker.example"
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+
+	var served Catalog
+	if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil {
+		t.Fatal(err)
+	}
+	if served.Published != CurrentCatalog.Published {
+		t.Errorf("Published = %q, want %q", served.Published, CurrentCatalog.Published)
+	}
+	artifact := served.Versions["17"].Releases[0].Artifacts[0]
+	if want := "http://lan-host:8080" + testArchivePath; artifact.URL != want {
+		t.Errorf("URL = %q, want %q", artifact.URL, want)
+	}
+	if want := "http://lan-host:8080" + testArchivePath + ".sig"; artifact.SignatureURL != want {
+		t.Errorf("SignatureURL = %q, want %q", artifact.SignatureURL, want)
+	}
+	if strings.Contains(rec.Body.String(), "attacker.example") {
+		t.Error("the catalog uses the request's Host header")
+	}
+	if strings.Contains(CurrentCatalog.Versions["17"].Releases[0].Artifacts[0].URL, "lan-host") {
+		t.Error("serving the catalog changed the local one")
+	}
+}
+
+func TestAdvertisedBase(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		addr      string
+		advertise string
+		want      string
+		wantErr   bool
+	}{
+		{":8080", "http://lan-host:9000/", "http://lan-host:9000", false},
+		{":8080", "https://mirror.example.com/jdk", "https://mirror.example.com/jdk", false},
+		{":8080", "ftp://lan-host", "", true},
+		{":8080", "lan-host:8080", "", true},
+		{"lan-host:8080", "", "http://lan-host:8080", false},
+		{"192.168.1.5:8080", "", "http://192.168.1.5:8080", false},
+		{"[::1]:8080", "", "http://[::1]:8080", false},
+		{":8080", "", "http://" + hostname + ":8080", false},
+		{"0.0.0.0:8080", "", "http://" + hostname + ":8080", false},
+		{"[::]:8080", "", "http://" + hostname + ":8080", false},
+		{"8080", "", "", true},
+	}
+	for _, tt := range tests {
+		got, err := advertisedBase(tt.addr, tt.advertise)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("advertisedBase(%q, %q) error = %v, wantErr %v", tt.addr, tt.advertise, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("advertisedBase(%q, %q) = %q, want %q", tt.addr, tt.advertise, got, tt.want)
+		}
+	}
+}
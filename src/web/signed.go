@@ -0,0 +1,223 @@
+package web
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SignatureSuffix is appended to a catalog's URL to find its signature
+const SignatureSuffix = ".sig"
+
+// SignedCatalog is how an updated catalog is stored: the exact bytes that
+// were signed together with the signature, so both are checked again on
+// every load
+type SignedCatalog struct {
+	URL       string `json:"url"`
+	Fetched   string `json:"fetched"`
+	Published string `json:"published"`
+	Signature string `json:"signature"`
+	Catalog   string `json:"catalog"`
+}
+
+// CatalogUpdate is the outcome of UpdateCatalog
+type CatalogUpdate struct {
+	Published string
+	Previous  string
+	Updated   bool
+}
+
+// ParsePublicKey decodes a base64 ed25519 public key
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("catalog_public_key must be a base64 ed25519 public key (%d bytes)", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// GenerateCatalogKey creates a key pair for signing catalogs, both base64
+// encoded; the private key is the 32-byte seed
+func GenerateCatalogKey() (string, string, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(public), base64.StdEncoding.EncodeToString(private.Seed()), nil
+}
+
+// SignCatalog signs a catalog file's content and returns the base64 signature
+func SignCatalog(content []byte, privateKey string) (string, error) {
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(privateKey))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return "", fmt.Errorf("the private key must be a base64 ed25519 seed (%d bytes)", ed25519.SeedSize)
+	}
+	catalog, _, err := ParseCatalog(content)
+	if err != nil {
+		return "", err
+	}
+	if catalog.Published == "" {
+		return "", fmt.Errorf("the catalog needs a published time, since catalog update refuses catalogs without one")
+	}
+	signature := ed25519.Sign(ed25519.NewKeyFromSeed(seed), content)
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// VerifyCatalog checks a signature, given base64 encoded or as the raw 64
+// bytes, over a catalog's content
+func VerifyCatalog(content []byte, signature []byte, key ed25519.PublicKey) error {
+	sig := signature
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+		if err != nil {
+			return fmt.Errorf("the signature is neither base64 nor a raw ed25519 signature")
+		}
+		sig = decoded
+	}
+	if len(sig) != ed25519.SignatureSize || !ed25519.Verify(key, content, sig) {
+		return fmt.Errorf("the catalog signature does not match catalog_public_key")
+	}
+	return nil
+}
+
+// UpdateCatalog downloads a catalog and its signature (the catalog URL with
+// SignatureSuffix), verifies them and stores them in store. Unsigned
+// catalogs, catalogs without a publication time and catalogs older than the
+// stored one are refused, so a compromised mirror cannot roll machines back
+// to a catalog that still allows vulnerable releases.
+func UpdateCatalog(catalogURL string, publicKey string, store string) (CatalogUpdate, error) {
+	var update CatalogUpdate
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return update, err
+	}
+
+	content, err := GetRemoteTextFile(catalogURL)
+	if err != nil {
+		return update, failure(ErrNetwork, "%v", err)
+	}
+	signature, err := GetRemoteTextFile(catalogURL + SignatureSuffix)
+	if err != nil {
+		return update, failure(ErrNetwork, "refusing the catalog because its signature could not be downloaded: %v", err)
+	}
+	if err := VerifyCatalog([]byte(content), []byte(signature), key); err != nil {
+		return update, failure(ErrVerification, "refusing the catalog: %v", err)
+	}
+
+	catalog, _, err := ParseCatalog([]byte(content))
+	if err != nil {
+		return update, err
+	}
+	if catalog.Published == "" {
+		return update, fmt.Errorf("refusing the catalog because it has no published time")
+	}
+	update.Published = catalog.Published
+
+	if previous, err := readSignedFile(store); err == nil {
+		update.Previous = previous.Published
+		switch compareTimes(catalog.Published, previous.Published) {
+		case -1:
+			return update, failure(ErrVerification, "refusing the catalog published %s because the stored one is newer (%s)", catalog.Published, previous.Published)
+		case 0:
+			if previous.Catalog != content {
+				return update, failure(ErrVerification, "refusing the catalog because it differs from the stored one published at the same time (%s)", catalog.Published)
+			}
+			return update, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return update, fmt.Errorf("could not read the stored catalog %s: %v", store, err)
+	}
+
+	signed := SignedCatalog{
+		URL:       catalogURL,
+		Fetched:   time.Now().UTC().Format(time.RFC3339),
+		Published: catalog.Published,
+		Signature: base64.StdEncoding.EncodeToString(decodedSignature([]byte(signature))),
+		Catalog:   content,
+	}
+	if err := writeSignedFile(store, signed); err != nil {
+		return update, err
+	}
+	update.Updated = true
+	return update, nil
+}
+
+// readSignedCatalog loads a stored catalog, checking its signature again
+func readSignedCatalog(path string, publicKey string) (*Catalog, SignedCatalog, error) {
+	signed, err := readSignedFile(path)
+	if err != nil {
+		return nil, signed, err
+	}
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, signed, err
+	}
+	if err := VerifyCatalog([]byte(signed.Catalog), []byte(signed.Signature), key); err != nil {
+		return nil, signed, err
+	}
+	catalog, err := parseCatalog([]byte(signed.Catalog))
+	return catalog, signed, err
+}
+
+func readSignedFile(path string) (SignedCatalog, error) {
+	var signed SignedCatalog
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return signed, err
+	}
+	err = json.Unmarshal(content, &signed)
+	return signed, err
+}
+
+// writeSignedFile replaces the stored catalog in one rename, so an
+// interrupted update leaves the previous one intact
+func writeSignedFile(path string, signed SignedCatalog) error {
+	content, err := json.MarshalIndent(signed, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// decodedSignature returns the raw bytes of a signature VerifyCatalog accepted
+func decodedSignature(signature []byte) []byte {
+	if len(signature) == ed25519.SignatureSize {
+		return signature
+	}
+	decoded, _ := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	return decoded
+}
+
+// compareTimes compares two RFC 3339 times that ValidateCatalog accepted
+func compareTimes(a string, b string) int {
+	ta, _ := time.Parse(time.RFC3339, a)
+	tb, _ := time.Parse(time.RFC3339, b)
+	return ta.Compare(tb)
+}
@@ -0,0 +1,209 @@
+package web
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// signedContent returns a valid catalog published at a time, and its signature
+func signedContent(t *testing.T, published string, eol string, privateKey string) (string, string) {
+	t.Helper()
+	c := testCatalog()
+	c.Published = published
+	setFeature(&c, "17", func(fr *FeatureRelease) { fr.EOL = eol })
+	content, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := SignCatalog(content, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content), signature
+}
+
+func TestVerifyCatalog(t *testing.T) {
+	public, private, err := GenerateCatalogKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPublic, _, _ := GenerateCatalogKey()
+	content, signature := signedContent(t, "2024-05-01T00:00:00Z", "2027-10-31", private)
+	raw, _ := base64.StdEncoding.DecodeString(signature)
+
+	tests := []struct {
+		name      string
+		content   string
+		signature []byte
+		key       string
+		wantErr   bool
+	}{
+		{"base64", content, []byte(signature), public, false},
+		{"base64 with newline", content, []byte(signature + "\n"), public, false},
+		{"raw", content, raw, public, false},
+		{"other key", content, []byte(signature), otherPublic, true},
+		{"tampered", content + " ", []byte(signature), public, true},
+		{"garbage", content, []byte("not a signature"), public, true},
+		{"empty", content, nil, public, true},
+	}
+	for _, tt := range tests {
+		key, err := ParsePublicKey(tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyCatalog([]byte(tt.content), tt.signature, key); (err != nil) != tt.wantErr {
+			t.Errorf("%s: VerifyCatalog error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSignCatalogNeedsPublished(t *testing.T) {
+	_, private, _ := GenerateCatalogKey()
+	c := testCatalog()
+	c.Published = ""
+	content, _ := json.Marshal(c)
+	if _, err := SignCatalog(content, private); err == nil {
+		t.Error("SignCatalog signed a catalog without a published time")
+	}
+	if _, err := SignCatalog(content, "c2hvcnQ="); err == nil {
+		t.Error("SignCatalog accepted a short key")
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	public, _, _ := GenerateCatalogKey()
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{public, false},
+		{" " + public + "\n", false},
+		{"", true},
+		{"not base64!", true},
+		{base64.StdEncoding.EncodeToString(make([]byte, ed25519.PublicKeySize-1)), true},
+	}
+	for _, tt := range tests {
+		if _, err := ParsePublicKey(tt.key); (err != nil) != tt.wantErr {
+			t.Errorf("ParsePublicKey(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+		}
+	}
+}
+
+// catalogServer serves a catalog and its signature, which tests replace
+type catalogServer struct {
+	mu        sync.Mutex
+	content   string
+	signature string
+}
+
+func (s *catalogServer) set(content string, signature string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.content, s.signature = content, signature
+}
+
+func (s *catalogServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.URL.Path {
+	case "/catalog.json":
+		w.Write([]byte(s.content))
+	case "/catalog.json" + SignatureSuffix:
+		if s.signature == "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(s.signature))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestUpdateCatalog(t *testing.T) {
+	public, private, _ := GenerateCatalogKey()
+	_, otherPrivate, _ := GenerateCatalogKey()
+	backend := &catalogServer{}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	catalogURL := server.URL + "/catalog.json"
+	store := filepath.Join(t.TempDir(), "signed.json")
+
+	may, maySig := signedContent(t, "2024-05-01T00:00:00Z", "2027-10-31", private)
+	mayChanged, mayChangedSig := signedContent(t, "2024-05-01T00:00:00Z", "2030-01-01", private)
+	april, aprilSig := signedContent(t, "2024-04-01T00:00:00Z", "2027-10-31", private)
+	june, juneSig := signedContent(t, "2024-06-01T00:00:00Z", "2027-10-31", private)
+	forged, forgedSig := signedContent(t, "2024-07-01T00:00:00Z", "2027-10-31", otherPrivate)
+
+	// Each step runs against the store the previous steps left behind
+	steps := []struct {
+		name        string
+		content     string
+		signature   string
+		wantUpdated bool
+		wantErr     error
+		wantStored  string
+	}{
+		{"first catalog", may, maySig, true, nil, may},
+		{"same catalog again", may, maySig, false, nil, may},
+		{"unsigned", june, "", false, ErrNetwork, may},
+		{"wrong key", forged, forgedSig, false, ErrVerification, may},
+		{"signature of another catalog", june, maySig, false, ErrVerification, may},
+		{"rollback", april, aprilSig, false, ErrVerification, may},
+		{"same time, other content", mayChanged, mayChangedSig, false, ErrVerification, may},
+		{"newer catalog", june, juneSig, true, nil, june},
+		{"rollback after update", may, maySig, false, ErrVerification, june},
+	}
+	for _, step := range steps {
+		backend.set(step.content, step.signature)
+		update, err := UpdateCatalog(catalogURL, public, store)
+		switch {
+		case step.wantErr == nil && err != nil:
+			t.Fatalf("%s: UpdateCatalog error = %v", step.name, err)
+		case step.wantErr != nil && !errors.Is(err, step.wantErr):
+			t.Fatalf("%s: UpdateCatalog error = %v, want %v", step.name, err, step.wantErr)
+		}
+		if update.Updated != step.wantUpdated {
+			t.Errorf("%s: Updated = %v, want %v", step.name, update.Updated, step.wantUpdated)
+		}
+
+		_, signed, err := readSignedCatalog(store, public)
+		if err != nil {
+			t.Fatalf("%s: stored catalog: %v", step.name, err)
+		}
+		if signed.Catalog != step.wantStored {
+			t.Errorf("%s: a different catalog was stored", step.name)
+		}
+	}
+}
+
+func TestReadSignedCatalogRechecks(t *testing.T) {
+	public, private, _ := GenerateCatalogKey()
+	otherPublic, _, _ := GenerateCatalogKey()
+	content, signature := signedContent(t, "2024-05-01T00:00:00Z", "2027-10-31", private)
+	store := filepath.Join(t.TempDir(), "signed.json")
+	if err := writeSignedFile(store, SignedCatalog{Published: "2024-05-01T00:00:00Z", Signature: signature, Catalog: content}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := readSignedCatalog(store, public); err != nil {
+		t.Errorf("readSignedCatalog: %v", err)
+	}
+	if _, _, err := readSignedCatalog(store, otherPublic); err == nil {
+		t.Error("readSignedCatalog accepted a catalog signed with another key")
+	}
+
+	// Editing the stored catalog invalidates it
+	signed, _ := readSignedFile(store)
+	signed.Catalog = content[:len(content)-1] + " }"
+	writeSignedFile(store, signed)
+	if _, _, err := readSignedCatalog(store, public); err == nil {
+		t.Error("readSignedCatalog accepted an edited catalog")
+	}
+}
@@ -0,0 +1,107 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// TLSOptions configures how every download verifies and authenticates TLS connections
+type TLSOptions struct {
+	// Verify enables certificate verification; turning it off is only meant for debugging
+	Verify bool
+	// CABundle is a PEM file of extra root certificates, e.g. a corporate proxy's root CA
+	CABundle string
+	// ClientCert and ClientKey are PEM files for servers that require client certificates
+	ClientCert string
+	ClientKey  string
+	// MinVersion is the lowest TLS version accepted: "1.0", "1.1", "1.2" or "1.3"
+	MinVersion string
+}
+
+var (
+	client     = &http.Client{}
+	tlsOptions = TLSOptions{Verify: true}
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// SetTLS applies TLS settings to every download. On error the previous
+// settings stay in effect.
+func SetTLS(opts TLSOptions) error {
+	previous := tlsOptions
+	tlsOptions = opts
+	if err := buildClient(); err != nil {
+		tlsOptions = previous
+		return err
+	}
+	return nil
+}
+
+// buildClient recreates the shared client from the TLS settings. Proxies
+// are resolved per request by proxyFunc.
+func buildClient() error {
+	config, err := tlsConfig(tlsOptions)
+	if err != nil {
+		return err
+	}
+
+	client = &http.Client{Transport: &http.Transport{Proxy: proxyFunc, TLSClientConfig: config}}
+	return nil
+}
+
+func tlsConfig(opts TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: !opts.Verify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if opts.MinVersion != "" {
+		v, ok := tlsVersions[opts.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported minimum TLS version %q (use 1.0, 1.1, 1.2 or 1.3)", opts.MinVersion)
+		}
+		config.MinVersion = v
+	}
+
+	if opts.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(opts.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle: %v", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CABundle)
+		}
+		config.RootCAs = pool
+	}
+
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// Do sends a request with the shared client, so it goes through the
+// configured proxy and TLS settings
+func Do(req *http.Request) (*http.Response, error) {
+	return client.Do(req)
+}
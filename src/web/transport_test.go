@@ -0,0 +1,148 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertPair writes a self-signed certificate and its key as PEM files
+func writeCertPair(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "jdkvm test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// writeServerCA saves the certificate of a test server as a CA bundle
+func writeServerCA(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTLSConfig(t *testing.T) {
+	certFile, keyFile := writeCertPair(t)
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		opts         TLSOptions
+		wantErr      bool
+		wantInsecure bool
+		wantMin      uint16
+		wantCerts    int
+	}{
+		{"verify", TLSOptions{Verify: true}, false, false, tls.VersionTLS12, 0},
+		{"no verify", TLSOptions{Verify: false}, false, true, tls.VersionTLS12, 0},
+		{"minimum 1.3", TLSOptions{Verify: true, MinVersion: "1.3"}, false, false, tls.VersionTLS13, 0},
+		{"minimum 1.0", TLSOptions{Verify: true, MinVersion: "1.0"}, false, false, tls.VersionTLS10, 0},
+		{"bad minimum", TLSOptions{Verify: true, MinVersion: "1.4"}, true, false, 0, 0},
+		{"bad minimum spelling", TLSOptions{Verify: true, MinVersion: "TLS1.2"}, true, false, 0, 0},
+		{"missing CA bundle", TLSOptions{Verify: true, CABundle: filepath.Join(t.TempDir(), "missing.pem")}, true, false, 0, 0},
+		{"CA bundle without certificates", TLSOptions{Verify: true, CABundle: empty}, true, false, 0, 0},
+		{"client certificate", TLSOptions{Verify: true, ClientCert: certFile, ClientKey: keyFile}, false, false, tls.VersionTLS12, 1},
+		{"certificate without key", TLSOptions{Verify: true, ClientCert: certFile}, true, false, 0, 0},
+		{"key without certificate", TLSOptions{Verify: true, ClientKey: keyFile}, true, false, 0, 0},
+		{"key of another certificate", TLSOptions{Verify: true, ClientCert: certFile, ClientKey: certFile}, true, false, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := tlsConfig(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("tlsConfig error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if config.InsecureSkipVerify != tt.wantInsecure {
+				t.Errorf("InsecureSkipVerify = %v, want %v", config.InsecureSkipVerify, tt.wantInsecure)
+			}
+			if config.MinVersion != tt.wantMin {
+				t.Errorf("MinVersion = %x, want %x", config.MinVersion, tt.wantMin)
+			}
+			if len(config.Certificates) != tt.wantCerts {
+				t.Errorf("%d client certificates, want %d", len(config.Certificates), tt.wantCerts)
+			}
+		})
+	}
+}
+
+func TestSetTLS(t *testing.T) {
+	t.Cleanup(func() { SetTLS(TLSOptions{Verify: true}) })
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	ca := writeServerCA(t, server)
+
+	// Each step runs with the settings the previous steps left in effect
+	steps := []struct {
+		name        string
+		opts        TLSOptions
+		wantSetErr  bool
+		wantFetchOK bool
+	}{
+		{"verify rejects an unknown CA", TLSOptions{Verify: true}, false, false},
+		{"verify off accepts it", TLSOptions{Verify: false}, false, true},
+		{"CA bundle makes it trusted", TLSOptions{Verify: true, CABundle: ca}, false, true},
+		{"invalid settings keep the previous ones", TLSOptions{Verify: false, MinVersion: "2.0"}, true, true},
+		{"back to the system roots", TLSOptions{Verify: true}, false, false},
+	}
+	for _, step := range steps {
+		if err := SetTLS(step.opts); (err != nil) != step.wantSetErr {
+			t.Fatalf("%s: SetTLS error = %v, wantErr %v", step.name, err, step.wantSetErr)
+		}
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		resp, err := Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if (err == nil) != step.wantFetchOK {
+			t.Errorf("%s: request error = %v, want success %v", step.name, err, step.wantFetchOK)
+		}
+	}
+}
@@ -1,81 +1,76 @@
 package web
 
 import (
+	"archive/tar"
 	"archive/zip"
-	"crypto/tls"
-	"encoding/json"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/blang/semver"
-	"jdkvm/file"
+	"jdkvm/arch"
+	"jdkvm/java"
 )
 
-var client = &http.Client{}
-var javaBaseAddress = "https://github.com/adoptium/temurin/releases/download/"
-
-// JavaVersionInfo contains information about a specific Java version
+// JavaVersionInfo describes the newest release of a feature release, as
+// derived from the catalog
 type JavaVersionInfo struct {
 	Latest string `json:"latest"`
-	URL    string `json:"url"`
 	Short  string `json:"short"`
+	Vendor string `json:"vendor,omitempty"`
+	// LTS marks long-term support feature releases
+	LTS bool `json:"lts,omitempty"`
+	// EOL is the date (YYYY-MM-DD) the vendor stops supporting the feature release
+	EOL string `json:"eol,omitempty"`
+	// Artifacts lists the builds of the release
+	Artifacts []CatalogArtifact `json:"artifacts,omitempty"`
 }
 
-// JavaVersionMapping maps major version numbers to version information
-var JavaVersionMapping map[string]JavaVersionInfo
-
-// LoadVersionMapping loads the version mapping from the JSON file
-func LoadVersionMapping() error {
-	// Define all possible paths to check
-	pathsToCheck := []string{}
-	
-	// Try the current directory first
-	pathsToCheck = append(pathsToCheck, "version_mapping.json")
-	
-	// Try web subdirectory
-	pathsToCheck = append(pathsToCheck, filepath.Join("web", "version_mapping.json"))
-	
-	// Try parent directory
-	pathsToCheck = append(pathsToCheck, filepath.Join("..", "web", "version_mapping.json"))
-	
-	// Try relative to executable
-	exe, err := os.Executable()
-	if err == nil {
-		exeDir := filepath.Dir(exe)
-		pathsToCheck = append(pathsToCheck, filepath.Join(exeDir, "version_mapping.json"))
-		pathsToCheck = append(pathsToCheck, filepath.Join(exeDir, "web", "version_mapping.json"))
-	}
-	
-	// Try to find the file in any of the paths
-	var mappingPath string
-	for _, path := range pathsToCheck {
-		if Exists(path) {
-			mappingPath = path
-			fmt.Printf("Found version mapping file at: %s\n", mappingPath)
-			break
-		}
-	}
-	
-	if mappingPath == "" {
-		return fmt.Errorf("could not find version_mapping.json file")
+// VendorName returns the vendor of the builds, defaulting to Temurin
+func (info JavaVersionInfo) VendorName() string {
+	if info.Vendor == "" {
+		return java.DefaultVendor
 	}
+	return info.Vendor
+}
 
-	content, err := os.ReadFile(mappingPath)
+// IsEOL reports whether the feature release is past its end of support at
+// the given time. The EOL day itself, in now's time zone, is still supported.
+func (info JavaVersionInfo) IsEOL(now time.Time) bool {
+	if info.EOL == "" {
+		return false
+	}
+	end, err := time.ParseInLocation("2006-01-02", info.EOL, now.Location())
 	if err != nil {
-		return err
+		return false
 	}
+	return !now.Before(end.AddDate(0, 0, 1))
+}
 
-	// Initialize the mapping
-	JavaVersionMapping = make(map[string]JavaVersionInfo)
-	
-	return json.Unmarshal(content, &JavaVersionMapping)
+// Artifact describes the build of an image for mirror URL templates
+func (info JavaVersionInfo) Artifact(osName string, a string, image string) Artifact {
+	return Artifact{Major: java.Major(info.Latest), Version: info.Latest, OS: osName, Arch: a, Image: image}
 }
 
+// ArtifactURL returns the archive URL of an image for an operating system and architecture
+func (info JavaVersionInfo) ArtifactURL(osName string, a string, image string) (string, error) {
+	artifact, err := info.FindArtifact(osName, a, image)
+	return artifact.URL, err
+}
+
+// JavaVersionMapping maps major version numbers to version information
+var JavaVersionMapping map[string]JavaVersionInfo
+
+// CurrentCatalog is the full catalog JavaVersionMapping was derived from
+var CurrentCatalog Catalog
+
 // Exists checks if a file exists
 func Exists(path string) bool {
 	_, err := os.Stat(path)
@@ -90,32 +85,35 @@ func Unzip(src string, dest string) error {
 	}
 	defer reader.Close()
 
+	if dest, err = filepath.EvalSymlinks(dest); err != nil {
+		return err
+	}
 	for _, file := range reader.File {
-		filePath := filepath.Join(dest, file.Name)
+		filePath, err := extractPath(dest, file.Name)
+		if err != nil {
+			return err
+		}
 
 		// Create directories if they don't exist
 		if file.FileInfo().IsDir() {
-			os.MkdirAll(filePath, os.ModeDir)
+			if err := os.MkdirAll(filePath, 0o755); err != nil {
+				return err
+			}
 			continue
 		}
 
 		// Create parent directories
-		os.MkdirAll(filepath.Dir(filePath), os.ModeDir)
-
-		// Extract file
-		srcFile, err := file.Open()
-		if err != nil {
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
 			return err
 		}
-		defer srcFile.Close()
 
-		destFile, err := os.Create(filePath)
+		// Extract file
+		srcFile, err := file.Open()
 		if err != nil {
 			return err
 		}
-		defer destFile.Close()
-
-		_, err = io.Copy(destFile, srcFile)
+		err = writeFile(filePath, srcFile, file.Mode().Perm()|0o600)
+		srcFile.Close()
 		if err != nil {
 			return err
 		}
@@ -124,182 +122,273 @@ func Unzip(src string, dest string) error {
 	return nil
 }
 
-func SetProxy(p string, verifyssl bool) {
-	if p != "" && p != "none" {
-		proxyUrl, _ := url.Parse(p)
-		client = &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyUrl), TLSClientConfig: &tls.Config{InsecureSkipVerify: verifyssl}}}
-	} else {
-		client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: verifyssl}}}
+// Untar extracts a gzip-compressed tar file to the specified destination
+func Untar(src string, dest string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
 	}
-}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
 
-func SetJavaMirror(mirror string) {
-	if mirror != "" && mirror != "none" {
-		javaBaseAddress = mirror
-		if strings.ToLower(javaBaseAddress[0:4]) != "http" {
-			javaBaseAddress = "http://" + javaBaseAddress
+	if dest, err = filepath.EvalSymlinks(dest); err != nil {
+		return err
+	}
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
 		}
-		if !strings.HasSuffix(javaBaseAddress, "/") {
-			javaBaseAddress = javaBaseAddress + "/"
+		if err != nil {
+			return err
 		}
-	}
-}
 
-func GetFullJavaUrl(path string) string {
-	return javaBaseAddress + path
+		filePath, err := extractPath(dest, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(filePath, 0o755)
+		case tar.TypeSymlink:
+			// Links are kept relative and inside the destination, so nothing
+			// written later can go through one to the rest of the disk
+			target := filepath.Join(filepath.Dir(filePath), header.Linkname)
+			if filepath.IsAbs(header.Linkname) || !within(dest, target) {
+				return fmt.Errorf("illegal link in archive: %s -> %s", header.Name, header.Linkname)
+			}
+			if err = os.MkdirAll(filepath.Dir(filePath), 0o755); err == nil {
+				err = os.Symlink(header.Linkname, filePath)
+			}
+		case tar.TypeLink:
+			// Hard links name another entry of the archive
+			target, linkErr := extractPath(dest, header.Linkname)
+			if linkErr != nil {
+				return fmt.Errorf("illegal link in archive: %s -> %s", header.Name, header.Linkname)
+			}
+			if err = os.MkdirAll(filepath.Dir(filePath), 0o755); err == nil {
+				err = os.Link(target, filePath)
+			}
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(filePath), 0o755); err == nil {
+				err = writeFile(filePath, reader, os.FileMode(header.Mode).Perm())
+			}
+		default:
+			// Devices and pipes have no place in a Java image
+			return fmt.Errorf("unsupported entry in archive: %s", header.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
 }
 
-func Download(url string, target string, version string) bool {
-	output, err := os.Create(target)
+// extractPath returns where an archive entry goes under dest, which must have
+// its symlinks resolved. Names that lead outside dest are refused, whether
+// through ".." or through a symlink extracted earlier.
+func extractPath(dest string, name string) (string, error) {
+	filePath := filepath.Join(dest, name)
+	if filepath.IsAbs(name) || !within(dest, filePath) {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+	parent, err := resolveExisting(filepath.Dir(filePath))
 	if err != nil {
-		fmt.Println("Error while creating", target, "-", err)
-		return false
+		return "", err
 	}
-	defer output.Close()
+	if !within(dest, parent) {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+	return filepath.Join(parent, filepath.Base(filePath)), nil
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// resolveExisting resolves the symlinks of the part of path that exists
+func resolveExisting(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	resolved, err = resolveExisting(parent)
 	if err != nil {
-		fmt.Println(err)
-		return false
+		return "", err
 	}
+	return filepath.Join(resolved, filepath.Base(path)), nil
+}
 
-	req.Header.Set("User-Agent", "JDKVM for Windows")
+// within reports whether path is dir or lies below it
+func within(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)) && !filepath.IsAbs(rel)
+}
 
-	response, err := client.Do(req)
+// writeFile creates a file from an archive entry
+func writeFile(path string, r io.Reader, mode os.FileMode) error {
+	destFile, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
 	if err != nil {
-		fmt.Println("Error while downloading", url, "-", err)
-		return false
+		return err
 	}
-	defer response.Body.Close()
+	_, err = io.Copy(destFile, r)
+	if closeErr := destFile.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
 
-	if response.StatusCode != 200 {
-		fmt.Println("Download failed with status code:", response.StatusCode)
-		return false
+// Operating system names Adoptium uses in its archive names
+const (
+	OSWindows = "windows"
+	OSMac     = "mac"
+	OSLinux   = "linux"
+	OSAlpine  = "alpine-linux"
+)
+
+// HostOS returns the Adoptium operating system name matching this machine.
+// Linux hosts built on musl (Alpine) need the separate alpine-linux builds.
+func HostOS() string {
+	switch runtime.GOOS {
+	case "windows":
+		return OSWindows
+	case "darwin":
+		return OSMac
+	case "linux":
+		if arch.HostLibc() == arch.Musl {
+			return OSAlpine
+		}
+		return OSLinux
 	}
+	return runtime.GOOS
+}
 
-	_, err = io.Copy(output, response.Body)
-	if err != nil {
-		fmt.Println("Error while writing to file", target, "-", err)
-		return false
+// CheckOSCompatible returns an error when a build for osName cannot run on this host
+func CheckOSCompatible(osName string) error {
+	host := HostOS()
+	if osName == host {
+		return nil
 	}
+	if osName == OSLinux && host == OSAlpine {
+		return fmt.Errorf("the %s build links against glibc, but this host uses musl; install the %s build instead", osName, OSAlpine)
+	}
+	if osName == OSAlpine && host == OSLinux {
+		return fmt.Errorf("the %s build links against musl, but this host uses glibc; install the %s build instead", osName, OSLinux)
+	}
+	return fmt.Errorf("the %s build cannot run on a %s host", osName, host)
+}
 
-	return true
+// temurinImage holds the image token Adoptium uses in its archive names
+var temurinImage = map[string]string{
+	java.ImageJDK:        "jdk",
+	java.ImageJRE:        "jre",
+	java.ImageDebug:      "debugimage",
+	java.ImageTest:       "testimage",
+	java.ImageStaticLibs: "static-libs",
 }
 
-// GetJava downloads and installs the specified Java version
-func GetJava(root string, v string, a string) bool {
-	// Load version mapping if not already loaded
-	if JavaVersionMapping == nil {
-		if err := LoadVersionMapping(); err != nil {
-			fmt.Println("Error loading version mapping:", err)
-			return false
-		}
+// rewriteImage rewrites a JDK archive URL to the archive of another image
+// type. Adoptium splits the static libraries of its Linux builds by C
+// library, naming them static-libs-glibc and static-libs-musl; the glibc ones
+// go with the glibc JDK.
+func rewriteImage(url string, image string, osName string) string {
+	token, ok := temurinImage[image]
+	if !ok || image == java.ImageJDK {
+		return url
+	}
+	if image == java.ImageStaticLibs && osName == OSLinux {
+		token += "-glibc"
 	}
+	return strings.Replace(url, "-jdk_", "-"+token+"_", 1)
+}
 
-	// Check if the version is in our version mapping
-	versionInfo, exists := JavaVersionMapping[v]
-	if !exists {
-		fmt.Printf("Error: Unsupported Java version '%s'. Please use one of the supported versions.\n", v)
-		fmt.Println("Supported versions:")
-		for version := range JavaVersionMapping {
-			fmt.Printf("  - %s\n", version)
-		}
+// Download saves url to target, printing what went wrong when it fails
+func Download(url string, target string, version string) bool {
+	if err := download(url, target); err != nil {
+		fmt.Println(err)
 		return false
 	}
+	return true
+}
 
-	// Use the full version from the mapping
-	fullVersion := versionInfo.Latest
-	fmt.Printf("Using Java %s version: %s\n", v, fullVersion)
-
-	// Create version directory
-	versionDir := filepath.Join(root, "v"+fullVersion)
-	
-	// Check if version is already installed (verify directory structure)
-	if file.Exists(versionDir) && file.Exists(filepath.Join(versionDir, "bin", "java.exe")) {
-		fmt.Printf("Java version %s is already installed.\n", fullVersion)
-		return true
-	} else if file.Exists(versionDir) {
-		// Clean up incomplete installation
-		fmt.Printf("Found incomplete installation of Java %s. Cleaning up...\n", fullVersion)
-		os.RemoveAll(versionDir)
-	}
-	
-	// Create version directory
-	os.MkdirAll(versionDir, os.ModeDir)
-
-	// Download the ZIP file
-	tempDir := os.TempDir()
-	zipPath := filepath.Join(tempDir, fmt.Sprintf("java-%s.zip", fullVersion))
-	
-	fmt.Printf("Downloading Java from: %s\n", versionInfo.URL)
-	fmt.Printf("Saving to: %s\n", zipPath)
-	
-	if !Download(versionInfo.URL, zipPath, fullVersion) {
-		fmt.Println("Failed to download Java ZIP file.")
-		os.Remove(zipPath) // Clean up
-		os.RemoveAll(versionDir) // Clean up incomplete directory
-		return false
+// downloadError is a download the server is to blame for: it could not be
+// reached, the connection or TLS handshake failed, or it answered with a
+// status other than 200. Status is 0 when there was no answer.
+type downloadError struct {
+	url    string
+	status int
+	err    error
+}
+
+func (e *downloadError) Error() string {
+	if e.status != 0 {
+		return fmt.Sprintf("Download failed with status code: %d", e.status)
 	}
+	return fmt.Sprintf("Error while downloading %s - %v", e.url, e.err)
+}
 
-	// Unzip the downloaded file
-	fmt.Printf("Unzipping Java %s...\n", fullVersion)
-	tempExtractDir := filepath.Join(tempDir, fmt.Sprintf("java-%s-extract", fullVersion))
-	os.MkdirAll(tempExtractDir, os.ModeDir)
-	
-	if err := Unzip(zipPath, tempExtractDir); err != nil {
-		fmt.Printf("Failed to unzip Java ZIP file: %v\n", err)
-		os.Remove(zipPath) // Clean up
-		os.RemoveAll(tempExtractDir) // Clean up
-		os.RemoveAll(versionDir) // Clean up incomplete directory
-		return false
+func (e *downloadError) Unwrap() error {
+	return e.err
+}
+
+// download saves url to target. Errors from the server are *downloadError,
+// local ones such as a full disk are not.
+func download(url string, target string) error {
+	output, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("Error while creating %s - %v", target, err)
 	}
+	defer output.Close()
 
-	// Find the extracted JDK directory (it usually has a name like jdk-17.0.11+9)
-	extractedItems, _ := os.ReadDir(tempExtractDir)
-	var jdkDir string
-	for _, item := range extractedItems {
-		if item.IsDir() && strings.HasPrefix(item.Name(), "jdk-") {
-			jdkDir = filepath.Join(tempExtractDir, item.Name())
-			break
-		}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
 	}
 
-	if jdkDir == "" {
-		fmt.Println("Failed to find JDK directory in extracted files.")
-		os.Remove(zipPath) // Clean up
-		os.RemoveAll(tempExtractDir) // Clean up
-		os.RemoveAll(versionDir) // Clean up incomplete directory
-		return false
+	req.Header.Set("User-Agent", "JDKVM for Windows")
+
+	response, err := client.Do(req)
+	if err != nil {
+		return &downloadError{url: url, err: err}
 	}
+	defer response.Body.Close()
 
-	// Move the JDK contents to our version directory
-	jdkContents, _ := os.ReadDir(jdkDir)
-	for _, item := range jdkContents {
-		srcPath := filepath.Join(jdkDir, item.Name())
-		destPath := filepath.Join(versionDir, item.Name())
-		
-		if err := os.Rename(srcPath, destPath); err != nil {
-			fmt.Printf("Failed to move %s to %s: %v\n", item.Name(), versionDir, err)
-			os.Remove(zipPath) // Clean up
-			os.RemoveAll(tempExtractDir) // Clean up
-			os.RemoveAll(versionDir) // Clean up incomplete directory
-			return false
+	if response.StatusCode != 200 {
+		return &downloadError{url: url, status: response.StatusCode}
+	}
+
+	body := &bodyReader{r: response.Body}
+	if _, err := io.Copy(output, body); err != nil {
+		if body.err != nil {
+			return &downloadError{url: url, err: body.err}
 		}
+		return fmt.Errorf("Error while writing to file %s - %v", target, err)
 	}
+	return nil
+}
 
-	// Clean up temporary files
-	os.Remove(zipPath)
-	os.RemoveAll(tempExtractDir)
+// bodyReader remembers a read error, which tells a connection dropped by the
+// server apart from a local write error
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
 
-	// Verify the installation
-	javaExe := filepath.Join(versionDir, "bin", "java.exe")
-	if !file.Exists(javaExe) {
-		fmt.Println("Java installation verification failed: java.exe not found.")
-		return false
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
 	}
-
-	fmt.Printf("Successfully installed Java %s\n", fullVersion)
-	return true
+	return n, err
 }
 
 // GetAvailableVersions returns the available Java versions from the mapping
@@ -311,14 +400,39 @@ func GetAvailableVersions() []string {
 		}
 	}
 
-	// Return only the major versions we support
+	// Return only the major versions we support, oldest first
 	versions := make([]string, 0, len(JavaVersionMapping))
 	for v := range JavaVersionMapping {
 		versions = append(versions, v)
 	}
+	sort.Slice(versions, func(i, j int) bool {
+		return java.Compare(versions[i], versions[j]) < 0
+	})
 	return versions
 }
 
+// FindRelease resolves a requested version against the current catalog, as
+// Catalog.FindRelease does
+func FindRelease(version string, vendor string) (JavaVersionInfo, bool) {
+	if JavaVersionMapping == nil {
+		if err := LoadVersionMapping(); err != nil {
+			return JavaVersionInfo{}, false
+		}
+	}
+	return CurrentCatalog.FindRelease(version, vendor)
+}
+
+// GetReleases returns every release of a feature release in the current
+// catalog, newest first
+func GetReleases(major string, vendor string) []JavaVersionInfo {
+	if JavaVersionMapping == nil {
+		if err := LoadVersionMapping(); err != nil {
+			return nil
+		}
+	}
+	return CurrentCatalog.Releases(major, vendor)
+}
+
 func GetRemoteTextFile(url string) (string, error) {
 	response, httperr := client.Get(url)
 	if httperr != nil {
@@ -0,0 +1,209 @@
+package web
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jdkvm/arch"
+	"jdkvm/java"
+)
+
+// tarEntry is one entry of a test archive
+type tarEntry struct {
+	name     string
+	typeflag byte
+	linkname string
+	body     string
+}
+
+func writeTarGz(t *testing.T, path string, entries []tarEntry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0o644, Size: int64(len(e.body))}
+		if e.typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Size > 0 {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUntar(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		wantErr bool
+	}{
+		{"regular layout", []tarEntry{
+			{name: "jdk/", typeflag: tar.TypeDir},
+			{name: "jdk/bin/java", typeflag: tar.TypeReg, body: "java"},
+			{name: "jdk/lib/libjvm.so", typeflag: tar.TypeReg, body: "jvm"},
+			{name: "jdk/bin/jvm", typeflag: tar.TypeSymlink, linkname: "../lib/libjvm.so"},
+			{name: "jdk/bin/javaw", typeflag: tar.TypeLink, linkname: "jdk/bin/java"},
+		}, false},
+		{"parent directory", []tarEntry{{name: "../evil", typeflag: tar.TypeReg, body: "x"}}, true},
+		{"nested parent directory", []tarEntry{{name: "jdk/../../evil", typeflag: tar.TypeReg, body: "x"}}, true},
+		{"absolute path", []tarEntry{{name: "/tmp/evil", typeflag: tar.TypeReg, body: "x"}}, true},
+		{"absolute symlink", []tarEntry{{name: "etc", typeflag: tar.TypeSymlink, linkname: "/etc"}}, true},
+		{"symlink to parent", []tarEntry{{name: "up", typeflag: tar.TypeSymlink, linkname: "../.."}}, true},
+		{"write through symlink", []tarEntry{
+			{name: "self", typeflag: tar.TypeSymlink, linkname: "."},
+			{name: "up", typeflag: tar.TypeSymlink, linkname: "self/.."},
+			{name: "up/evil", typeflag: tar.TypeReg, body: "x"},
+		}, true},
+		{"hard link outside", []tarEntry{{name: "passwd", typeflag: tar.TypeLink, linkname: "../outside"}}, true},
+		{"absolute hard link", []tarEntry{{name: "passwd", typeflag: tar.TypeLink, linkname: "/etc/passwd"}}, true},
+		{"device", []tarEntry{{name: "null", typeflag: tar.TypeChar}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dest := filepath.Join(root, "dest")
+			if err := os.MkdirAll(dest, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(root, "outside"), []byte("secret"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			archive := filepath.Join(root, "test.tar.gz")
+			writeTarGz(t, archive, tt.entries)
+
+			err := Untar(archive, dest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Untar error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, err := os.Lstat(filepath.Join(root, "evil")); err == nil {
+				t.Error("Untar wrote outside the destination")
+			}
+			if tt.wantErr {
+				return
+			}
+			content, err := os.ReadFile(filepath.Join(dest, "jdk", "bin", "jvm"))
+			if err != nil || string(content) != "jvm" {
+				t.Errorf("symlink content = %q, %v", content, err)
+			}
+			content, err = os.ReadFile(filepath.Join(dest, "jdk", "bin", "javaw"))
+			if err != nil || string(content) != "java" {
+				t.Errorf("hard link content = %q, %v", content, err)
+			}
+		})
+	}
+}
+
+func TestUnzip(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		wantErr bool
+	}{
+		{"regular layout", []string{"jdk/", "jdk/bin/java.exe", "jdk/release"}, false},
+		{"parent directory", []string{"../evil"}, true},
+		{"nested parent directory", []string{"jdk/../../evil"}, true},
+		{"absolute path", []string{"/evil"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dest := filepath.Join(root, "dest")
+			if err := os.MkdirAll(dest, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			archive := filepath.Join(root, "test.zip")
+			f, err := os.Create(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			zw := zip.NewWriter(f)
+			for _, name := range tt.files {
+				w, err := zw.Create(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				w.Write([]byte(name))
+			}
+			zw.Close()
+			f.Close()
+
+			err = Unzip(archive, dest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unzip error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, err := os.Lstat(filepath.Join(root, "evil")); err == nil {
+				t.Error("Unzip wrote outside the destination")
+			}
+			if !tt.wantErr && !Exists(filepath.Join(dest, "jdk", "bin", "java.exe")) {
+				t.Error("jdk/bin/java.exe was not extracted")
+			}
+		})
+	}
+}
+
+func TestInstallArchiveUndoesInstallWithoutMetadata(t *testing.T) {
+	root := t.TempDir()
+	archivePath := filepath.Join(t.TempDir(), "jdk.tar.gz")
+	writeTarGz(t, archivePath, []tarEntry{
+		{name: "jdk-17.0.11+9/", typeflag: tar.TypeDir},
+		{name: "jdk-17.0.11+9/bin/java", typeflag: tar.TypeReg, body: "#!/bin/sh\n"},
+		{name: "jdk-17.0.11+9/bin/java.exe", typeflag: tar.TypeReg, body: "#!/bin/sh\n"},
+		// A directory where the metadata file goes makes writing it fail
+		{name: "jdk-17.0.11+9/.jdkvm.json/", typeflag: tar.TypeDir},
+	})
+	info := JavaVersionInfo{Latest: "17.0.11+9", Short: "17", Vendor: "temurin"}
+
+	if err := InstallArchive(root, info, arch.Host(), HostOS(), java.ImageJDK, archivePath, ""); err == nil {
+		t.Fatal("InstallArchive succeeded without writing the metadata")
+	}
+	if entries, _ := os.ReadDir(root); len(entries) > 0 {
+		t.Errorf("InstallArchive left %s behind", entries[0].Name())
+	}
+}
+
+func TestIsEOL(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		name string
+		eol  string
+		now  time.Time
+		want bool
+	}{
+		{"no date", "", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{"invalid date", "2024-13-01", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{"past", "2024-10-31", time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), true},
+		{"future", "2027-10-31", time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), false},
+		{"start of the EOL day", "2024-10-31", time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC), false},
+		{"end of the EOL day", "2024-10-31", time.Date(2024, 10, 31, 23, 59, 59, 0, time.UTC), false},
+		{"start of the next day", "2024-10-31", time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), true},
+		{"next day in the local time zone", "2024-10-31", time.Date(2024, 11, 1, 0, 30, 0, 0, tokyo), true},
+		{"EOL day in the local time zone", "2024-10-31", time.Date(2024, 10, 31, 23, 30, 0, 0, tokyo), false},
+	}
+	for _, tt := range tests {
+		info := JavaVersionInfo{EOL: tt.eol}
+		if got := info.IsEOL(tt.now); got != tt.want {
+			t.Errorf("%s: IsEOL(%s) with EOL %q = %v, want %v", tt.name, tt.now, tt.eol, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"jdkvm/cli"
+	"jdkvm/file"
+	"jdkvm/java"
+)
+
+// which prints the absolute path of a JDK tool, for scripts:
+//
+//	jdkvm which <tool> [--version <version>] [--arch <arch>]
+//
+// Without --version the tool comes from the active JDK (JAVA_HOME).
+// Only the path goes to stdout; errors go to stderr, with exit status 3 when
+// the version or tool is not installed.
+func which(tool string, version string, q java.Query) error {
+	if tool == "" {
+		return cli.Usage("which needs a tool name")
+	}
+
+	home, err := resolveHome(version, q)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(home, "bin", tool)
+	if runtime.GOOS == "windows" && filepath.Ext(tool) == "" {
+		path += ".exe"
+	}
+	if !file.Exists(path) {
+		return cli.Exit(cli.ExitNotInstalled, "%s is not part of %s", tool, home)
+	}
+	fmt.Println(path)
+	return nil
+}
+
+// home prints the install directory of a version, for scripts:
+//
+//	jdkvm home <version> [--arch <arch>]
+func home(version string, q java.Query) error {
+	if version == "" {
+		return cli.Usage("home needs a version")
+	}
+	dir, err := resolveHome(version, q)
+	if err != nil {
+		return err
+	}
+	fmt.Println(dir)
+	return nil
+}
+
+// resolveHome returns the install directory of a version, or of the active
+// JDK when version is empty. q filters the installs by vendor, arch and image.
+func resolveHome(version string, q java.Query) (string, error) {
+	if version == "" {
+		active := os.Getenv("JAVA_HOME")
+		if active == "" || !file.Exists(java.Executable(active)) {
+			return "", cli.Exit(cli.ExitNotInstalled, "no active Java: JAVA_HOME is not set to an install; run jdkvm use <version> or pass --version")
+		}
+		return filepath.Abs(active)
+	}
+
+	q.Version = version
+	inst, ok := java.Find(env.root, q)
+	if !ok {
+		return "", notInstalled(version, q)
+	}
+	return filepath.Abs(inst.Dir)
+}